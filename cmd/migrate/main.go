@@ -0,0 +1,87 @@
+// Command migrate applies, reverts and reports schema migrations.
+//
+//	migrate status
+//	migrate up   [--dry-run]
+//	migrate down [--dry-run] [-n 1]
+//
+// It works on the database named by DATABASE_URL, read from the environment
+// and the config file (-config or CONFIG_FILE) just like the server does.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lojf/nextgen/internal/config"
+	"github.com/lojf/nextgen/internal/db"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate status | up [--dry-run] | down [--dry-run] [-n N]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "run the steps in a rolled-back transaction and print the SQL")
+	steps := fs.Int("n", 1, "number of migrations to revert (down only)")
+	configFile := fs.String("config", "", "KEY=VALUE settings file (default $CONFIG_FILE); the environment wins")
+	_ = fs.Parse(os.Args[2:])
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.Open(cfg.DatabaseURL); err != nil {
+		log.Fatalf("db open: %v", err)
+	}
+
+	switch cmd {
+	case "status":
+		st, err := db.Status()
+		if err != nil {
+			log.Fatalf("status: %v", err)
+		}
+		pending := 0
+		for _, s := range st {
+			if s.AppliedAt == nil {
+				pending++
+				fmt.Printf("  pending                    %s\n", s.ID)
+				continue
+			}
+			fmt.Printf("  %s  %s\n", s.AppliedAt.Local().Format("2006-01-02 15:04:05"), s.ID)
+		}
+		fmt.Printf("%d migration(s), %d pending\n", len(st), pending)
+
+	case "up":
+		ids, err := db.MigrateUp(os.Stdout, *dryRun)
+		if err != nil {
+			log.Fatalf("up: %v", err)
+		}
+		if len(ids) == 0 {
+			fmt.Println("nothing to apply")
+		}
+
+	case "down":
+		if *steps < 1 {
+			usage()
+		}
+		ids, err := db.MigrateDown(os.Stdout, *steps, *dryRun)
+		if err != nil {
+			log.Fatalf("down: %v", err)
+		}
+		if len(ids) == 0 {
+			fmt.Println("nothing to revert")
+		}
+
+	default:
+		usage()
+	}
+}
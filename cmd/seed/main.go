@@ -6,15 +6,22 @@ import (
 	"log"
 	"time"
 
+	"github.com/lojf/nextgen/internal/config"
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 func main() {
 	reset := flag.Bool("reset", false, "delete existing parents/children/classes/registrations before seeding")
+	configFile := flag.String("config", "", "KEY=VALUE settings file (default $CONFIG_FILE); the environment wins")
 	flag.Parse()
 
-	if err := db.Init(); err != nil {
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := db.Init(cfg.DatabaseURL); err != nil {
 		log.Fatalf("db init: %v", err)
 	}
 	conn := db.Conn()
@@ -28,6 +35,7 @@ func main() {
 		conn.Exec("DELETE FROM class_templates")
 		conn.Exec("DELETE FROM children")
 		conn.Exec("DELETE FROM parents")
+		conn.Exec("DELETE FROM households")
 		conn.Exec("DELETE FROM classes")
 	}
 
@@ -72,8 +80,11 @@ func main() {
 	parentMap := map[string]models.Parent{}
 	for _, p := range parents {
 		pp := p
-		if err := conn.Where("phone = ?", pp.Phone).FirstOrCreate(&pp).Error; err != nil {
-			log.Fatalf("seed parent %s: %v", p.Phone, err)
+		if err := conn.Where("phone = ?", pp.Phone).First(&pp).Error; err != nil {
+			// Each seeded parent is the only guardian of their own household.
+			if err := svc.CreateParent(conn, &pp); err != nil {
+				log.Fatalf("seed parent %s: %v", p.Phone, err)
+			}
 		}
 		parentMap[p.Phone] = pp
 	}
@@ -86,16 +97,18 @@ func main() {
 
 		child := row.Child
 		child.ParentID = p.ID
+		child.HouseholdID = p.HouseholdID
 		if err := conn.Where("parent_id = ? AND name = ?", child.ParentID, child.Name).FirstOrCreate(&child).Error; err != nil {
 			log.Fatalf("seed child %s: %v", child.Name, err)
 		}
 
 		reg := models.Registration{
-			ParentID: p.ID,
-			ChildID:  child.ID,
-			ClassID:  classes[row.ClassIdx].ID,
-			Status:   row.Status,
-			Code:     fmt.Sprintf("DUMMY-%d-%d", child.ID, classes[row.ClassIdx].ID),
+			HouseholdID: p.HouseholdID,
+			ParentID:    p.ID,
+			ChildID:     child.ID,
+			ClassID:     classes[row.ClassIdx].ID,
+			Status:      row.Status,
+			Code:        fmt.Sprintf("DUMMY-%d-%d", child.ID, classes[row.ClassIdx].ID),
 		}
 		if row.Status == "confirmed" {
 			ci := classes[row.ClassIdx].Date.Add(15 * time.Minute)
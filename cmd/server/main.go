@@ -1,39 +1,82 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/lojf/nextgen/internal/bot"
+	"github.com/lojf/nextgen/internal/config"
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/handlers"
+	svc "github.com/lojf/nextgen/internal/services"
 	"github.com/lojf/nextgen/internal/web"
 )
 
 func main() {
-	// Init DB (creates nextgen.db in working dir)
-	if err := db.Init(); err != nil {
-		log.Fatalf("db init: %v", err)
+	migrate := flag.Bool("migrate", false, "apply pending schema migrations before starting")
+	configFile := flag.String("config", "", "KEY=VALUE settings file (default $CONFIG_FILE); the environment wins")
+	flag.Parse()
+
+	// Bad settings stop the server here, not halfway through a Sunday.
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Open DB (DATABASE_URL; default nextgen.db in working dir)
+	if err := db.Open(cfg.DatabaseURL); err != nil {
+		log.Fatalf("db open: %v", err)
+	}
+	// Never run against a schema the code does not expect. Applying is an
+	// explicit decision: `migrate up`, -migrate, or AUTO_MIGRATE=1.
+	pending, err := db.Pending()
+	if err != nil {
+		log.Fatalf("db migrations: %v", err)
+	}
+	if len(pending) > 0 {
+		if !*migrate && !cfg.AutoMigrate {
+			log.Fatalf("%d pending migration(s), first is %s; run `migrate up` or start with -migrate",
+				len(pending), pending[0].ID)
+		}
+		if _, err := db.MigrateUp(os.Stderr, false); err != nil {
+			log.Fatalf("db migrate: %v", err)
+		}
 	}
 	// Make sure a fresh install has a way in.
-	if err := handlers.EnsureBootstrapAdmin(db.Conn()); err != nil {
+	if err := handlers.EnsureBootstrapAdmin(db.Conn(), cfg.AdminPassword); err != nil {
 		log.Fatalf("bootstrap admin: %v", err)
 	}
-	bot.StartReminderLoop()
+	bot.Configure(cfg)
+	bot.StartReminderLoop(cfg)
+	startNoShowLoop(15 * time.Minute)
 
-	r := web.Router()
+	r := web.Router(cfg)
 
-	addr := getEnv("ADDR", ":8080")
-	log.Printf("LOJF NextGen listening on %s", addr)
-	if err := http.ListenAndServe(addr, r); err != nil {
+	log.Printf("LOJF NextGen listening on %s (public %s)", cfg.Addr, cfg.PublicBaseURL)
+	if err := http.ListenAndServe(cfg.Addr, r); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func getEnv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// startNoShowLoop runs svc.MarkNoShows at startup and then every interval.
+func startNoShowLoop(interval time.Duration) {
+	run := func() {
+		n, err := svc.MarkNoShows(time.Now())
+		if err != nil {
+			log.Printf("no-shows: %v", err)
+		} else if n > 0 {
+			log.Printf("no-shows: marked %d registration(s)", n)
+		}
 	}
-	return def
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
 }
@@ -9,6 +9,8 @@ cd "$APP_DIR"
 
 /opt/homebrew/bin/git pull origin "$BRANCH" 2>/dev/null || git pull origin "$BRANCH"
 /usr/bin/make build
+# The server will not start on a pending migration; apply them first.
+./bin/lojf-migrate up
 
 if command -v sudo >/dev/null 2>&1; then
   sudo systemctl restart "$SERVICE_NAME"
@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
+
+	"github.com/lojf/nextgen/internal/config"
 )
 
 type Client struct {
@@ -15,8 +16,17 @@ type Client struct {
 	apiURL string
 }
 
+// conf is the server config. main sets it with Configure; until then the
+// bot runs on the defaults.
+var conf = config.Default()
+
+// Configure hands the bot the config loaded at startup.
+func Configure(c config.Config) {
+	conf = c
+}
+
 func NewClient() *Client {
-	tok := os.Getenv("TG_BOT_TOKEN")
+	tok := conf.Telegram.BotToken
 	return &Client{
 		token:  tok,
 		apiURL: "https://api.telegram.org/bot" + tok,
@@ -85,6 +85,9 @@ func (d *Dispatcher) Handle(u *Update) {
 			code := strings.TrimSpace(strings.TrimPrefix(text, "/link"))
 			code = strings.Trim(code, " :")
 			d.handleLinkCode(&tu, chat, code)
+		case strings.HasPrefix(text, "/cancel"):
+			code := strings.TrimSpace(strings.TrimPrefix(text, "/cancel"))
+			d.handleCancel(chat, &tu, code)
 		case strings.EqualFold(text, "My registrations"), strings.HasPrefix(text, "/my"):
 			d.handleMy(chat, &tu)
 		case strings.EqualFold(text, "Register"), strings.HasPrefix(text, "/register"):
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
-	"net/url"
+	svc "github.com/lojf/nextgen/internal/services"
 	"strings"
 	"time"
 	"unicode"
@@ -66,10 +66,11 @@ func (d *Dispatcher) handleMy(chat int64, tu *models.TelegramUser) {
 	}
 	var rows []row
 	db.Conn().Table("registrations r").
+		Where("r.deleted_at IS NULL").
 		Select("r.code, r.status, children.name as child, classes.name as class, classes.date as date").
 		Joins("JOIN children ON children.id = r.child_id").
 		Joins("JOIN classes ON classes.id = r.class_id").
-		Where("r.parent_id = ? AND classes.date >= ?", *tu.ParentID, time.Now().Add(-2*time.Hour)).
+		Where("r.household_id = ? AND classes.date >= ?", linkedHousehold(tu), time.Now().Add(-2*time.Hour)).
 		Order("classes.date asc, r.created_at asc").
 		Scan(&rows)
 
@@ -83,7 +84,7 @@ func (d *Dispatcher) handleMy(chat int64, tu *models.TelegramUser) {
 	loc, _ := time.LoadLocation("Asia/Jakarta")
 	for _, r := range rows {
 		date := r.Date.In(loc).Format("Mon, 02 Jan 2006")
-		if r.Status == "waitlisted" {
+		if svc.Status(r.Status) == svc.StatusWaitlisted {
 			fmt.Fprintf(&b, "• %s — %s — %s — Waitlist\n", date, r.Class, r.Child)
 		} else {
 			fmt.Fprintf(&b, "• %s — %s — %s — <code>%s</code>\n", date, r.Class, r.Child, r.Code)
@@ -93,6 +94,34 @@ func (d *Dispatcher) handleMy(chat int64, tu *models.TelegramUser) {
 	_ = d.c.SendMessage(chat, b.String(), nil)
 }
 
+// handleCancel cancels one of the linked parent's family registrations, the
+// /cancel CODE that handleMy advertises.
+func (d *Dispatcher) handleCancel(chat int64, tu *models.TelegramUser, code string) {
+	if tu.ParentID == nil {
+		_ = d.c.SendMessage(chat, "Link first (share phone or /link CODE).", nil)
+		return
+	}
+	code = strings.ToUpper(strings.Trim(code, " :"))
+	if code == "" {
+		_ = d.c.SendMessage(chat, "Use: /cancel REG-XXXXXXXX\nSee <b>My registrations</b> for your codes.", nil)
+		return
+	}
+	var reg models.Registration
+	if err := db.Conn().Where("code = ? AND household_id = ?", code, linkedHousehold(tu)).First(&reg).Error; err != nil {
+		_ = d.c.SendMessage(chat, "Registration not found.", nil)
+		return
+	}
+	if svc.StatusOf(reg) == svc.StatusCanceled {
+		_ = d.c.SendMessage(chat, "Already canceled.", nil)
+		return
+	}
+	if err := svc.CancelByCode(reg.Code, svc.ParentActor(tu.Phone, models.SourceTelegram)); err != nil {
+		_ = d.c.SendMessage(chat, "Unable to cancel right now, please try again.", nil)
+		return
+	}
+	_ = d.c.SendMessage(chat, fmt.Sprintf("Canceled <code>%s</code>.", reg.Code), MainKeyboard())
+}
+
 func (d *Dispatcher) handleRegisterStart(chat int64, tu *models.TelegramUser) {
 	if tu.ParentID == nil {
 		_ = d.c.SendMessage(chat, "Link first (share phone or /link CODE).", nil)
@@ -101,7 +130,7 @@ func (d *Dispatcher) handleRegisterStart(chat int64, tu *models.TelegramUser) {
 	// For MVP, deep-link to your site flow:
 	_ = d.c.SendMessage(chat, "Open the registration page:", map[string]any{
 		"inline_keyboard": [][]map[string]any{
-			{{"text": "Open Register", "url": "https://nextgen.lojf.id/register?k=1"}},
+			{{"text": "Open Register", "url": conf.URL("/register?k=1")}},
 		},
 	})
 }
@@ -113,7 +142,7 @@ func (d *Dispatcher) handleAddChildStart(chat int64, tu *models.TelegramUser) {
 	}
 	_ = d.c.SendMessage(chat, "Add child on the website:", map[string]any{
 		"inline_keyboard": [][]map[string]any{
-			{{"text": "Open My Account", "url": "https://nextgen.lojf.id/account/profile"}},
+			{{"text": "Open My Account", "url": conf.URL("/account/profile")}},
 		},
 	})
 }
@@ -123,23 +152,44 @@ func (d *Dispatcher) handleAccount(chat int64, tu *models.TelegramUser) {
 		_ = d.c.SendMessage(chat, "Not linked. Share phone or /link CODE.", nil)
 		return
 	}
-	u := "https://nextgen.lojf.id/my/list"
+	u := conf.URL("/my/list")
 	_ = d.c.SendMessage(chat, "Open your account & registrations:", map[string]any{
 		"inline_keyboard": [][]map[string]any{
 			{{"text": "My Registrations", "url": u}},
-			{{"text": "Account Profile", "url": "https://nextgen.lojf.id/account/profile"}},
+			{{"text": "Account Profile", "url": conf.URL("/account/profile")}},
 		},
 	})
 }
 
+// linkedHousehold is the household of the guardian a chat is linked to, or 0.
+func linkedHousehold(tu *models.TelegramUser) uint {
+	var p models.Parent
+	if tu.ParentID == nil || db.Conn().First(&p, *tu.ParentID).Error != nil {
+		return 0
+	}
+	return p.HouseholdID
+}
+
+// householdChats lists the deliverable chats of every guardian of a household.
+func householdChats(householdID uint) []models.TelegramUser {
+	var out []models.TelegramUser
+	_ = db.Conn().
+		Where("deliverable = ? AND parent_id IN (?)", true,
+			db.Conn().Model(&models.Parent{}).Select("id").Where("household_id = ?", householdID)).
+		Find(&out).Error
+	return out
+}
+
 // Public helpers for other packages
 func NotifyPromotion(parentID uint, childName, className, dateStr, code string) {
-	var tu models.TelegramUser
-	if err := db.Conn().Where("parent_id = ? AND deliverable = 1", parentID).First(&tu).Error; err != nil {
+	var p models.Parent
+	if err := db.Conn().First(&p, parentID).Error; err != nil {
 		return
 	}
 	c := NewClient()
 	msg := fmt.Sprintf("🎉 <b>Promoted from Waitlist</b>\n%s — %s — %s\nCode: <code>%s</code>", childName, className, dateStr, code)
-	_ = c.SendMessage(tu.ChatID, msg, nil)
-	_ = c.SendPhoto(tu.ChatID, "https://nextgen.lojf.id/qr/"+url.PathEscape(code)+".png", "", nil)
+	for _, tu := range householdChats(p.HouseholdID) {
+		_ = c.SendMessage(tu.ChatID, msg, nil)
+		_ = c.SendPhoto(tu.ChatID, conf.URL(svc.QRImagePath(code, time.Now().Add(svc.QRImageLinkTTL))), "", nil)
+	}
 }
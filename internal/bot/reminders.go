@@ -2,47 +2,29 @@ package bot
 
 import (
 	"fmt"
-	"os"
-	"strings"
 	"time"
 
+	"github.com/lojf/nextgen/internal/config"
 	"github.com/lojf/nextgen/internal/db"
-	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
-func StartReminderLoop() {
-	if os.Getenv("TG_ENABLE_REMINDERS") != "1" {
+// StartReminderLoop sends class reminders every minute when
+// TG_ENABLE_REMINDERS is on.
+func StartReminderLoop(c config.Config) {
+	if !c.Telegram.Reminders {
 		return
 	}
+	tg := c.Telegram
 	go func() {
 		ticker := time.NewTicker(1 * time.Minute)
 		defer ticker.Stop()
 		for range ticker.C {
-			runReminders()
+			runReminders(tg)
 		}
 	}()
 }
 
-// Parse REMIND_OFFSETS like "24h,2h,1h". Defaults to 24h & 2h.
-func parseOffsets() []time.Duration {
-	raw := strings.TrimSpace(os.Getenv("REMIND_OFFSETS"))
-	if raw == "" {
-		return []time.Duration{24 * time.Hour, 2 * time.Hour}
-	}
-	parts := strings.Split(raw, ",")
-	out := make([]time.Duration, 0, len(parts))
-	for _, p := range parts {
-		d, err := time.ParseDuration(strings.TrimSpace(p))
-		if err == nil && d > 0 {
-			out = append(out, d)
-		}
-	}
-	if len(out) == 0 {
-		out = []time.Duration{24 * time.Hour, 2 * time.Hour}
-	}
-	return out
-}
-
 var remindersLoc = func() *time.Location {
 	loc, err := time.LoadLocation("Asia/Jakarta")
 	if err != nil {
@@ -51,48 +33,52 @@ var remindersLoc = func() *time.Location {
 	return loc
 }()
 
-func runReminders() {
+func runReminders(tg config.Telegram) {
 	loc := remindersLoc
 	now := time.Now().In(loc)
 	// Use a strict 1-minute window: [tick, tick+1m) to avoid duplicate sends
 	tick := now.Truncate(time.Minute)
 	next := tick.Add(time.Minute)
 
-	offsets := parseOffsets()
-	includeWaitlist := os.Getenv("REMIND_INCLUDE_WAITLIST") == "1"
+	offsets := tg.RemindOffsets
+	includeWaitlist := tg.RemindWaitlist
 
 	for _, ahead := range offsets {
-		// Classes due to remind in this tick:
-		// trigger = class.date - ahead ∈ [tick, next)
-		// => class.date ∈ [tick+ahead, next+ahead)
+		// Classes due to remind in this tick, timed from the class's start
+		// (its date for classes without one):
+		// trigger = start - ahead ∈ [tick, next)
+		// => start ∈ [tick+ahead, next+ahead)
 		start := tick.Add(ahead)
 		end := next.Add(ahead)
 
 		type row struct {
-			Parent uint
-			Child  string
-			Class  string
-			Code   string
-			Date   time.Time
-			Status string
+			Household uint
+			Child     string
+			Class     string
+			Code      string
+			Date      time.Time
+			StartsAt  *time.Time
+			Status    string
 		}
 		var rows []row
 
 		q := db.Conn().Table("registrations r").
-			Select(`r.parent_id as parent,
+			Where("r.deleted_at IS NULL").
+			Select(`r.household_id as household,
 			        children.name as child,
 			        classes.name  as class,
 			        r.code,
 			        classes.date  as date,
+			        classes.starts_at as starts_at,
 			        r.status`).
 			Joins("JOIN children ON children.id = r.child_id").
 			Joins("JOIN classes  ON classes.id = r.class_id").
-			Where("classes.date >= ? AND classes.date < ?", start, end)
+			Where("COALESCE(classes.starts_at, classes.date) >= ? AND COALESCE(classes.starts_at, classes.date) < ?", start, end)
 
 		if includeWaitlist {
-			q = q.Where("r.status IN ('confirmed','waitlisted')")
+			q = q.Where("r.status IN ?", []svc.Status{svc.StatusConfirmed, svc.StatusWaitlisted})
 		} else {
-			q = q.Where("r.status = 'confirmed'")
+			q = q.Where("r.status = ?", svc.StatusConfirmed)
 		}
 
 		if err := q.Scan(&rows).Error; err != nil {
@@ -103,42 +89,51 @@ func runReminders() {
 			continue
 		}
 
-		// Batch-load TelegramUsers for all parent IDs in one query.
-		parentIDs := make([]uint, 0, len(rows))
+		// Batch-load the TelegramUsers of every guardian of these households
+		// in one query; each linked guardian gets the reminder.
+		householdIDs := make([]uint, 0, len(rows))
 		for _, x := range rows {
-			parentIDs = append(parentIDs, x.Parent)
+			householdIDs = append(householdIDs, x.Household)
 		}
-		var tgUsers []models.TelegramUser
-		_ = db.Conn().Where("parent_id IN ? AND deliverable = 1", parentIDs).Find(&tgUsers).Error
-
-		tgMap := make(map[uint]models.TelegramUser, len(tgUsers))
-		for _, tu := range tgUsers {
-			if tu.ParentID != nil {
-				tgMap[*tu.ParentID] = tu
-			}
+		type chatRow struct {
+			HouseholdID uint
+			ChatID      int64
+		}
+		var chats []chatRow
+		_ = db.Conn().Table("telegram_users tu").
+			Select("parents.household_id, tu.chat_id").
+			Joins("JOIN parents ON parents.id = tu.parent_id AND parents.deleted_at IS NULL").
+			Where("parents.household_id IN ? AND tu.deliverable = ?", householdIDs, true).
+			Scan(&chats).Error
+
+		tgMap := make(map[uint][]int64, len(chats))
+		for _, ch := range chats {
+			tgMap[ch.HouseholdID] = append(tgMap[ch.HouseholdID], ch.ChatID)
 		}
 
 		c := NewClient()
 		for _, x := range rows {
-			tu, ok := tgMap[x.Parent]
-			if !ok {
-				continue
+			when := x.Date
+			if x.StartsAt != nil {
+				when = *x.StartsAt
 			}
-			dateStr := x.Date.In(loc).Format("Mon, 02 Jan 2006 15:04")
-
-			if x.Status == "waitlisted" {
-				_ = c.SendMessage(tu.ChatID,
-					fmt.Sprintf("⏰ Reminder: %s — %s — %s\nStatus: Waitlist", x.Child, x.Class, dateStr),
+			dateStr := when.In(loc).Format("Mon, 02 Jan 2006 15:04")
+
+			for _, chatID := range tgMap[x.Household] {
+				if svc.Status(x.Status) == svc.StatusWaitlisted {
+					_ = c.SendMessage(chatID,
+						fmt.Sprintf("⏰ Reminder: %s — %s — %s\nStatus: Waitlist", x.Child, x.Class, dateStr),
+						nil)
+					continue
+				}
+
+				// Confirmed: code + QR
+				_ = c.SendMessage(chatID,
+					fmt.Sprintf("⏰ Reminder: %s — %s — %s\nCode: <code>%s</code>", x.Child, x.Class, dateStr, x.Code),
 					nil)
-				continue
+				_ = c.SendPhoto(chatID,
+					conf.URL(svc.QRImagePath(x.Code, time.Now().Add(svc.QRImageLinkTTL))), "", nil)
 			}
-
-			// Confirmed: code + QR
-			_ = c.SendMessage(tu.ChatID,
-				fmt.Sprintf("⏰ Reminder: %s — %s — %s\nCode: <code>%s</code>", x.Child, x.Class, dateStr, x.Code),
-				nil)
-			_ = c.SendPhoto(tu.ChatID,
-				"https://nextgen.lojf.id/qr/"+x.Code+".png", "", nil)
 		}
 	}
 }
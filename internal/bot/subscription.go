@@ -2,29 +2,25 @@ package bot
 
 import (
 	"fmt"
-	"net/url"
 	"time"
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/events"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 func init() {
 	events.OnPromotion = func(reg models.Registration) {
 		// Load related records
-		var p models.Parent
-		if err := db.Conn().First(&p, reg.ParentID).Error; err != nil {
-			return
-		}
 		var c models.Child
 		_ = db.Conn().First(&c, reg.ChildID).Error
 		var cl models.Class
 		_ = db.Conn().First(&cl, reg.ClassID).Error
 
-		// Find linked Telegram user
-		var tu models.TelegramUser
-		if err := db.Conn().Where("parent_id = ? AND deliverable = 1", p.ID).First(&tu).Error; err != nil {
+		// Every linked guardian of the family hears about it
+		chats := householdChats(reg.HouseholdID)
+		if len(chats) == 0 {
 			return
 		}
 
@@ -33,7 +29,9 @@ func init() {
 
 		msg := fmt.Sprintf("🎉 <b>Promoted from Waitlist</b>\n%s — %s — %s\nCode: <code>%s</code>", c.Name, cl.Name, dateStr, reg.Code)
 		client := NewClient()
-		_ = client.SendMessage(tu.ChatID, msg, nil)
-		_ = client.SendPhoto(tu.ChatID, "https://nextgen.lojf.id/qr/"+url.PathEscape(reg.Code)+".png", "", nil)
+		for _, tu := range chats {
+			_ = client.SendMessage(tu.ChatID, msg, nil)
+			_ = client.SendPhoto(tu.ChatID, conf.URL(svc.QRImagePath(reg.Code, time.Now().Add(svc.QRImageLinkTTL))), "", nil)
+		}
 	}
 }
@@ -0,0 +1,217 @@
+// Package config gathers the server's settings in one typed, validated
+// struct. main loads it once at startup and hands it to the router, the bot
+// and the reminder loop; nothing else reads the environment.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config is everything the server is told from outside.
+type Config struct {
+	Addr string // ADDR, ":8080" by default
+
+	// DatabaseURL (DATABASE_URL) is handed to db.Open; empty means its
+	// default SQLite file. The migrate and seed commands load it the same
+	// way, so they always work on the server's database.
+	DatabaseURL string
+
+	// PublicBaseURL (PUBLIC_BASE_URL) is where parents and Telegram reach the
+	// site, without a trailing slash. Every link we hand out is built on it.
+	PublicBaseURL string
+
+	AutoMigrate   bool   // AUTO_MIGRATE: apply pending migrations on boot
+	SessionSecret string // SESSION_SECRET; empty means generate and persist one
+	AdminPassword string // ADMIN_PASSWORD for the bootstrap admin
+
+	// SecureCookies (SECURE_COOKIES) forces the Secure flag when TLS ends at
+	// a proxy that does not send X-Forwarded-Proto.
+	SecureCookies bool
+
+	// CheckinUndoWindow (CHECKIN_UNDO_WINDOW) is how long a volunteer may
+	// take back their own check-in.
+	CheckinUndoWindow time.Duration
+
+	// CheckinOpensBefore (CHECKIN_OPENS_BEFORE) and CheckinClosesAfter
+	// (CHECKIN_CLOSES_AFTER) bound when volunteers may check children into
+	// a class with a start time, around that time.
+	CheckinOpensBefore time.Duration
+	CheckinClosesAfter time.Duration
+
+	Telegram Telegram
+}
+
+// Telegram is the bot's share of the config.
+type Telegram struct {
+	BotToken      string          // TG_BOT_TOKEN
+	WebhookSecret string          // TG_WEBHOOK_SECRET
+	Reminders     bool            // TG_ENABLE_REMINDERS
+	RemindOffsets []time.Duration // REMIND_OFFSETS, e.g. "24h,2h"
+	// RemindWaitlist (REMIND_INCLUDE_WAITLIST) also reminds waitlisted
+	// families that they are still waiting.
+	RemindWaitlist bool
+}
+
+// Default is the config of a bare local run. Tests use it as is.
+func Default() Config {
+	return Config{
+		Addr:               ":8080",
+		PublicBaseURL:      "http://localhost:8080",
+		CheckinUndoWindow:  10 * time.Minute,
+		CheckinOpensBefore: time.Hour,
+		CheckinClosesAfter: time.Hour,
+		Telegram: Telegram{
+			RemindOffsets: []time.Duration{24 * time.Hour, 2 * time.Hour},
+		},
+	}
+}
+
+// URL joins path ("/qr/…") onto the public base URL.
+func (c Config) URL(path string) string {
+	return c.PublicBaseURL + path
+}
+
+// Load reads the optional config file at path (CONFIG_FILE when path is
+// empty), then the environment on top of it, and validates the result. The
+// file holds the same KEY=VALUE pairs as the environment, one per line, with
+// # comments; a variable set in the environment wins.
+func Load(path string) (Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	vals := map[string]string{}
+	if path != "" {
+		if err := readFile(path, vals); err != nil {
+			return Config{}, err
+		}
+	}
+	return parse(func(key string) string {
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+		return vals[key]
+	})
+}
+
+func readFile(path string, into map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return fmt.Errorf("config file %s:%d: want KEY=VALUE", path, n)
+		}
+		into[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+	}
+	return sc.Err()
+}
+
+// parse builds a Config from get and reports every bad value at once, so a
+// deploy is fixed in one round.
+func parse(get func(string) string) (Config, error) {
+	c := Default()
+	var errs []string
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, key+": "+fmt.Sprintf(format, args...))
+	}
+	str := func(key string) string { return strings.TrimSpace(get(key)) }
+	flag := func(key string) bool {
+		switch strings.ToLower(str(key)) {
+		case "", "0", "false", "no":
+			return false
+		case "1", "true", "yes":
+			return true
+		}
+		bad(key, "%q is not a yes/no value", str(key))
+		return false
+	}
+
+	if v := str("ADDR"); v != "" {
+		c.Addr = v
+	}
+	if _, port, err := net.SplitHostPort(c.Addr); err != nil {
+		bad("ADDR", "%q is not host:port", c.Addr)
+	} else {
+		c.PublicBaseURL = "http://localhost:" + port
+	}
+
+	c.DatabaseURL = str("DATABASE_URL")
+	c.AutoMigrate = flag("AUTO_MIGRATE")
+	c.SessionSecret = str("SESSION_SECRET")
+	c.AdminPassword = get("ADMIN_PASSWORD")
+	c.SecureCookies = flag("SECURE_COOKIES")
+
+	duration := func(key string, into *time.Duration) {
+		v := str(key)
+		if v == "" {
+			return
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			bad(key, "%q is not a positive duration like 10m", v)
+		} else {
+			*into = d
+		}
+	}
+	duration("CHECKIN_UNDO_WINDOW", &c.CheckinUndoWindow)
+	duration("CHECKIN_OPENS_BEFORE", &c.CheckinOpensBefore)
+	duration("CHECKIN_CLOSES_AFTER", &c.CheckinClosesAfter)
+	// Label printers are set per campus now; a leftover server-wide one
+	// would silently stop printing, so say where it went.
+	if str("LABEL_PRINTER") != "" {
+		bad("LABEL_PRINTER", "no longer used; set each campus's label printer under Admin → Campus")
+	}
+
+	c.Telegram.BotToken = str("TG_BOT_TOKEN")
+	c.Telegram.WebhookSecret = str("TG_WEBHOOK_SECRET")
+	c.Telegram.Reminders = flag("TG_ENABLE_REMINDERS")
+	c.Telegram.RemindWaitlist = flag("REMIND_INCLUDE_WAITLIST")
+	if v := str("REMIND_OFFSETS"); v != "" {
+		var offs []time.Duration
+		for _, p := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(p))
+			if err != nil || d <= 0 {
+				bad("REMIND_OFFSETS", "%q is not a positive duration", strings.TrimSpace(p))
+				continue
+			}
+			offs = append(offs, d)
+		}
+		if len(offs) > 0 {
+			c.Telegram.RemindOffsets = offs
+		}
+	}
+	if c.Telegram.Reminders && c.Telegram.BotToken == "" {
+		bad("TG_ENABLE_REMINDERS", "reminders need TG_BOT_TOKEN")
+	}
+
+	if v := str("PUBLIC_BASE_URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.RawQuery != "" || u.Fragment != "" {
+			bad("PUBLIC_BASE_URL", "%q is not an http(s)://host[/path] URL", v)
+		} else {
+			c.PublicBaseURL = strings.TrimRight(v, "/")
+		}
+	} else if c.Telegram.BotToken != "" {
+		// Links sent to Telegram must reach parents' phones, not localhost.
+		bad("PUBLIC_BASE_URL", "required when TG_BOT_TOKEN is set")
+	}
+
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("config: %s", strings.Join(errs, "; "))
+	}
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func TestParseDefaults(t *testing.T) {
+	c, err := parse(env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":8080" || c.PublicBaseURL != "http://localhost:8080" {
+		t.Errorf("addr/base = %q %q", c.Addr, c.PublicBaseURL)
+	}
+	if c.CheckinUndoWindow != 10*time.Minute || len(c.Telegram.RemindOffsets) != 2 {
+		t.Errorf("defaults = %+v", c)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	c, err := parse(env(map[string]string{
+		"ADDR":                 ":9000",
+		"PUBLIC_BASE_URL":      "https://nextgen.lojf.id/",
+		"SECURE_COOKIES":       "yes",
+		"CHECKIN_UNDO_WINDOW":  "5m",
+		"CHECKIN_OPENS_BEFORE": "45m",
+		"TG_BOT_TOKEN":         "123:abc",
+		"TG_ENABLE_REMINDERS":  "1",
+		"REMIND_OFFSETS":       "24h, 1h",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.URL("/qr/REG-1.png"); got != "https://nextgen.lojf.id/qr/REG-1.png" {
+		t.Errorf("URL = %q", got)
+	}
+	if !c.SecureCookies || c.CheckinUndoWindow != 5*time.Minute {
+		t.Errorf("parsed = %+v", c)
+	}
+	if c.CheckinOpensBefore != 45*time.Minute || c.CheckinClosesAfter != time.Hour {
+		t.Errorf("check-in window = %v before, %v after", c.CheckinOpensBefore, c.CheckinClosesAfter)
+	}
+	if !c.Telegram.Reminders || len(c.Telegram.RemindOffsets) != 2 || c.Telegram.RemindOffsets[1] != time.Hour {
+		t.Errorf("telegram = %+v", c.Telegram)
+	}
+}
+
+// TestParseFailsFast checks that every bad value is reported in one error.
+func TestParseFailsFast(t *testing.T) {
+	_, err := parse(env(map[string]string{
+		"PUBLIC_BASE_URL":      "nextgen.lojf.id",
+		"SECURE_COOKIES":       "maybe",
+		"CHECKIN_UNDO_WINDOW":  "ten",
+		"CHECKIN_CLOSES_AFTER": "0",
+		"LABEL_PRINTER":        "zpl://10.0.0.5", // per campus now
+		"REMIND_OFFSETS":       "24h,-1h",
+	}))
+	if err == nil {
+		t.Fatal("bad config accepted")
+	}
+	for _, key := range []string{"PUBLIC_BASE_URL", "SECURE_COOKIES", "CHECKIN_UNDO_WINDOW", "CHECKIN_CLOSES_AFTER", "LABEL_PRINTER", "REMIND_OFFSETS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+
+	// The bot sends links to phones; localhost will not do.
+	if _, err := parse(env(map[string]string{"TG_BOT_TOKEN": "123:abc"})); err == nil ||
+		!strings.Contains(err.Error(), "PUBLIC_BASE_URL") {
+		t.Errorf("bot token without base URL: err = %v", err)
+	}
+}
+
+func TestLoadFileUnderEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nextgen.conf")
+	body := "# site\nPUBLIC_BASE_URL=\"https://file.example\"\nCHECKIN_UNDO_WINDOW=3m\n" +
+		"DATABASE_URL=sqlite:///var/lib/lojf/nextgen.db\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHECKIN_UNDO_WINDOW", "7m")
+	t.Setenv("DATABASE_URL", "")
+	os.Unsetenv("DATABASE_URL") // restored by t.Setenv
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PublicBaseURL != "https://file.example" || c.CheckinUndoWindow != 7*time.Minute {
+		t.Errorf("got base %q, undo %v", c.PublicBaseURL, c.CheckinUndoWindow)
+	}
+	if c.DatabaseURL != "sqlite:///var/lib/lojf/nextgen.db" {
+		t.Errorf("DATABASE_URL from the file = %q", c.DatabaseURL)
+	}
+}
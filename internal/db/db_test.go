@@ -1,7 +1,6 @@
 package db_test
 
 import (
-	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
@@ -43,42 +42,45 @@ func TestInit_CreatesIndexes(t *testing.T) {
 	if err := os.Chdir(dir); err != nil {
 		t.Fatalf("chdir: %v", err)
 	}
-
-	if err := db.Init(); err != nil {
+	if err := db.Init(""); err != nil {
 		t.Fatalf("Init: %v", err)
 	}
-
-	sqlDB, err := db.Conn().DB()
-	if err != nil {
-		t.Fatalf("get sql.DB: %v", err)
+	if _, err := os.Stat(filepath.Join(dir, "nextgen.db")); err != nil {
+		t.Errorf("default database URL did not create nextgen.db: %v", err)
 	}
 
-	found := indexNames(t, sqlDB, "registrations")
 	for _, want := range []string{"idx_reg_class_status", "idx_reg_parent"} {
-		if !found[want] {
-			t.Errorf("index %q missing from registrations table; found: %v", want, found)
+		if !hasIndex(t, "registrations", want) {
+			t.Errorf("index %q missing from registrations table", want)
 		}
 	}
 }
 
-func indexNames(t *testing.T, sqlDB *sql.DB, table string) map[string]bool {
-	t.Helper()
-	rows, err := sqlDB.Query("PRAGMA index_list(" + table + ")")
-	if err != nil {
-		t.Fatalf("PRAGMA index_list: %v", err)
+func TestOpen_HonoursDatabaseURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "elsewhere.db")
+	if err := db.Open("sqlite://" + path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := db.Dialect().Name(); got != "sqlite" {
+		t.Errorf("dialect = %q, want sqlite", got)
 	}
-	defer rows.Close()
+	if _, err := db.Status(); err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at DATABASE_URL path: %v", err)
+	}
+}
 
-	out := make(map[string]bool)
-	for rows.Next() {
-		var seq int
-		var name string
-		var unique bool
-		var origin, partial string
-		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
-			t.Fatalf("scan: %v", err)
+func TestOpenURL_RejectsUnknownScheme(t *testing.T) {
+	for _, url := range []string{"mysql://root@localhost/nextgen", "nextgen.db", "sqlite://"} {
+		if err := db.OpenURL(url); err == nil {
+			t.Errorf("OpenURL(%q) succeeded, want an error", url)
 		}
-		out[name] = true
 	}
-	return out
+}
+
+func hasIndex(t *testing.T, table, name string) bool {
+	t.Helper()
+	return db.Conn().Migrator().HasIndex(table, name)
 }
@@ -0,0 +1,86 @@
+// Package dbtest hands tests a throwaway database: a SQLite file in a temp
+// directory by default, or a fresh schema on the Postgres named by
+// TEST_DATABASE_URL when one is configured. DATABASE_URL is never read, so a
+// developer's shell pointing at a real database is safe.
+package dbtest
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/lojf/nextgen/internal/db"
+)
+
+// Open points db.Conn at an empty database that lives as long as the test.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	if err := db.OpenURL(URL(t)); err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	conn := db.Conn()
+	conn.Logger = logger.Default.LogMode(logger.Silent)
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// Init is Open plus every migration.
+func Init(t testing.TB) *gorm.DB {
+	t.Helper()
+	conn := Open(t)
+	if _, err := db.MigrateUp(io.Discard, false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return conn
+}
+
+// Postgres reports whether tests run against TEST_DATABASE_URL.
+func Postgres() bool { return os.Getenv("TEST_DATABASE_URL") != "" }
+
+// URL returns a database URL private to t.
+func URL(t testing.TB) string {
+	t.Helper()
+	base := os.Getenv("TEST_DATABASE_URL")
+	if base == "" {
+		return "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	}
+
+	// One schema per test keeps tests independent on a shared server.
+	admin, err := gorm.Open(postgres.Open(base), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec(`CREATE SCHEMA "` + schema + `"`).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA "` + schema + `" CASCADE`)
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	u, err := url.Parse(base)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
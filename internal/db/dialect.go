@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLDialect covers the few places where SQLite and Postgres disagree. Raw SQL
+// elsewhere sticks to the common subset; anything that cannot goes here.
+type SQLDialect interface {
+	Name() string
+	// PhoneDigits wraps a text expression so it yields only the digits of a
+	// phone number: "+62 811-2345" -> "628112345".
+	PhoneDigits(expr string) string
+}
+
+var dialect SQLDialect = sqliteDialect
+
+// Dialect reports the SQL dialect of the open connection.
+func Dialect() SQLDialect { return dialect }
+
+var (
+	sqliteDialect   SQLDialect = sqliteSQL{}
+	postgresDialect SQLDialect = postgresSQL{}
+)
+
+type sqliteSQL struct{}
+
+func (sqliteSQL) Name() string { return "sqlite" }
+
+// SQLite has no regex replace; strip what NormPhone lets through.
+func (sqliteSQL) PhoneDigits(expr string) string {
+	for _, ch := range []string{"+", " ", "-", "(", ")"} {
+		expr = "REPLACE(" + expr + ",'" + ch + "','')"
+	}
+	return expr
+}
+
+type postgresSQL struct{}
+
+func (postgresSQL) Name() string { return "postgres" }
+
+func (postgresSQL) PhoneDigits(expr string) string {
+	return "regexp_replace(" + expr + ", '[^0-9]', '', 'g')"
+}
+
+// AggTime scans a timestamp that went through MIN/MAX. Postgres hands back a
+// time.Time; SQLite loses the column type in an aggregate and returns the
+// stored text, e.g. "2026-06-27 17:16:21.61+07:00".
+type AggTime struct {
+	Time  time.Time
+	Valid bool // false when the aggregate was NULL
+}
+
+// sqliteTimeLayouts are the shapes the SQLite driver writes, newest first.
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (t *AggTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = AggTime{}
+		return nil
+	case time.Time:
+		*t = AggTime{Time: v, Valid: true}
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("AggTime: cannot scan %T", src)
+}
+
+func (t *AggTime) parse(s string) error {
+	s = strings.TrimSpace(s)
+	for _, layout := range sqliteTimeLayouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			*t = AggTime{Time: ts, Valid: true}
+			return nil
+		}
+	}
+	// Odd precision or zone suffix: the calendar day is still worth having.
+	if len(s) >= 10 {
+		if ts, err := time.Parse("2006-01-02", s[:10]); err == nil {
+			*t = AggTime{Time: ts, Valid: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("AggTime: unrecognised timestamp %q", s)
+}
+
+func (t AggTime) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.Time, nil
+}
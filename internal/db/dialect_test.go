@@ -0,0 +1,65 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestPhoneDigits_MatchesFormattedNumbers(t *testing.T) {
+	conn := dbtest.Init(t)
+	h := models.Household{Name: "test"}
+	if err := conn.Create(&h).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, phone := range []string{"+62 (811) 234-5678", "0811-999-000"} {
+		if err := conn.Create(&models.Parent{HouseholdID: h.ID, Name: phone, Phone: phone}).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+
+	var got []string
+	conn.Model(&models.Parent{}).
+		Where(db.Dialect().PhoneDigits("phone")+" = ?", "628112345678").
+		Pluck("phone", &got)
+	if len(got) != 1 || got[0] != "+62 (811) 234-5678" {
+		t.Errorf("exact digits match = %v", got)
+	}
+
+	got = nil
+	conn.Model(&models.Parent{}).
+		Where(db.Dialect().PhoneDigits("phone")+" LIKE ?", "%999%").
+		Pluck("phone", &got)
+	if len(got) != 1 || got[0] != "0811-999-000" {
+		t.Errorf("partial digits match = %v", got)
+	}
+}
+
+func TestAggTime_ScansMaxOfTimestamp(t *testing.T) {
+	conn := dbtest.Init(t)
+	jkt := time.FixedZone("WIB", 7*3600)
+	early := time.Date(2026, 6, 20, 9, 0, 0, 0, jkt)
+	late := time.Date(2026, 6, 27, 17, 16, 21, 610000000, jkt)
+	for _, d := range []time.Time{early, late} {
+		conn.Create(&models.Class{Name: "K", Date: d, Capacity: 1})
+	}
+
+	var row struct {
+		Last  db.AggTime
+		Never db.AggTime
+	}
+	if err := conn.Model(&models.Class{}).
+		Select("MAX(date) AS last, MAX(signup_opens_at) AS never").
+		Scan(&row).Error; err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if !row.Last.Valid || !row.Last.Time.Equal(late) {
+		t.Errorf("MAX(date) = %+v, want %v", row.Last, late)
+	}
+	if row.Never.Valid {
+		t.Errorf("MAX over NULLs = %+v, want invalid", row.Never)
+	}
+}
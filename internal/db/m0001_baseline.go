@@ -0,0 +1,208 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0001Baseline is the schema as AutoMigrate left it before migrations
+// existed. On a database that already has these tables it is a no-op, so an
+// existing install simply records it as applied on first `migrate up`.
+var m0001Baseline = Migration{
+	ID: "0001_baseline",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(
+			&m0001Parent{},
+			&m0001Child{},
+			&m0001Class{},
+			&m0001Registration{},
+			&m0001TelegramUser{},
+			&m0001LinkCode{},
+			&m0001ClassQuestion{},
+			&m0001ClassTemplate{},
+			&m0001ClassTemplateQuestion{},
+			&m0001RegistrationAnswer{},
+			&m0001AdminUser{},
+			&m0001AuditLog{},
+			&m0001AppSetting{},
+		); err != nil {
+			return err
+		}
+		// Composite indexes that GORM doesn't auto-create from struct tags.
+		return SQL(
+			"CREATE INDEX IF NOT EXISTS idx_reg_class_status ON registrations(class_id, status)",
+			"CREATE INDEX IF NOT EXISTS idx_reg_parent ON registrations(parent_id)",
+		)(tx)
+	},
+	// Dropping every table is never what anyone means by "down".
+	Down: nil,
+}
+
+type m0001Parent struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Phone     string `gorm:"uniqueIndex;not null"`
+	Email     string
+	Children  []m0001Child `gorm:"foreignKey:ParentID"`
+}
+
+func (m0001Parent) TableName() string { return "parents" }
+
+type m0001Child struct {
+	ID        uint `gorm:"primaryKey"`
+	ParentID  uint `gorm:"index"`
+	Name      string
+	BirthDate time.Time
+	Gender    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m0001Child) TableName() string { return "children" }
+
+type m0001Class struct {
+	ID            uint `gorm:"primaryKey"`
+	Name          string
+	Date          time.Time
+	Capacity      int
+	Description   string `gorm:"type:text"`
+	SignupOpensAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (m0001Class) TableName() string { return "classes" }
+
+type m0001Registration struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ParentID    uint
+	ChildID     uint
+	ClassID     uint
+	Status      string
+	Code        string `gorm:"uniqueIndex"`
+	CheckInAt   *time.Time
+	CheckedInBy string
+}
+
+func (m0001Registration) TableName() string { return "registrations" }
+
+type m0001TelegramUser struct {
+	ID             uint  `gorm:"primarykey"`
+	TelegramUserID int64 `gorm:"uniqueIndex"`
+	ChatID         int64
+	Username       string
+	FirstName      string
+	Language       string
+	Phone          string
+	ParentID       *uint
+	LinkedAt       *time.Time
+	Deliverable    bool `gorm:"default:true"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (m0001TelegramUser) TableName() string { return "telegram_users" }
+
+type m0001LinkCode struct {
+	ID        uint      `gorm:"primarykey"`
+	Code      string    `gorm:"uniqueIndex"`
+	ParentID  uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m0001LinkCode) TableName() string { return "link_codes" }
+
+type m0001ClassQuestion struct {
+	ID         uint  `gorm:"primaryKey"`
+	ClassID    *uint `gorm:"index"`
+	TemplateID *uint `gorm:"index"`
+	Label      string
+	Kind       string
+	Choices    string
+	Required   bool
+	Position   int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (m0001ClassQuestion) TableName() string { return "class_questions" }
+
+type m0001ClassTemplate struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"size:200;not null"`
+	Description string `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Questions   []m0001ClassTemplateQuestion `gorm:"foreignKey:TemplateID"`
+}
+
+func (m0001ClassTemplate) TableName() string { return "class_templates" }
+
+type m0001ClassTemplateQuestion struct {
+	ID         uint   `gorm:"primaryKey"`
+	TemplateID uint   `gorm:"index;not null"`
+	Label      string `gorm:"size:255;not null"`
+	Kind       string `gorm:"size:20;not null"`
+	Options    string `gorm:"type:text"`
+	Required   bool   `gorm:"not null"`
+	Position   int    `gorm:"not null;default:0"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (m0001ClassTemplateQuestion) TableName() string { return "class_template_questions" }
+
+type m0001RegistrationAnswer struct {
+	ID             uint   `gorm:"primaryKey"`
+	RegistrationID uint   `gorm:"index;not null"`
+	QuestionID     uint   `gorm:"index;not null"`
+	Answer         string `gorm:"type:TEXT;not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (m0001RegistrationAnswer) TableName() string { return "registration_answers" }
+
+type m0001AdminUser struct {
+	ID        uint   `gorm:"primaryKey"`
+	Username  string `gorm:"uniqueIndex;not null"`
+	PassHash  string `gorm:"not null"`
+	Role      string `gorm:"index;not null"`
+	Campus    string `gorm:"index"`
+	Active    bool   `gorm:"not null;default:true"`
+	LastLogin *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m0001AdminUser) TableName() string { return "admin_users" }
+
+type m0001AuditLog struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+	Username  string    `gorm:"index"`
+	Role      string
+	StaffedBy string
+	IP        string
+	Action    string `gorm:"index"`
+	Target    string
+	Detail    string
+}
+
+func (m0001AuditLog) TableName() string { return "audit_logs" }
+
+type m0001AppSetting struct {
+	Key       string `gorm:"primaryKey"`
+	Value     string
+	UpdatedAt time.Time
+}
+
+func (m0001AppSetting) TableName() string { return "app_settings" }
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// m0002Campuses introduces the campuses table. Classes and check-in accounts
+// get a campus_id, backfilled from the two conventions that came before it:
+// the first word of the class name, and the free-text admin_users.campus
+// column, which is dropped once converted.
+var m0002Campuses = Migration{
+	ID: "0002_campuses",
+	// Columns are added with plain ALTER TABLE: letting AutoMigrate attach the
+	// foreign key makes SQLite rebuild the table, which silently drops every
+	// index that the (partial) migration struct does not declare.
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&m0002Campus{}); err != nil {
+			return err
+		}
+		if err := SQL(
+			"ALTER TABLE classes ADD COLUMN \"campus_id\" integer REFERENCES campuses(id)",
+			"CREATE INDEX idx_classes_campus_id ON classes(campus_id)",
+			"ALTER TABLE admin_users ADD COLUMN \"campus_id\" integer REFERENCES campuses(id)",
+			"CREATE INDEX idx_admin_users_campus_id ON admin_users(campus_id)",
+		)(tx); err != nil {
+			return err
+		}
+		if err := backfillCampuses(tx); err != nil {
+			return err
+		}
+		return SQL(
+			"DROP INDEX IF EXISTS idx_admin_users_campus",
+			"ALTER TABLE admin_users DROP COLUMN campus",
+		)(tx)
+	},
+	Down: func(tx *gorm.DB) error {
+		// Bring the text column back first, while campus_id still says
+		// where each account belongs.
+		if err := tx.AutoMigrate(&m0001AdminUser{}); err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE admin_users SET campus =
+			(SELECT code FROM campuses WHERE campuses.id = admin_users.campus_id)`).Error; err != nil {
+			return err
+		}
+		if err := SQL(
+			"DROP INDEX IF EXISTS idx_classes_campus_id",
+			"DROP INDEX IF EXISTS idx_admin_users_campus_id",
+		)(tx); err != nil {
+			return err
+		}
+		m := tx.Migrator()
+		if err := m.DropColumn(&m0002Class{}, "campus_id"); err != nil {
+			return err
+		}
+		if err := m.DropColumn(&m0002AdminUser{}, "campus_id"); err != nil {
+			return err
+		}
+		// On SQLite the DropColumns rebuilt both tables; restore their indexes.
+		if err := tx.AutoMigrate(&m0001Class{}, &m0001AdminUser{}); err != nil {
+			return err
+		}
+		return m.DropTable(&m0002Campus{})
+	},
+}
+
+type m0002Campus struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"uniqueIndex;not null"`
+	Name      string
+	Address   string
+	TimeZone  string `gorm:"not null;default:Asia/Jakarta"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m0002Campus) TableName() string { return "campuses" }
+
+type m0002Class struct {
+	ID       uint `gorm:"primaryKey"`
+	Name     string
+	CampusID *uint        `gorm:"index"`
+	Campus   *m0002Campus `gorm:"foreignKey:CampusID"`
+}
+
+func (m0002Class) TableName() string { return "classes" }
+
+type m0002AdminUser struct {
+	ID       uint `gorm:"primaryKey"`
+	CampusID *uint
+}
+
+func (m0002AdminUser) TableName() string { return "admin_users" }
+
+// backfillCampuses gives classes and check-in accounts a real campus_id.
+// Rows that already carry one are left alone.
+func backfillCampuses(tx *gorm.DB) error {
+	byCode := map[string]uint{}
+	var existing []m0002Campus
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, c := range existing {
+		byCode[strings.ToUpper(c.Code)] = c.ID
+	}
+
+	ensure := func(code, name string) (uint, error) {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if id, ok := byCode[code]; ok {
+			return id, nil
+		}
+		if name == "" {
+			name = code
+		}
+		c := m0002Campus{Code: code, Name: name, TimeZone: "Asia/Jakarta"}
+		if err := tx.Create(&c).Error; err != nil {
+			return 0, err
+		}
+		byCode[code] = c.ID
+		return c.ID, nil
+	}
+
+	// 1) Classes: "FJB Little Stars (Feast Jakarta Barat)" -> FJB.
+	var classes []m0002Class
+	if err := tx.Where("campus_id IS NULL").Find(&classes).Error; err != nil {
+		return err
+	}
+	for _, cl := range classes {
+		code := models.CampusCodeFromClassName(cl.Name)
+		if code == "" {
+			continue
+		}
+		id, err := ensure(code, models.CampusNameFromClassName(cl.Name))
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&m0002Class{}).Where("id = ?", cl.ID).Update("campus_id", id).Error; err != nil {
+			return err
+		}
+	}
+
+	// 2) Accounts: fresh installs never had the legacy text column.
+	if !tx.Migrator().HasColumn(&m0001AdminUser{}, "campus") {
+		return nil
+	}
+	var users []m0001AdminUser
+	if err := tx.Select("id", "campus").
+		Where("campus_id IS NULL AND campus IS NOT NULL AND campus <> ''").
+		Find(&users).Error; err != nil {
+		return err
+	}
+	for _, u := range users {
+		id, err := ensure(u.Campus, "")
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&m0002AdminUser{}).Where("id = ?", u.ID).Update("campus_id", id).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// TestInit_BackfillsCampuses upgrades a database from before the campuses
+// table: campus lived in the class name and in a free-text admin_users.campus.
+// Production databases of that age are all SQLite, so this test always is.
+func TestInit_BackfillsCampuses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nextgen.db")
+	legacy, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	for _, stmt := range []string{
+		// DDL exactly as AutoMigrate produced it before campus_id existed.
+		"CREATE TABLE `classes` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`date` datetime,`capacity` integer," +
+			"`description` text,`signup_opens_at` datetime,`created_at` datetime,`updated_at` datetime)",
+		"CREATE TABLE `admin_users` (`id` integer PRIMARY KEY AUTOINCREMENT,`username` text NOT NULL,`pass_hash` text NOT NULL," +
+			"`role` text NOT NULL,`campus` text,`active` numeric NOT NULL DEFAULT true,`last_login` datetime," +
+			"`created_at` datetime,`updated_at` datetime)",
+		"CREATE INDEX `idx_admin_users_campus` ON `admin_users`(`campus`)",
+		"CREATE INDEX `idx_admin_users_role` ON `admin_users`(`role`)",
+		"CREATE UNIQUE INDEX `idx_admin_users_username` ON `admin_users`(`username`)",
+		`INSERT INTO classes (name, capacity) VALUES
+			('FJB Little Stars (Feast Jakarta Barat)', 10),
+			('FJB - Stars Club (Feast Jakarta Barat)', 10),
+			('FJU Awesome Kids (Feast Jakarta Utara)', 10)`,
+		`INSERT INTO admin_users (username, pass_hash, role, campus) VALUES
+			('boss', 'x', 'admin', ''),
+			('fjb-checkin', 'x', 'checkin', 'fjb'),
+			('fjs-checkin', 'x', 'checkin', 'FJS')`,
+	} {
+		if err := legacy.Exec(stmt).Error; err != nil {
+			t.Fatalf("seed legacy schema: %v", err)
+		}
+	}
+	if sqlDB, err := legacy.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	if err := db.OpenURL("sqlite://" + path); err != nil {
+		t.Fatalf("OpenURL: %v", err)
+	}
+	if _, err := db.MigrateUp(io.Discard, false); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+
+	var campuses []models.Campus
+	db.Conn().Order("code").Find(&campuses)
+	ids := map[string]uint{}
+	for _, c := range campuses {
+		ids[c.Code] = c.ID
+	}
+	if len(ids) != 3 || ids["FJB"] == 0 || ids["FJU"] == 0 || ids["FJS"] == 0 {
+		t.Fatalf("campuses after backfill = %+v, want FJB, FJS, FJU", campuses)
+	}
+	var fjb models.Campus
+	db.Conn().First(&fjb, ids["FJB"])
+	if fjb.Name != "Feast Jakarta Barat" {
+		t.Errorf("FJB name = %q, want it taken from the class name", fjb.Name)
+	}
+
+	var classes []models.Class
+	db.Conn().Order("id").Find(&classes)
+	for i, want := range []string{"FJB", "FJB", "FJU"} {
+		if classes[i].CampusID == nil || *classes[i].CampusID != ids[want] {
+			t.Errorf("class %q campus_id = %v, want %s", classes[i].Name, classes[i].CampusID, want)
+		}
+	}
+
+	var users []models.AdminUser
+	db.Conn().Order("id").Find(&users)
+	if users[0].CampusID != nil {
+		t.Errorf("admin with empty campus got campus_id %d", *users[0].CampusID)
+	}
+	if users[1].CampusID == nil || *users[1].CampusID != ids["FJB"] {
+		t.Errorf("fjb-checkin campus_id = %v, want FJB", users[1].CampusID)
+	}
+	if users[2].CampusID == nil || *users[2].CampusID != ids["FJS"] {
+		t.Errorf("fjs-checkin campus_id = %v, want FJS", users[2].CampusID)
+	}
+
+	// A second boot must not duplicate anything.
+	if err := db.OpenURL("sqlite://" + path); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, err := db.MigrateUp(io.Discard, false); err != nil {
+		t.Fatalf("second MigrateUp: %v", err)
+	}
+	var n int64
+	db.Conn().Model(&models.Campus{}).Count(&n)
+	if n != 3 {
+		t.Errorf("campuses after second Init = %d, want 3", n)
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// m0003SoftDelete gives parents, children, classes and registrations a
+// deleted_at column so admin deletes go to the trash instead of taking a
+// family's attendance history with them.
+//
+// A parent's phone stays unique among live rows only: someone whose old
+// record sits in the trash can still register again.
+var m0003SoftDelete = Migration{
+	ID: "0003_soft_delete",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, t := range m0003Tables {
+			if err := m.AddColumn(t, "DeletedAt"); err != nil {
+				return err
+			}
+			if err := m.CreateIndex(t, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		return SQL(
+			"DROP INDEX IF EXISTS idx_parents_phone",
+			"CREATE UNIQUE INDEX idx_parents_phone ON parents(phone) WHERE deleted_at IS NULL",
+		)(tx)
+	},
+	// Before this step a delete was final, so trashed rows go for good rather
+	// than coming back to life.
+	Down: func(tx *gorm.DB) error {
+		if err := SQL(
+			"DELETE FROM registration_answers WHERE registration_id IN (SELECT id FROM registrations WHERE deleted_at IS NOT NULL)",
+			"DELETE FROM registrations WHERE deleted_at IS NOT NULL",
+			"DELETE FROM children WHERE deleted_at IS NOT NULL",
+			"DELETE FROM class_questions WHERE class_id IN (SELECT id FROM classes WHERE deleted_at IS NOT NULL)",
+			"DELETE FROM classes WHERE deleted_at IS NOT NULL",
+			"DELETE FROM parents WHERE deleted_at IS NOT NULL",
+			"DROP INDEX IF EXISTS idx_parents_phone",
+			"CREATE UNIQUE INDEX idx_parents_phone ON parents(phone)",
+		)(tx); err != nil {
+			return err
+		}
+		for _, table := range []string{"parents", "children", "classes", "registrations"} {
+			// Plain DROP COLUMN: on SQLite the migrator would rebuild the
+			// table and lose its other indexes.
+			if err := SQL(
+				"DROP INDEX IF EXISTS idx_"+table+"_deleted_at",
+				"ALTER TABLE "+table+" DROP COLUMN deleted_at",
+			)(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var m0003Tables = []any{&m0003Parent{}, &m0003Child{}, &m0003Class{}, &m0003Registration{}}
+
+type m0003Parent struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0003Parent) TableName() string { return "parents" }
+
+type m0003Child struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0003Child) TableName() string { return "children" }
+
+type m0003Class struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0003Class) TableName() string { return "classes" }
+
+type m0003Registration struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0003Registration) TableName() string { return "registrations" }
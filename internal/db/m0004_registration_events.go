@@ -0,0 +1,33 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0004RegistrationEvents adds the per-registration status history. Existing
+// registrations start with an empty history; nothing is backfilled, because
+// guessing who did what would be worse than admitting we do not know.
+var m0004RegistrationEvents = Migration{
+	ID: "0004_registration_events",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&m0004RegistrationEvent{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&m0004RegistrationEvent{})
+	},
+}
+
+type m0004RegistrationEvent struct {
+	ID             uint      `gorm:"primaryKey"`
+	RegistrationID uint      `gorm:"index;not null"`
+	CreatedAt      time.Time `gorm:"index"`
+
+	Kind   string `gorm:"not null"`
+	Actor  string
+	Source string
+	Note   string
+}
+
+func (m0004RegistrationEvent) TableName() string { return "registration_events" }
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0005Households groups parents into households so a family can have more
+// than one guardian, each with their own phone. Every existing parent becomes
+// the only guardian of a new household that owns their children and
+// registrations; trashed parents included, so a restore finds them whole.
+var m0005Households = Migration{
+	ID: "0005_households",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&m0005Household{}); err != nil {
+			return err
+		}
+		// Plain ALTER TABLE for the same reason as 0002: no table rebuilds.
+		for _, table := range m0005Tables {
+			if err := SQL(
+				"ALTER TABLE "+table+" ADD COLUMN \"household_id\" integer REFERENCES households(id)",
+				"CREATE INDEX idx_"+table+"_household_id ON "+table+"(household_id)",
+			)(tx); err != nil {
+				return err
+			}
+		}
+
+		var parents []m0005Parent
+		if err := tx.Where("household_id IS NULL").Order("id").Find(&parents).Error; err != nil {
+			return err
+		}
+		for _, p := range parents {
+			h := m0005Household{Name: p.Name, CreatedAt: p.CreatedAt, UpdatedAt: time.Now()}
+			if err := tx.Create(&h).Error; err != nil {
+				return err
+			}
+			for _, table := range m0005Tables {
+				col := "parent_id"
+				if table == "parents" {
+					col = "id"
+				}
+				if err := tx.Exec("UPDATE "+table+" SET household_id = ? WHERE "+col+" = ?", h.ID, p.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	// Children and registrations stay with the guardian in their parent_id;
+	// a household that had two guardians splits back into two families.
+	Down: func(tx *gorm.DB) error {
+		for _, table := range m0005Tables {
+			if err := SQL(
+				"DROP INDEX IF EXISTS idx_"+table+"_household_id",
+				"ALTER TABLE "+table+" DROP COLUMN household_id",
+			)(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropTable(&m0005Household{})
+	},
+}
+
+var m0005Tables = []string{"parents", "children", "registrations"}
+
+type m0005Household struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (m0005Household) TableName() string { return "households" }
+
+type m0005Parent struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time
+}
+
+func (m0005Parent) TableName() string { return "parents" }
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0006Pickups adds the per-child authorized pickup list and the check-out
+// columns on registrations. Past attendance has no check-out; it stays empty.
+var m0006Pickups = Migration{
+	ID: "0006_pickups",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&m0006PickupPerson{}); err != nil {
+			return err
+		}
+		m := tx.Migrator()
+		for _, col := range m0006RegColumns {
+			if err := m.AddColumn(&m0006Registration{}, col); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		// Plain DROP COLUMN, as in 0003, so SQLite keeps the other indexes.
+		if err := SQL(
+			"ALTER TABLE registrations DROP COLUMN check_out_at",
+			"ALTER TABLE registrations DROP COLUMN checked_out_by",
+			"ALTER TABLE registrations DROP COLUMN picked_up_by",
+		)(tx); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&m0006PickupPerson{})
+	},
+}
+
+var m0006RegColumns = []string{"CheckOutAt", "CheckedOutBy", "PickedUpBy"}
+
+type m0006PickupPerson struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ChildID      uint   `gorm:"index;not null"`
+	Name         string `gorm:"not null"`
+	Relation     string
+	Phone        string
+	Photo        []byte
+	PhotoType    string
+	DoNotRelease bool `gorm:"not null;default:false"`
+	Note         string
+}
+
+func (m0006PickupPerson) TableName() string { return "pickup_persons" }
+
+type m0006Registration struct {
+	ID           uint `gorm:"primaryKey"`
+	CheckOutAt   *time.Time
+	CheckedOutBy string
+	PickedUpBy   string
+}
+
+func (m0006Registration) TableName() string { return "registrations" }
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// m0007SecurityCodes adds the check-in security code on registrations and the
+// allergy and medical notes printed next to it on the name tag. Children
+// already checked in have no code, so their check-out does not ask for one.
+var m0007SecurityCodes = Migration{
+	ID: "0007_security_codes",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.AddColumn(&m0007Registration{}, "SecurityCode"); err != nil {
+			return err
+		}
+		for _, col := range []string{"Allergies", "MedicalNotes"} {
+			if err := m.AddColumn(&m0007Child{}, col); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: SQL(
+		"ALTER TABLE registrations DROP COLUMN security_code",
+		"ALTER TABLE children DROP COLUMN allergies",
+		"ALTER TABLE children DROP COLUMN medical_notes",
+	),
+}
+
+type m0007Registration struct {
+	ID           uint `gorm:"primaryKey"`
+	SecurityCode string
+}
+
+func (m0007Registration) TableName() string { return "registrations" }
+
+type m0007Child struct {
+	ID           uint `gorm:"primaryKey"`
+	Allergies    string
+	MedicalNotes string
+}
+
+func (m0007Child) TableName() string { return "children" }
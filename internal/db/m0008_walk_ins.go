@@ -0,0 +1,22 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// m0008WalkIns adds the per-class walk-in allowance: seats above capacity that
+// only the check-in station can fill. Existing classes get none.
+var m0008WalkIns = Migration{
+	ID: "0008_walk_ins",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&m0008Class{}, "WalkInSeats")
+	},
+	Down: SQL("ALTER TABLE classes DROP COLUMN walk_in_seats"),
+}
+
+type m0008Class struct {
+	ID          uint `gorm:"primaryKey"`
+	WalkInSeats int  `gorm:"not null;default:0"`
+}
+
+func (m0008Class) TableName() string { return "classes" }
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0009StationSyncs adds the idempotency log for check-ins that stations
+// recorded offline and uploaded later.
+var m0009StationSyncs = Migration{
+	ID: "0009_station_syncs",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&m0009StationSync{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&m0009StationSync{})
+	},
+}
+
+type m0009StationSync struct {
+	ID             uint   `gorm:"primaryKey"`
+	IdemKey        string `gorm:"uniqueIndex;not null"`
+	RegistrationID uint   `gorm:"index"`
+	Device         string
+	ClientAt       time.Time
+	Outcome        string
+	Reason         string
+	CreatedAt      time.Time
+}
+
+func (m0009StationSync) TableName() string { return "station_syncs" }
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0010FamilyCodes adds the per-guardian, per-day family check-in codes.
+var m0010FamilyCodes = Migration{
+	ID: "0010_family_codes",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&m0010FamilyCode{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&m0010FamilyCode{})
+	},
+}
+
+type m0010FamilyCode struct {
+	ID          uint   `gorm:"primaryKey"`
+	Code        string `gorm:"uniqueIndex;not null"`
+	ParentID    uint   `gorm:"uniqueIndex:idx_family_codes_parent_day;not null"`
+	Day         string `gorm:"uniqueIndex:idx_family_codes_parent_day;not null"`
+	HouseholdID uint   `gorm:"index;not null"`
+	CreatedAt   time.Time
+}
+
+func (m0010FamilyCode) TableName() string { return "family_codes" }
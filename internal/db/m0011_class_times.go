@@ -0,0 +1,38 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0011ClassTimes adds when a class runs and where. Existing classes keep
+// empty times and are treated as lasting all day, as before.
+var m0011ClassTimes = Migration{
+	ID: "0011_class_times",
+	Up: func(tx *gorm.DB) error {
+		for _, col := range []string{"StartsAt", "EndsAt", "Room"} {
+			if err := tx.Migrator().AddColumn(&m0011Class{}, col); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, col := range []string{"ends_at", "starts_at", "room"} {
+			if err := tx.Exec("ALTER TABLE classes DROP COLUMN " + col).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+type m0011Class struct {
+	ID       uint `gorm:"primaryKey"`
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	Room     string
+}
+
+func (m0011Class) TableName() string { return "classes" }
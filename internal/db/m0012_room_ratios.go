@@ -0,0 +1,43 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0012RoomRatios adds the per-class child-to-adult ratio and the log of which
+// volunteers are in which room. Existing classes get no ratio.
+var m0012RoomRatios = Migration{
+	ID: "0012_room_ratios",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&m0012Class{}, "KidsPerAdult"); err != nil {
+			return err
+		}
+		return tx.AutoMigrate(&m0012RoomStaff{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(&m0012RoomStaff{}); err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE classes DROP COLUMN kids_per_adult").Error
+	},
+}
+
+type m0012Class struct {
+	ID           uint `gorm:"primaryKey"`
+	KidsPerAdult int  `gorm:"not null;default:0"`
+}
+
+func (m0012Class) TableName() string { return "classes" }
+
+type m0012RoomStaff struct {
+	ID      uint   `gorm:"primaryKey"`
+	ClassID uint   `gorm:"index;not null"`
+	Name    string `gorm:"not null"`
+	InAt    time.Time
+	OutAt   *time.Time `gorm:"index"`
+	AddedBy string
+}
+
+func (m0012RoomStaff) TableName() string { return "room_staff" }
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// m0013ClassSeries adds recurring class series. Sessions stay ordinary
+// classes that point back at their series; shared questions hang off the
+// series instead of each session.
+var m0013ClassSeries = Migration{
+	ID: "0013_class_series",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&m0013Series{}); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&m0013Class{}, "SeriesID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(&m0013Class{}, "SeriesID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&m0013ClassQuestion{}, "SeriesID"); err != nil {
+			return err
+		}
+		return tx.Migrator().CreateIndex(&m0013ClassQuestion{}, "SeriesID")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&m0013ClassQuestion{}, "SeriesID"); err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE class_questions DROP COLUMN series_id").Error; err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropIndex(&m0013Class{}, "SeriesID"); err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE classes DROP COLUMN series_id").Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&m0013Series{})
+	},
+}
+
+type m0013Series struct {
+	ID            uint `gorm:"primaryKey"`
+	Name          string
+	Description   string `gorm:"type:text"`
+	CampusID      *uint  `gorm:"index"`
+	FirstDate     time.Time
+	IntervalWeeks int    `gorm:"not null;default:1"`
+	StartTime     string `gorm:"size:5"`
+	EndTime       string `gorm:"size:5"`
+	Blackouts     string `gorm:"type:text"`
+	Room          string
+	Capacity      int
+	WalkInSeats   int
+	KidsPerAdult  int `gorm:"not null;default:0"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0013Series) TableName() string { return "class_series" }
+
+type m0013Class struct {
+	ID       uint  `gorm:"primaryKey"`
+	SeriesID *uint `gorm:"index"`
+}
+
+func (m0013Class) TableName() string { return "classes" }
+
+type m0013ClassQuestion struct {
+	ID       uint  `gorm:"primaryKey"`
+	SeriesID *uint `gorm:"index"`
+}
+
+func (m0013ClassQuestion) TableName() string { return "class_questions" }
@@ -0,0 +1,67 @@
+package db
+
+import "gorm.io/gorm"
+
+// m0014AgeLimits adds optional age limits to classes, templates and series,
+// and records on a registration who let a child in past them. Everything
+// existing stays open to all ages.
+var m0014AgeLimits = Migration{
+	ID: "0014_age_limits",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, t := range m0014AgeTables {
+			for _, col := range []string{"MinAge", "MaxAge"} {
+				if err := m.AddColumn(t, col); err != nil {
+					return err
+				}
+			}
+		}
+		return m.AddColumn(&m0014Registration{}, "AgeOverrideBy")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE registrations DROP COLUMN age_override_by").Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"classes", "class_templates", "class_series"} {
+			for _, col := range []string{"min_age", "max_age"} {
+				if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN " + col).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var m0014AgeTables = []any{&m0014Class{}, &m0014ClassTemplate{}, &m0014ClassSeries{}}
+
+type m0014Class struct {
+	ID     uint `gorm:"primaryKey"`
+	MinAge *int
+	MaxAge *int
+}
+
+func (m0014Class) TableName() string { return "classes" }
+
+type m0014ClassTemplate struct {
+	ID     uint `gorm:"primaryKey"`
+	MinAge *int
+	MaxAge *int
+}
+
+func (m0014ClassTemplate) TableName() string { return "class_templates" }
+
+type m0014ClassSeries struct {
+	ID     uint `gorm:"primaryKey"`
+	MinAge *int
+	MaxAge *int
+}
+
+func (m0014ClassSeries) TableName() string { return "class_series" }
+
+type m0014Registration struct {
+	ID            uint `gorm:"primaryKey"`
+	AgeOverrideBy string
+}
+
+func (m0014Registration) TableName() string { return "registrations" }
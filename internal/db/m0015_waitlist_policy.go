@@ -0,0 +1,22 @@
+package db
+
+import "gorm.io/gorm"
+
+// m0015WaitlistPolicy lets each class pick how its waitlist is ordered.
+// Existing classes keep first come, first served (blank).
+var m0015WaitlistPolicy = Migration{
+	ID: "0015_waitlist_policy",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&m0015Class{}, "WaitlistPolicy")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("ALTER TABLE classes DROP COLUMN waitlist_policy").Error
+	},
+}
+
+type m0015Class struct {
+	ID             uint   `gorm:"primaryKey"`
+	WaitlistPolicy string `gorm:"not null;default:''"`
+}
+
+func (m0015Class) TableName() string { return "classes" }
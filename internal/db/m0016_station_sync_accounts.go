@@ -0,0 +1,35 @@
+package db
+
+import "gorm.io/gorm"
+
+// m0016StationSyncAccounts scopes offline check-in idempotency keys to the
+// station account that sent them, so two stations that happen to make up
+// the same key no longer collide. Existing rows belong to no account (0).
+var m0016StationSyncAccounts = Migration{
+	ID: "0016_station_sync_accounts",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&m0016StationSync{}, "AccountID"); err != nil {
+			return err
+		}
+		if err := tx.Exec("DROP INDEX idx_station_syncs_idem_key").Error; err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX idx_station_syncs_account_key ON station_syncs (account_id, idem_key)").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Exec("DROP INDEX idx_station_syncs_account_key").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("CREATE UNIQUE INDEX idx_station_syncs_idem_key ON station_syncs (idem_key)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE station_syncs DROP COLUMN account_id").Error
+	},
+}
+
+type m0016StationSync struct {
+	ID        uint `gorm:"primaryKey"`
+	AccountID uint `gorm:"not null;default:0"`
+}
+
+func (m0016StationSync) TableName() string { return "station_syncs" }
@@ -0,0 +1,21 @@
+package db
+
+import "gorm.io/gorm"
+
+// m0017CampusLabelPrinter gives each campus its own LAN label printer,
+// replacing the one server-wide LABEL_PRINTER. Campuses start without one,
+// so their stations print labels from the browser.
+var m0017CampusLabelPrinter = Migration{
+	ID: "0017_campus_label_printer",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&m0017Campus{}, "LabelPrinter")
+	},
+	Down: SQL("ALTER TABLE campuses DROP COLUMN label_printer"),
+}
+
+type m0017Campus struct {
+	ID           uint   `gorm:"primaryKey"`
+	LabelPrinter string `gorm:"not null;default:''"`
+}
+
+func (m0017Campus) TableName() string { return "campuses" }
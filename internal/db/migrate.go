@@ -0,0 +1,206 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migration is one ordered schema step. IDs sort lexically ("0001_baseline",
+// "0002_campuses", ...) and are never reused or renumbered once shipped: the
+// ID is what schema_migrations remembers.
+//
+// Up and Down run inside a transaction. Steps must not depend on the current
+// models package shape — a later column added to models.Class would otherwise
+// leak into an old step — so they declare the tables they touch locally.
+type Migration struct {
+	ID   string
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error // nil = irreversible
+}
+
+// SQL builds a migration step from plain statements, run in order.
+func SQL(stmts ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, s := range stmts {
+			if err := tx.Exec(s).Error; err != nil {
+				return fmt.Errorf("%s: %w", s, err)
+			}
+		}
+		return nil
+	}
+}
+
+// schemaMigration is the bookkeeping row for an applied Migration.
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// registry holds every migration in order. Add new steps to the end of the
+// list in migrations.go; never edit one that has shipped.
+func registry() []Migration {
+	ms := append([]Migration(nil), migrations...)
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].ID < ms[j].ID })
+	return ms
+}
+
+// MigrationStatus is one line of `migrate status`.
+type MigrationStatus struct {
+	ID        string
+	AppliedAt *time.Time // nil = pending
+}
+
+func applied(c *gorm.DB) (map[string]time.Time, error) {
+	if err := c.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := c.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		out[r.ID] = r.AppliedAt
+	}
+	return out, nil
+}
+
+// Status lists every known migration and when it was applied.
+func Status() ([]MigrationStatus, error) {
+	done, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	var out []MigrationStatus
+	for _, m := range registry() {
+		st := MigrationStatus{ID: m.ID}
+		if at, ok := done[m.ID]; ok {
+			at := at
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Pending returns the migrations not yet recorded in schema_migrations.
+func Pending() ([]Migration, error) {
+	done, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	var out []Migration
+	for _, m := range registry() {
+		if _, ok := done[m.ID]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// step is one direction of one Migration, bookkeeping included.
+type step struct {
+	id string
+	fn func(tx *gorm.DB) error
+}
+
+// MigrateUp applies every pending migration, each in its own transaction.
+//
+// With dryRun set the steps still run, all inside one transaction that is
+// always rolled back, and every statement is echoed to out. That shows exactly
+// what `up` would do — including failures — without changing the database.
+func MigrateUp(out io.Writer, dryRun bool) ([]string, error) {
+	pending, err := Pending()
+	if err != nil {
+		return nil, err
+	}
+	var steps []step
+	for _, m := range pending {
+		m := m
+		steps = append(steps, step{m.ID, func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		}})
+	}
+	return runSteps(out, dryRun, "up", steps)
+}
+
+// MigrateDown reverts the last n applied migrations, newest first.
+func MigrateDown(out io.Writer, n int, dryRun bool) ([]string, error) {
+	done, err := applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	ms := registry()
+	var steps []step
+	for i := len(ms) - 1; i >= 0 && len(steps) < n; i-- {
+		m := ms[i]
+		if _, ok := done[m.ID]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			// Revert what can be reverted, then report the wall.
+			ids, err := runSteps(out, dryRun, "down", steps)
+			if err != nil {
+				return ids, err
+			}
+			return ids, fmt.Errorf("migration %s is irreversible", m.ID)
+		}
+		steps = append(steps, step{m.ID, func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{ID: m.ID}).Error
+		}})
+	}
+	return runSteps(out, dryRun, "down", steps)
+}
+
+// errDryRun rolls back a dry-run transaction; it never escapes runSteps.
+var errDryRun = errors.New("dry run")
+
+func runSteps(out io.Writer, dryRun bool, dir string, steps []step) ([]string, error) {
+	var ids []string
+	if !dryRun {
+		for _, st := range steps {
+			if err := conn.Transaction(st.fn); err != nil {
+				return ids, fmt.Errorf("migration %s %s: %w", st.id, dir, err)
+			}
+			fmt.Fprintf(out, "%s %s\n", dir, st.id)
+			ids = append(ids, st.id)
+		}
+		return ids, nil
+	}
+
+	// Later steps usually depend on earlier ones, so a dry run keeps them all
+	// in one transaction and throws it away at the end.
+	echo := conn.Session(&gorm.Session{Logger: logger.New(
+		log.New(out, "", 0),
+		logger.Config{LogLevel: logger.Info, Colorful: false},
+	)})
+	err := echo.Transaction(func(tx *gorm.DB) error {
+		for _, st := range steps {
+			fmt.Fprintf(out, "-- %s %s (dry run)\n", dir, st.id)
+			if err := st.fn(tx); err != nil {
+				return fmt.Errorf("migration %s %s: %w", st.id, dir, err)
+			}
+			ids = append(ids, st.id)
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return ids, nil
+	}
+	return ids, err
+}
@@ -0,0 +1,88 @@
+package db_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/db/dbtest"
+)
+
+func TestOpen_ReportsEverythingPendingOnFreshDB(t *testing.T) {
+	dbtest.Open(t)
+	st, err := db.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(st) == 0 {
+		t.Fatal("no migrations registered")
+	}
+	for i, s := range st {
+		if s.AppliedAt != nil {
+			t.Errorf("%s applied on a fresh database", s.ID)
+		}
+		if i > 0 && st[i-1].ID >= s.ID {
+			t.Errorf("migration IDs out of order or duplicated: %s then %s", st[i-1].ID, s.ID)
+		}
+	}
+}
+
+func TestMigrateUp_DryRunChangesNothing(t *testing.T) {
+	dbtest.Open(t)
+	before, _ := db.Pending()
+	ids, err := db.MigrateUp(io.Discard, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(ids) != len(before) {
+		t.Errorf("dry run walked %d steps, want %d", len(ids), len(before))
+	}
+	after, _ := db.Pending()
+	if len(after) != len(before) {
+		t.Errorf("pending after dry run = %d, want %d", len(after), len(before))
+	}
+	if db.Conn().Migrator().HasTable("parents") {
+		t.Error("dry run left a parents table behind")
+	}
+}
+
+func TestMigrateDownThenUp(t *testing.T) {
+	dbtest.Init(t)
+	if p, _ := db.Pending(); len(p) != 0 {
+		t.Fatalf("Init left %d pending", len(p))
+	}
+
+	st, _ := db.Status()
+	last := st[len(st)-1].ID
+	ids, err := db.MigrateDown(io.Discard, 1, false)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != last {
+		t.Fatalf("down reverted %v, want [%s]", ids, last)
+	}
+	p, _ := db.Pending()
+	if len(p) != 1 || p[0].ID != last {
+		t.Fatalf("pending after down = %v, want [%s]", p, last)
+	}
+
+	if _, err := db.MigrateUp(io.Discard, false); err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+	if !hasIndex(t, "registrations", "idx_reg_class_status") {
+		t.Error("composite index lost across down/up")
+	}
+}
+
+// The baseline is recorded, not undone: dropping every table is never what
+// an operator means by "down".
+func TestMigrateDown_StopsAtIrreversibleBaseline(t *testing.T) {
+	dbtest.Init(t)
+	st, _ := db.Status()
+	if _, err := db.MigrateDown(io.Discard, len(st), false); err == nil {
+		t.Fatal("expected an error reverting the baseline")
+	}
+	if !db.Conn().Migrator().HasTable("parents") {
+		t.Error("baseline tables were dropped")
+	}
+}
@@ -0,0 +1,22 @@
+package db
+
+// migrations is the ordered schema history. Append only.
+var migrations = []Migration{
+	m0001Baseline,
+	m0002Campuses,
+	m0003SoftDelete,
+	m0004RegistrationEvents,
+	m0005Households,
+	m0006Pickups,
+	m0007SecurityCodes,
+	m0008WalkIns,
+	m0009StationSyncs,
+	m0010FamilyCodes,
+	m0011ClassTimes,
+	m0012RoomRatios,
+	m0013ClassSeries,
+	m0014AgeLimits,
+	m0015WaitlistPolicy,
+	m0016StationSyncAccounts,
+	m0017CampusLabelPrinter,
+}
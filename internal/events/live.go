@@ -0,0 +1,67 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Change is a registration moving while stations watch: checked in, undone,
+// checked out, canceled or promoted. A volunteer entering or leaving a room
+// is one too, with no RegID. Services publish it after the change
+// has committed.
+type Change struct {
+	Kind     string    `json:"kind"` // a models.Event* kind, or staff_in/staff_out
+	RegID    uint      `json:"reg_id"`
+	Code     string    `json:"code"`
+	ClassID  uint      `json:"class_id"`
+	CampusID *uint     `json:"campus_id,omitempty"`
+	Day      string    `json:"day"` // class date at its campus, YYYY-MM-DD
+	Status   string    `json:"status"`
+	By       string    `json:"by,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// liveBuffer is how far a watcher may fall behind before it is dropped. A
+// dropped watcher's channel is closed; it reconnects and reloads.
+const liveBuffer = 64
+
+var live = struct {
+	sync.Mutex
+	subs map[chan Change]func(Change) bool
+}{subs: map[chan Change]func(Change) bool{}}
+
+// Subscribe returns a channel of the changes match accepts, and a function
+// that stops them. The channel is closed when the watcher is dropped or
+// stopped.
+func Subscribe(match func(Change) bool) (<-chan Change, func()) {
+	ch := make(chan Change, liveBuffer)
+	live.Lock()
+	live.subs[ch] = match
+	live.Unlock()
+	return ch, func() {
+		live.Lock()
+		defer live.Unlock()
+		if _, ok := live.subs[ch]; ok {
+			delete(live.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish hands c to every watcher that wants it without ever blocking the
+// caller.
+func Publish(c Change) {
+	live.Lock()
+	defer live.Unlock()
+	for ch, match := range live.subs {
+		if match != nil && !match(c) {
+			continue
+		}
+		select {
+		case ch <- c:
+		default:
+			delete(live.subs, ch)
+			close(ch)
+		}
+	}
+}
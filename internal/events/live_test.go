@@ -0,0 +1,37 @@
+package events
+
+import "testing"
+
+func TestPublish_FiltersAndDropsSlowWatchers(t *testing.T) {
+	fjb := uint(1)
+	mine, stopMine := Subscribe(func(c Change) bool { return c.CampusID != nil && *c.CampusID == fjb })
+	defer stopMine()
+	all, stopAll := Subscribe(nil)
+	defer stopAll()
+
+	Publish(Change{Kind: "checked_in", RegID: 1, CampusID: &fjb})
+	Publish(Change{Kind: "checked_in", RegID: 2})
+
+	if c := <-mine; c.RegID != 1 {
+		t.Errorf("campus watcher got reg %d, want 1", c.RegID)
+	}
+	if len(mine) != 0 {
+		t.Errorf("campus watcher got another campus's change")
+	}
+	if len(all) != 2 {
+		t.Fatalf("unfiltered watcher has %d changes, want 2", len(all))
+	}
+
+	// Nobody reads all: once its buffer is full it is dropped and closed.
+	for i := 0; i < liveBuffer; i++ {
+		Publish(Change{RegID: uint(10 + i)})
+	}
+	n := 0
+	for range all {
+		n++
+	}
+	if n != liveBuffer {
+		t.Errorf("slow watcher drained %d changes before close, want %d", n, liveBuffer)
+	}
+	stopAll() // stopping a dropped watcher is harmless
+}
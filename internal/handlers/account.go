@@ -70,25 +70,25 @@ func AccountProfileForm(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		var kids []models.Child
-		_ = db.Conn().Where("parent_id = ?", parent.ID).Order("name asc").Find(&kids).Error
+		kids := svc.HouseholdChildren(parent.HouseholdID)
 
 		// Telegram link status
 		var tg models.TelegramUser
 		linked := false
-		if err := db.Conn().Where("parent_id = ? AND deliverable = 1", parent.ID).First(&tg).Error; err == nil {
+		if err := db.Conn().Where("parent_id = ? AND deliverable = ?", parent.ID, true).First(&tg).Error; err == nil {
 			linked = true
 		}
 
 		_ = view.ExecuteTemplate(w, "parents/account_profile.tmpl", map[string]any{
-			"Title":    "My Account",
-			"Parent":   parent,
-			"Kids":     kids,
-			"Phone":    phone,
-			"LinkCode": r.URL.Query().Get("link_code"),
-			"TGLinked": linked,
-			"TG":       tg,
-			"Flash":    MakeFlash(r, "", ""), // ← unified flash (query ok/error OR handler messages)
+			"Title":     "My Account",
+			"Parent":    parent,
+			"Kids":      kids,
+			"Guardians": svc.Guardians(parent.HouseholdID),
+			"Phone":     phone,
+			"LinkCode":  r.URL.Query().Get("link_code"),
+			"TGLinked":  linked,
+			"TG":        tg,
+			"Flash":     MakeFlash(r, "", ""), // ← unified flash (query ok/error OR handler messages)
 		})
 	}
 }
@@ -197,7 +197,7 @@ func AccountNewChildSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	child := models.Child{Name: name, BirthDate: d, ParentID: parent.ID}
+	child := models.Child{Name: name, BirthDate: d, HouseholdID: parent.HouseholdID, ParentID: parent.ID}
 	if err := db.Conn().Create(&child).Error; err != nil {
 		http.Error(w, "db error", 500)
 		return
@@ -211,19 +211,18 @@ func AccountEditChildForm(t *template.Template) http.HandlerFunc {
 	template.Must(view.ParseFiles("templates/pages/parents/account_child_edit.tmpl"))
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := r.URL.Query().Get("id")
-		phone := r.URL.Query().Get("phone")
-		id, _ := strconv.Atoi(idStr)
-		var child models.Child
-		if err := db.Conn().First(&child, id).Error; err != nil {
-			http.Error(w, "child not found", 404)
+		child, ok := accountChild(w, r, r.URL.Query().Get("id"))
+		if !ok {
 			return
 		}
+		phone, _ := readParentCookies(r)
 		_ = view.ExecuteTemplate(w, "parents/account_child_edit.tmpl", map[string]any{
 			"Title":     "Edit Child",
 			"Child":     child,
 			"Phone":     phone,
 			"BirthDate": child.BirthDate.Format("2006-01-02"),
+			"Pickups":   svc.PickupPersons(child.ID),
+			"Flash":     MakeFlash(r, "", ""),
 		})
 	}
 }
@@ -255,7 +254,7 @@ func AccountEditChildSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var child models.Child
-	if err := db.Conn().First(&child, childID).Error; err != nil || child.ParentID != parent.ID {
+	if err := db.Conn().First(&child, childID).Error; err != nil || child.HouseholdID != parent.HouseholdID {
 		http.Error(w, "child not found", http.StatusNotFound)
 		return
 	}
@@ -270,6 +269,10 @@ func AccountEditChildSubmit(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	child.Gender = gender
+	if _, ok := r.Form["allergies"]; ok {
+		child.Allergies = strings.TrimSpace(r.FormValue("allergies"))
+		child.MedicalNotes = strings.TrimSpace(r.FormValue("medical_notes"))
+	}
 
 	if err := db.Conn().Save(&child).Error; err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
@@ -296,7 +299,8 @@ func AccountDeleteChild(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// NOTE: You may want to block delete if child has future registrations.
-	if err := db.Conn().Delete(&child).Error; err != nil {
+	// Soft delete: an admin can bring the child back from /admin/trash.
+	if err := svc.DeleteChild(child.ID, svc.ParentActor(phone, models.SourceWeb)); err != nil {
 		http.Error(w, "db error", 500)
 		return
 	}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// POST /account/guardians
+//
+// Adds a second parent (or grandparent, ...) to the signed-in household. They
+// log in with their own phone and see the same children.
+func AccountGuardianAdd(w http.ResponseWriter, r *http.Request) {
+	parent, ok := cookieParent(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	email, valid := svc.NormEmail(r.FormValue("email"))
+	if !valid {
+		http.Redirect(w, r, "/account/profile?error=invalid_email", http.StatusSeeOther)
+		return
+	}
+	_, err := svc.AddGuardian(parent.HouseholdID, r.FormValue("guardian_name"), r.FormValue("guardian_phone"), email)
+	if err != nil {
+		http.Redirect(w, r, "/account/profile?error="+guardianErr(err), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/account/profile?ok=guardian_added", http.StatusSeeOther)
+}
+
+// POST /account/guardians/remove
+func AccountGuardianRemove(w http.ResponseWriter, r *http.Request) {
+	parent, ok := cookieParent(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if uint(id) == parent.ID {
+		// Removing yourself would log you out of your own family.
+		http.Redirect(w, r, "/account/profile?error=guardian_self", http.StatusSeeOther)
+		return
+	}
+	if err := svc.RemoveGuardian(parent.HouseholdID, uint(id), parentActor(r)); err != nil {
+		http.Redirect(w, r, "/account/profile?error="+guardianErr(err), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/account/profile?ok=guardian_removed", http.StatusSeeOther)
+}
+
+// cookieParent loads the guardian signed in by cookie, or sends them to the
+// phone gate.
+func cookieParent(w http.ResponseWriter, r *http.Request) (models.Parent, bool) {
+	var parent models.Parent
+	phone, _ := readParentCookies(r)
+	if strings.TrimSpace(phone) == "" || db.Conn().Where("phone = ?", phone).First(&parent).Error != nil {
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+		return parent, false
+	}
+	return parent, true
+}
+
+// guardianErr maps household errors to flash keys.
+func guardianErr(err error) string {
+	switch {
+	case errors.Is(err, svc.ErrGuardianPhoneTaken):
+		return "guardian_phone_taken"
+	case errors.Is(err, svc.ErrGuardianExists):
+		return "guardian_exists"
+	case errors.Is(err, svc.ErrLastGuardian):
+		return "last_guardian"
+	case errors.Is(err, svc.ErrMissingGuardian):
+		return "missing"
+	}
+	return "guardian_failed"
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 // put this near the top of the file or in a shared helpers file
@@ -38,7 +40,7 @@ func AdminClasses(t *template.Template) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		var classes []models.Class
-		if err := db.Conn().Order("date desc").Find(&classes).Error; err != nil {
+		if err := db.Conn().Preload("Campus").Order("date desc").Find(&classes).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
@@ -50,8 +52,9 @@ func AdminClasses(t *template.Template) http.HandlerFunc {
 		}
 		var counts []countRow
 		_ = db.Conn().Table("registrations").
+			Where("registrations.deleted_at IS NULL").
 			Select("class_id, COUNT(*) as total").
-			Where("status != ?", "canceled").
+			Where("status != ?", svc.StatusCanceled).
 			Group("class_id").
 			Scan(&counts).Error
 		countMap := map[uint]int{}
@@ -87,7 +90,7 @@ func AdminDeleteClass(w http.ResponseWriter, r *http.Request) {
 
 	var count int64
 	db.Conn().Model(&models.Registration{}).
-		Where("class_id = ? AND status != ?", id, "canceled").
+		Where("class_id = ? AND status != ?", id, svc.StatusCanceled).
 		Count(&count)
 
 	if count > 0 {
@@ -95,9 +98,12 @@ func AdminDeleteClass(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Safe to delete — remove questions and class
-	db.Conn().Where("class_id = ?", id).Delete(&models.ClassQuestion{})
-	db.Conn().Delete(&class)
+	// Safe to delete — to the trash, questions stay with it for a restore
+	if err := svc.DeleteClass(class.ID, staffActor(r)); err != nil {
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, "class.delete", fmt.Sprintf("class:%d (%s)", class.ID, class.Name), "")
 
 	http.Redirect(w, r, "/admin/classes?ok=deleted", http.StatusSeeOther)
 }
@@ -132,8 +138,10 @@ func AdminNewClass(t *template.Template) http.HandlerFunc {
 		}
 
 		data := map[string]any{
-			"Title": "Admin • New Class",
-			"Tpls":  tpls,
+			"Title":    "Admin • New Class",
+			"Tpls":     tpls,
+			"Campuses": allCampuses(),
+			"WaitlistPolicies": svc.WaitlistPolicies(),
 		}
 		if err := view.ExecuteTemplate(w, "admin/classes_new.tmpl", data); err != nil {
 			http.Error(w, err.Error(), 500)
@@ -158,6 +166,10 @@ func AdminCreateClass(w http.ResponseWriter, r *http.Request) {
 	if dateStr == "" || name == "" || capStr == "" {
 		http.Error(w, "missing fields", http.StatusBadRequest); return
 	}
+	campusID, ok := classCampusFromForm(r)
+	if !ok {
+		http.Error(w, "pick a campus", http.StatusBadRequest); return
+	}
 	locJkt, _ := time.LoadLocation("Asia/Jakarta")
 	d, err := time.ParseInLocation("2006-01-02", dateStr,locJkt)
 	if err != nil { http.Error(w, "invalid date", http.StatusBadRequest); return }
@@ -166,24 +178,64 @@ func AdminCreateClass(w http.ResponseWriter, r *http.Request) {
 	if err != nil || capacity < 0 {
 		http.Error(w, "invalid capacity", http.StatusBadRequest); return
 	}
+	walkIns, ok := walkInSeatsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid walk-in seats", http.StatusBadRequest); return
+	}
+	kidsPerAdult, ok := kidsPerAdultFromForm(r)
+	if !ok {
+		http.Error(w, "invalid ratio", http.StatusBadRequest); return
+	}
+	minAge, maxAge, ok := ageLimitsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid age range", http.StatusBadRequest); return
+	}
+	policy := r.FormValue("waitlist_policy")
+	if !svc.IsWaitlistPolicy(policy) {
+		http.Error(w, "unknown waitlist policy", http.StatusBadRequest); return
+	}
 
 	opensAt, err := parseOptionalJakartaDateTime(openDate, openTime)
 	if err != nil {
 		http.Error(w, "invalid opens-at", http.StatusBadRequest); return
 	}
+	startsAt, endsAt, ok := classTimesFromForm(r, d)
+	if !ok {
+		http.Error(w, "invalid start/end time", http.StatusBadRequest); return
+	}
 
 	cl := models.Class{
+		CampusID:      campusID,
 		Date:          d,
 		Name:          name,
 		Capacity:      capacity,
+		WalkInSeats:   walkIns,
+		KidsPerAdult:  kidsPerAdult,
+		MinAge:        minAge,
+		MaxAge:        maxAge,
+		WaitlistPolicy: policy,
 		Description:   strings.TrimSpace(desc),
 		SignupOpensAt: opensAt,
+		StartsAt:      startsAt,
+		EndsAt:        endsAt,
+		Room:          strings.TrimSpace(r.FormValue("room")),
 	}
 	if err := db.Conn().Create(&cl).Error; err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError); return
 	}
 
 	// ------- Custom Questions -------
+	for _, q := range questionsFromForm(r) {
+		q.ClassID = &cl.ID
+		_ = db.Conn().Create(&q).Error
+	}
+
+	http.Redirect(w, r, "/admin/classes?ok=saved", http.StatusSeeOther)
+}
+
+// questionsFromForm reads the custom questions of the new-class form (and
+// the new-series form, which shares it), owner left unset.
+func questionsFromForm(r *http.Request) []models.ClassQuestion {
 	labels := r.Form["q_label[]"]
 	kinds  := r.Form["q_kind[]"]        // "text" | "radio"
 	reqs   := r.Form["q_required[]"]    // checkbox indices as strings
@@ -198,6 +250,7 @@ func AdminCreateClass(w http.ResponseWriter, r *http.Request) {
 		if i, err := strconv.Atoi(v); err == nil { reqIdx[i] = true }
 	}
 
+	var out []models.ClassQuestion
 	n := len(labels)
 	for i := 0; i < n; i++ {
 		lbl := strings.TrimSpace(labels[i])
@@ -215,18 +268,15 @@ func AdminCreateClass(w http.ResponseWriter, r *http.Request) {
 
 		if kind != "radio" { choices = "" } // only store for radio
 
-		q := models.ClassQuestion{
-			ClassID:  &cl.ID,
+		out = append(out, models.ClassQuestion{
 			Label:    lbl,
 			Kind:     kind,
 			Options:  choices,     // comma-separated
 			Required: reqIdx[i],
 			Position: i,
-		}
-		_ = db.Conn().Create(&q).Error
+		})
 	}
-
-	http.Redirect(w, r, "/admin/classes?ok=saved", http.StatusSeeOther)
+	return out
 }
 
 
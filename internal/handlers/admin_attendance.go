@@ -22,25 +22,6 @@ type attendanceRow struct {
 	LastStr     string
 }
 
-// checkInDateStr formats a raw check_in_at string (as stored by SQLite, e.g.
-// "2026-06-27 17:16:21.61+07:00") into the dd-mm-yyyy Jakarta date used across
-// the admin UI. Falls back to the leading date portion if the layout differs.
-func checkInDateStr(raw string) string {
-	if raw == "" {
-		return ""
-	}
-	if ts, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", raw); err == nil {
-		return fmtDate(ts)
-	}
-	if len(raw) >= 10 {
-		if d, err := time.Parse("2006-01-02", raw[:10]); err == nil {
-			return fmtDate(d)
-		}
-		return raw[:10]
-	}
-	return raw
-}
-
 type attendanceVM struct {
 	Title      string
 	Rows       []attendanceRow
@@ -48,6 +29,8 @@ type attendanceVM struct {
 	To         string
 	Class      string   // selected class-name filter ("" = all)
 	ClassNames []string // distinct class names for the dropdown
+	Campus     string   // selected campus_id ("" = all)
+	Campuses   []models.Campus
 	Summary    struct {
 		Students    int
 		Attendances int64
@@ -80,16 +63,18 @@ func attendanceWindow(fFrom, fTo string) (fromUTC, toUTC time.Time, fromStr, toS
 
 // queryAttendance returns per-child attendance counts (check-ins) for classes
 // whose date falls inside [fromUTC, toUTC], sorted by most frequent first.
-func queryAttendance(fromUTC, toUTC time.Time, className string) ([]attendanceRow, error) {
+// A nil campusID covers every campus.
+func queryAttendance(fromUTC, toUTC time.Time, className string, campusID *uint) ([]attendanceRow, error) {
 	type attAgg struct {
 		ChildID     uint
 		ChildName   string
 		ParentName  string
 		ParentPhone string
 		Attended    int64
-		LastAt      string
+		LastAt      db.AggTime
 	}
 	q := db.Conn().Table("registrations").
+		Where("registrations.deleted_at IS NULL").
 		Select(`children.id AS child_id, children.name AS child_name,
 			parents.name AS parent_name, parents.phone AS parent_phone,
 			COUNT(DISTINCT registrations.class_id) AS attended,
@@ -103,9 +88,12 @@ func queryAttendance(fromUTC, toUTC time.Time, className string) ([]attendanceRo
 	if className != "" {
 		q = q.Where("classes.name = ?", className)
 	}
+	if campusID != nil {
+		q = q.Where("classes.campus_id = ?", *campusID)
+	}
 
 	var aggs []attAgg
-	err := q.Group("children.id").
+	err := q.Group("children.id, parents.id").
 		Order("attended DESC, child_name ASC").
 		Scan(&aggs).Error
 	if err != nil {
@@ -114,15 +102,18 @@ func queryAttendance(fromUTC, toUTC time.Time, className string) ([]attendanceRo
 
 	rows := make([]attendanceRow, 0, len(aggs))
 	for i, a := range aggs {
-		rows = append(rows, attendanceRow{
+		row := attendanceRow{
 			Rank:        i + 1,
 			ChildID:     a.ChildID,
 			ChildName:   a.ChildName,
 			ParentName:  a.ParentName,
 			ParentPhone: a.ParentPhone,
 			Attended:    a.Attended,
-			LastStr:     checkInDateStr(a.LastAt),
-		})
+		}
+		if a.LastAt.Valid {
+			row.LastStr = fmtDate(a.LastAt.Time)
+		}
+		rows = append(rows, row)
 	}
 	return rows, nil
 }
@@ -136,8 +127,9 @@ func AdminAttendance(t *template.Template) http.HandlerFunc {
 		fromUTC, toUTC, fromStr, toStr := attendanceWindow(
 			r.URL.Query().Get("from"), r.URL.Query().Get("to"))
 		className := r.URL.Query().Get("class")
+		campusStr := r.URL.Query().Get("campus_id")
 
-		rows, err := queryAttendance(fromUTC, toUTC, className)
+		rows, err := queryAttendance(fromUTC, toUTC, className, parseCampusID(campusStr))
 		if err != nil {
 			http.Error(w, "db error", http.StatusInternalServerError)
 			return
@@ -146,6 +138,7 @@ func AdminAttendance(t *template.Template) http.HandlerFunc {
 		vm := attendanceVM{
 			Title: "Admin • Attendance", Rows: rows, From: fromStr, To: toStr,
 			Class: className, ClassNames: distinctClassNames(),
+			Campus: campusStr, Campuses: allCampuses(),
 		}
 		vm.Summary.Students = len(rows)
 		for _, rr := range rows {
@@ -165,7 +158,7 @@ func AdminAttendanceCSV(w http.ResponseWriter, r *http.Request) {
 		r.URL.Query().Get("from"), r.URL.Query().Get("to"))
 	className := r.URL.Query().Get("class")
 
-	rows, err := queryAttendance(fromUTC, toUTC, className)
+	rows, err := queryAttendance(fromUTC, toUTC, className, parseCampusID(r.URL.Query().Get("campus_id")))
 	if err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// TestQueryAttendance_CountsAndLastDate runs the per-child aggregate end to
+// end: MAX(check_in_at) comes back as text on SQLite and as a timestamp on
+// Postgres, and both must render as the same Jakarta date.
+func TestQueryAttendance_CountsAndLastDate(t *testing.T) {
+	gdb := openTestDB(t)
+
+	parent := models.Parent{Name: "Ibu Sari", Phone: "+628111"}
+	gdb.Create(&parent)
+	kid := models.Child{Name: "Budi", ParentID: parent.ID}
+	gdb.Create(&kid)
+
+	jkt := capacityLoc
+	first := time.Date(2026, 6, 20, 9, 0, 0, 0, jkt)
+	second := time.Date(2026, 6, 27, 9, 0, 0, 0, jkt)
+	codes := []string{"ATT-0620", "ATT-0627"}
+	for i, d := range []time.Time{first, second} {
+		cl := models.Class{Name: "FJB Little Stars", Date: d, Capacity: 10}
+		gdb.Create(&cl)
+		in := d.Add(17 * time.Minute)
+		gdb.Create(&models.Registration{
+			ParentID: parent.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: codes[i], CheckInAt: &in,
+		})
+	}
+
+	rows, err := queryAttendance(first.Add(-time.Hour), second.Add(time.Hour), "", nil)
+	if err != nil {
+		t.Fatalf("queryAttendance: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(rows))
+	}
+	r := rows[0]
+	if r.Attended != 2 || r.ParentName != "Ibu Sari" {
+		t.Errorf("row = %+v, want 2 sessions for Ibu Sari", r)
+	}
+	if r.LastStr != "27 Jun 2026" {
+		t.Errorf("LastStr = %q, want 27 Jun 2026", r.LastStr)
+	}
+}
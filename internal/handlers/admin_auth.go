@@ -13,7 +13,6 @@ import (
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -48,7 +47,7 @@ var (
 // restarts do not sign everyone out.
 func sessionSecret() []byte {
 	secretOnce.Do(func() {
-		if s := os.Getenv("SESSION_SECRET"); s != "" {
+		if s := conf.SessionSecret; s != "" {
 			secretVal = []byte(s)
 			return
 		}
@@ -136,6 +135,25 @@ func actorLabel(r *http.Request) string {
 	return ""
 }
 
+// sessionUser resolves the session cookie to an active account. Routes
+// behind RequireRole use CurrentUser; this is for public ones that serve
+// staff differently.
+func sessionUser(r *http.Request) (*models.AdminUser, bool) {
+	c, err := r.Cookie(adminCookieName)
+	if err != nil || c.Value == "" {
+		return nil, false
+	}
+	id, ok := parseToken(c.Value)
+	if !ok {
+		return nil, false
+	}
+	var u models.AdminUser
+	if err := db.Conn().First(&u, id).Error; err != nil || !u.Active {
+		return nil, false
+	}
+	return &u, true
+}
+
 // ---------- middleware ----------
 
 // RequireRole blocks the request unless the caller is logged in with one of the
@@ -149,18 +167,12 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 				redirectLogin(w, r)
 				return
 			}
-			id, ok := parseToken(c.Value)
+			u, ok := sessionUser(r)
 			if !ok {
 				clearAdminCookie(w)
 				redirectLogin(w, r)
 				return
 			}
-			var u models.AdminUser
-			if err := db.Conn().First(&u, id).Error; err != nil || !u.Active {
-				clearAdminCookie(w)
-				redirectLogin(w, r)
-				return
-			}
 			allowed := false
 			for _, want := range roles {
 				if u.Role == want {
@@ -182,7 +194,7 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 					defaultLanding(u.Role))
 				return
 			}
-			ctx := context.WithValue(r.Context(), ctxUserKey, &u)
+			ctx := context.WithValue(r.Context(), ctxUserKey, u)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -211,11 +223,7 @@ func clearAdminCookie(w http.ResponseWriter) {
 // override) is therefore the authoritative signal; the request checks are
 // fallbacks for other deployments.
 func isHTTPS(r *http.Request) bool {
-	switch strings.ToLower(os.Getenv("SECURE_COOKIES")) {
-	case "1", "true", "yes":
-		return true
-	}
-	if r.TLS != nil {
+	if conf.SecureCookies || r.TLS != nil {
 		return true
 	}
 	return strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
@@ -373,7 +381,7 @@ func AdminLogout(w http.ResponseWriter, r *http.Request) {
 // EnsureBootstrapAdmin creates a first admin account when the table is empty so
 // a fresh install is reachable. The password comes from ADMIN_PASSWORD; if that
 // is unset a random one is generated and logged once.
-func EnsureBootstrapAdmin(gdb *gorm.DB) error {
+func EnsureBootstrapAdmin(gdb *gorm.DB, password string) error {
 	var n int64
 	if err := gdb.Model(&models.AdminUser{}).Count(&n).Error; err != nil {
 		return err
@@ -381,7 +389,7 @@ func EnsureBootstrapAdmin(gdb *gorm.DB) error {
 	if n > 0 {
 		return nil
 	}
-	pw := os.Getenv("ADMIN_PASSWORD")
+	pw := password
 	generated := false
 	if pw == "" {
 		buf := make([]byte, 12)
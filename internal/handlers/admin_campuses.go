@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/labels"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+type campusRow struct {
+	models.Campus
+	Classes  int64
+	Accounts int64
+}
+
+type campusesVM struct {
+	Title    string
+	Campuses []campusRow
+	Flash    *Flash
+}
+
+// GET /admin/campuses
+func AdminCampuses(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/campuses.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		cs := allCampuses()
+		rows := make([]campusRow, 0, len(cs))
+		for _, c := range cs {
+			row := campusRow{Campus: c}
+			db.Conn().Model(&models.Class{}).Where("campus_id = ?", c.ID).Count(&row.Classes)
+			db.Conn().Model(&models.AdminUser{}).Where("campus_id = ?", c.ID).Count(&row.Accounts)
+			rows = append(rows, row)
+		}
+		if err := view.ExecuteTemplate(w, "admin/campuses.tmpl", campusesVM{
+			Title:    "Admin • Campus",
+			Campuses: rows,
+			Flash:    MakeFlash(r, "", ""),
+		}); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// campusFromForm reads and validates the shared create/update fields.
+func campusFromForm(r *http.Request) (models.Campus, string) {
+	c := models.Campus{
+		Code:         strings.ToUpper(strings.TrimSpace(r.FormValue("code"))),
+		Name:         strings.TrimSpace(r.FormValue("name")),
+		Address:      strings.TrimSpace(r.FormValue("address")),
+		TimeZone:     strings.TrimSpace(r.FormValue("tz")),
+		LabelPrinter: strings.TrimSpace(r.FormValue("label_printer")),
+	}
+	if c.Code == "" || strings.ContainsAny(c.Code, " \t") {
+		return c, "kode+wajib+diisi+tanpa+spasi"
+	}
+	if c.TimeZone == "" {
+		c.TimeZone = "Asia/Jakarta"
+	}
+	if _, err := time.LoadLocation(c.TimeZone); err != nil {
+		return c, "zona+waktu+tidak+dikenal"
+	}
+	if _, err := labels.ParsePrinter(c.LabelPrinter); err != nil {
+		return c, "printer+label+harus+zpl://host:port+atau+escpos://host:port"
+	}
+	return c, ""
+}
+
+// POST /admin/campuses
+func AdminCampusCreate(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	c, bad := campusFromForm(r)
+	if bad != "" {
+		http.Redirect(w, r, "/admin/campuses?error="+bad, http.StatusSeeOther)
+		return
+	}
+	var n int64
+	db.Conn().Model(&models.Campus{}).Where("UPPER(code) = ?", c.Code).Count(&n)
+	if n > 0 {
+		http.Redirect(w, r, "/admin/campuses?error=kode+sudah+dipakai", http.StatusSeeOther)
+		return
+	}
+	if err := db.Conn().Create(&c).Error; err != nil {
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, "campus.create", "campus:"+c.Code, c.Name+" tz="+c.TimeZone+" printer="+c.LabelPrinter)
+	http.Redirect(w, r, "/admin/campuses?ok=campus+"+c.Code+"+dibuat", http.StatusSeeOther)
+}
+
+// POST /admin/campuses/{id}
+func AdminCampusUpdate(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	var cur models.Campus
+	if err := db.Conn().First(&cur, chi.URLParam(r, "id")).Error; err != nil {
+		http.Error(w, "not found", 404)
+		return
+	}
+	c, bad := campusFromForm(r)
+	if bad != "" {
+		http.Redirect(w, r, "/admin/campuses?error="+bad, http.StatusSeeOther)
+		return
+	}
+	var n int64
+	db.Conn().Model(&models.Campus{}).Where("UPPER(code) = ? AND id <> ?", c.Code, cur.ID).Count(&n)
+	if n > 0 {
+		http.Redirect(w, r, "/admin/campuses?error=kode+sudah+dipakai", http.StatusSeeOther)
+		return
+	}
+	if err := db.Conn().Model(&cur).Updates(map[string]any{
+		"code":          c.Code,
+		"name":          c.Name,
+		"address":       c.Address,
+		"time_zone":     c.TimeZone,
+		"label_printer": c.LabelPrinter,
+		"updated_at":    time.Now(),
+	}).Error; err != nil {
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, "campus.update", "campus:"+c.Code, c.Name+" tz="+c.TimeZone+" printer="+c.LabelPrinter)
+	http.Redirect(w, r, "/admin/campuses?ok=campus+"+c.Code+"+disimpan", http.StatusSeeOther)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 type capacityRow struct {
@@ -18,16 +19,20 @@ type capacityRow struct {
 	Confirmed   int64
 	Waitlisted  int64
 	CheckedIn   int64
+	NoShow      int64
 	Available   int
 	FillPercent int
+	Ratio       svc.Ratio // children and adults in the room right now
 }
 
 type capacityVM struct {
 	Title   string
 	Rows    []capacityRow
-	From    string
-	To      string
-	Summary struct {
+	From     string
+	To       string
+	Campus   string // selected campus_id ("" = all)
+	Campuses []models.Campus
+	Summary  struct {
 		Classes    int
 		Capacity   int
 		Confirmed  int64
@@ -64,6 +69,7 @@ func AdminCapacity(t *template.Template) http.HandlerFunc {
 
 		fromStr := r.URL.Query().Get("from")
 		toStr := r.URL.Query().Get("to")
+		campusStr := r.URL.Query().Get("campus_id")
 
 		// Use Jakarta day boundaries so it matches roster + parent views regardless of server TZ.
 		nowJ := time.Now().In(loc)
@@ -80,10 +86,11 @@ func AdminCapacity(t *template.Template) http.HandlerFunc {
 
 		// Load classes in window
 		var classes []models.Class
-		if err := db.Conn().
-			Where("date BETWEEN ? AND ?", fromUTC, toUTC).
-			Order("date desc, name asc").
-			Find(&classes).Error; err != nil {
+		cq := db.Conn().Where("date BETWEEN ? AND ?", fromUTC, toUTC)
+		if campusID := parseCampusID(campusStr); campusID != nil {
+			cq = cq.Where("campus_id = ?", *campusID)
+		}
+		if err := cq.Order("date desc, name asc").Find(&classes).Error; err != nil {
 			http.Error(w, "db error", http.StatusInternalServerError)
 			return
 		}
@@ -94,22 +101,31 @@ func AdminCapacity(t *template.Template) http.HandlerFunc {
 			Confirmed int64
 			Waitlisted int64
 			CheckedIn int64
+			NoShow    int64
 		}
 		var aggs []capAgg
+		classIDs := make([]uint, len(classes))
+		for i, c := range classes {
+			classIDs[i] = c.ID
+		}
 		if len(classes) > 0 {
-			classIDs := make([]uint, len(classes))
-			for i, c := range classes {
-				classIDs[i] = c.ID
-			}
 			_ = db.Conn().Table("registrations").
+				Where("registrations.deleted_at IS NULL").
 				Select(`class_id,
-					SUM(CASE WHEN status = 'confirmed'  AND check_in_at IS NULL     THEN 1 ELSE 0 END) AS confirmed,
-					SUM(CASE WHEN status = 'waitlisted'                             THEN 1 ELSE 0 END) AS waitlisted,
-					SUM(CASE WHEN status = 'confirmed'  AND check_in_at IS NOT NULL THEN 1 ELSE 0 END) AS checked_in`).
+					SUM(CASE WHEN status = ? AND check_in_at IS NULL     THEN 1 ELSE 0 END) AS confirmed,
+					SUM(CASE WHEN status = ?                             THEN 1 ELSE 0 END) AS waitlisted,
+					SUM(CASE WHEN status = ? AND check_in_at IS NOT NULL THEN 1 ELSE 0 END) AS checked_in,
+					SUM(CASE WHEN status = ?                             THEN 1 ELSE 0 END) AS no_show`,
+					svc.StatusConfirmed, svc.StatusWaitlisted, svc.StatusConfirmed, svc.StatusNoShow).
 				Where("class_id IN ?", classIDs).
 				Group("class_id").
 				Scan(&aggs).Error
 		}
+		ratios, err := svc.RoomRatios(db.Conn(), classIDs)
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
 		aggMap := make(map[uint]capAgg, len(aggs))
 		for _, a := range aggs {
 			aggMap[a.ClassID] = a
@@ -143,8 +159,10 @@ func AdminCapacity(t *template.Template) http.HandlerFunc {
 				Confirmed:   confirmed,
 				Waitlisted:  waitlisted,
 				CheckedIn:   checkedIn,
+				NoShow:      agg.NoShow,
 				Available:   avail,
 				FillPercent: fill,
+				Ratio:       ratios[c.ID],
 			})
 
 			totalCap += c.Capacity
@@ -158,6 +176,9 @@ func AdminCapacity(t *template.Template) http.HandlerFunc {
 			Rows:  rows,
 			From:  fromJ.Format("2006-01-02"),
 			To:    toJ.Format("2006-01-02"),
+
+			Campus:   campusStr,
+			Campuses: allCampuses(),
 		}
 		vm.Summary.Classes = len(classes)
 		vm.Summary.Capacity = totalCap
@@ -48,6 +48,19 @@ func AdminEditClassForm(t *template.Template) http.HandlerFunc {
 			openTimeVal = jkt.Format("15:04")
 		}
 
+		// Start/end as HH:MM in Jakarta. Classes saved before StartsAt
+		// existed kept their time on Date; offer it as the start.
+		jkt, _ := time.LoadLocation("Asia/Jakarta")
+		var timeVal, endTimeVal string
+		if class.StartsAt != nil {
+			timeVal = class.StartsAt.In(jkt).Format("15:04")
+		} else if d := class.Date.In(jkt); d.Hour() != 0 || d.Minute() != 0 {
+			timeVal = d.Format("15:04")
+		}
+		if class.EndsAt != nil {
+			endTimeVal = class.EndsAt.In(jkt).Format("15:04")
+		}
+
 		// Load existing questions (ordered)
 		var qs []models.ClassQuestion
 		_ = db.Conn().
@@ -55,14 +68,23 @@ func AdminEditClassForm(t *template.Template) http.HandlerFunc {
 			Order("position asc, id asc").
 			Find(&qs).Error
 
+		var campusVal uint
+		if class.CampusID != nil {
+			campusVal = *class.CampusID
+		}
+
 		if err := view.ExecuteTemplate(w, "admin/classes_edit.tmpl", map[string]any{
 			"Title":       "Admin • Edit Class",
 			"Class":       class,
-			"DateVal":     class.Date.Format("2006-01-02"),
-			"TimeVal":     class.Date.Format("15:04"),
+			"DateVal":     class.Date.In(jkt).Format("2006-01-02"),
+			"TimeVal":     timeVal,
+			"EndTimeVal":  endTimeVal,
 			"OpenDateVal": openDateVal,
 			"OpenTimeVal": openTimeVal,
 			"Questions":   qs,
+			"Campuses":    allCampuses(),
+			"WaitlistPolicies": svc.WaitlistPolicies(),
+			"CampusVal":   campusVal,
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
 		}
@@ -84,7 +106,6 @@ func AdminUpdateClass(w http.ResponseWriter, r *http.Request) {
 	// ----- Class fields -----
 	name := normalizeClassName(r.FormValue("name"))
 	date := r.FormValue("date")     // YYYY-MM-DD
-	timeStr := r.FormValue("time")  // optional HH:MM
 	capStr := r.FormValue("capacity")
 	desc := r.FormValue("description")
 
@@ -92,20 +113,18 @@ func AdminUpdateClass(w http.ResponseWriter, r *http.Request) {
 	openDate := r.FormValue("open_date")
 	openTime := r.FormValue("open_time")
 
-	// Compose datetime for class date/time (Jakarta or UTC? We keep as parsed local then store as time with no TZ consideration like before)
-	dtStr := date
+	// Date stays the Jakarta midnight of the class day; the session's hours
+	// go to StartsAt/EndsAt.
 	loc, _ := time.LoadLocation("Asia/Jakarta")
-
-	if strings.TrimSpace(timeStr) != "" {
-		dtStr = date + " " + timeStr
-	}
-	dt, err := time.ParseInLocation("2006-01-02 15:04", dtStr, loc)
+	dt, err := time.ParseInLocation("2006-01-02", date, loc)
 	if err != nil {
-		dt, err = time.Parse("2006-01-02", date)
-		if err != nil {
-			http.Error(w, "invalid date/time", http.StatusBadRequest)
-			return
-		}
+		http.Error(w, "invalid date/time", http.StatusBadRequest)
+		return
+	}
+	startsAt, endsAt, ok := classTimesFromForm(r, dt)
+	if !ok {
+		http.Error(w, "invalid start/end time", http.StatusBadRequest)
+		return
 	}
 
 	capacity, err := strconv.Atoi(capStr)
@@ -113,6 +132,31 @@ func AdminUpdateClass(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid capacity", http.StatusBadRequest)
 		return
 	}
+	walkIns, ok := walkInSeatsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid walk-in seats", http.StatusBadRequest)
+		return
+	}
+	kidsPerAdult, ok := kidsPerAdultFromForm(r)
+	if !ok {
+		http.Error(w, "invalid ratio", http.StatusBadRequest)
+		return
+	}
+	minAge, maxAge, ok := ageLimitsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid age range", http.StatusBadRequest)
+		return
+	}
+	policy := r.FormValue("waitlist_policy")
+	if !svc.IsWaitlistPolicy(policy) {
+		http.Error(w, "unknown waitlist policy", http.StatusBadRequest)
+		return
+	}
+	campusID, ok := classCampusFromForm(r)
+	if !ok {
+		http.Error(w, "pick a campus", http.StatusBadRequest)
+		return
+	}
 
 	// Parse optional opens-at in Asia/Jakarta; store as UTC
 	var opensAt *time.Time
@@ -134,8 +178,17 @@ func AdminUpdateClass(w http.ResponseWriter, r *http.Request) {
 
 	// Save class core fields
 	class.Name = name
+	class.CampusID = campusID
 	class.Date = dt
+	class.StartsAt = startsAt
+	class.EndsAt = endsAt
+	class.Room = strings.TrimSpace(r.FormValue("room"))
 	class.Capacity = capacity
+	class.WalkInSeats = walkIns
+	class.KidsPerAdult = kidsPerAdult
+	class.MinAge = minAge
+	class.MaxAge = maxAge
+	class.WaitlistPolicy = policy
 	class.Description = desc
 	class.SignupOpensAt = opensAt
 
@@ -251,3 +304,69 @@ func normalizeChoices(s string) string {
 	}
 	return strings.Join(out, ", ")
 }
+
+// walkInSeatsFromForm reads the optional walk-in allowance; blank means none.
+func walkInSeatsFromForm(r *http.Request) (int, bool) {
+	return optionalCountFromForm(r, "walkin_seats")
+}
+
+// kidsPerAdultFromForm reads the optional child-to-adult ratio; blank means
+// none is enforced.
+func kidsPerAdultFromForm(r *http.Request) (int, bool) {
+	return optionalCountFromForm(r, "kids_per_adult")
+}
+
+func optionalCountFromForm(r *http.Request, key string) (int, bool) {
+	s := strings.TrimSpace(r.FormValue(key))
+	if s == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= 0
+}
+
+// classTimesFromForm reads the optional start ("time") and end ("end_time")
+// HH:MM on day, a Jakarta midnight. No start means an all-day class; an end
+// needs a start and must come after it.
+func classTimesFromForm(r *http.Request, day time.Time) (start, end *time.Time, ok bool) {
+	at := func(key string) (*time.Time, bool) {
+		s := strings.TrimSpace(r.FormValue(key))
+		if s == "" {
+			return nil, true
+		}
+		hm, err := time.Parse("15:04", s)
+		if err != nil {
+			return nil, false
+		}
+		t := day.Add(time.Duration(hm.Hour())*time.Hour + time.Duration(hm.Minute())*time.Minute)
+		return &t, true
+	}
+	start, okS := at("time")
+	end, okE := at("end_time")
+	if !okS || !okE || (end != nil && (start == nil || !end.After(*start))) {
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+// ageLimitsFromForm reads the optional "min_age" and "max_age" (whole years
+// on the class day). Blank means no limit; both set must not cross.
+func ageLimitsFromForm(r *http.Request) (min, max *int, ok bool) {
+	read := func(key string) (*int, bool) {
+		s := strings.TrimSpace(r.FormValue(key))
+		if s == "" {
+			return nil, true
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 99 {
+			return nil, false
+		}
+		return &n, true
+	}
+	min, okMin := read("min_age")
+	max, okMax := read("max_age")
+	if !okMin || !okMax || (min != nil && max != nil && *min > *max) {
+		return nil, nil, false
+	}
+	return min, max, true
+}
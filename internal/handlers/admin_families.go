@@ -10,6 +10,7 @@ import (
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 type familyRow struct {
@@ -20,6 +21,8 @@ type familyRow struct {
 	Sessions     int    // distinct classes in period
 	IsNew        bool
 	FirstDateStr string
+	NoShows      int  // in the policy window, ending now
+	Held         bool // over the no-show policy: new registrations start waitlisted
 }
 
 type familiesVM struct {
@@ -31,6 +34,8 @@ type familiesVM struct {
 	NewFamilies       int
 	ReturningFamilies int
 	PctNew            int
+	Policy            svc.NoShowPolicy
+	Flash             *Flash
 }
 
 func AdminFamilies(t *template.Template) http.HandlerFunc {
@@ -75,119 +80,138 @@ func AdminFamilies(t *template.Template) http.HandlerFunc {
 		// inclusive upper bound: end of day in Jakarta
 		toEnd := time.Date(to.Year(), to.Month(), to.Day(), 23, 59, 59, 0, loc)
 
-		// 1. Unique parent IDs with confirmed registrations in the period
-		var activeParentIDs []uint
+		policy, err := svc.GetNoShowPolicy(db.Conn())
+		if err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+		flash := MakeFlash(r, "", "")
+
+		// 1. Households with confirmed registrations in the period. A family with
+		// two guardians is still one family. A no-show was confirmed too.
+		// Registrations that held a seat: confirmed, whether or not the child came.
+		held := []svc.Status{svc.StatusConfirmed, svc.StatusNoShow}
+		var householdIDs []uint
 		if err := db.Conn().
 			Table("registrations").
+			Where("registrations.deleted_at IS NULL").
 			Joins("JOIN classes ON classes.id = registrations.class_id").
-			Where("registrations.status = 'confirmed' AND classes.date >= ? AND classes.date <= ?", from.UTC(), toEnd.UTC()).
+			Where("registrations.status IN ? AND classes.date >= ? AND classes.date <= ?", held, from.UTC(), toEnd.UTC()).
 			Distinct().
-			Pluck("registrations.parent_id", &activeParentIDs).Error; err != nil {
+			Pluck("registrations.household_id", &householdIDs).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
 
-		if len(activeParentIDs) == 0 {
+		if len(householdIDs) == 0 {
 			_ = view.ExecuteTemplate(w, "admin/families.tmpl", familiesVM{
-				Title: "Admin • Families",
-				From:  fromStr,
-				To:    toStr,
+				Title:  "Admin • Families",
+				From:   fromStr,
+				To:     toStr,
+				Policy: policy,
+				Flash:  flash,
 			})
 			return
 		}
 
-		// 2. Load parent records
-		var parents []models.Parent
-		if err := db.Conn().Where("id IN ?", activeParentIDs).Find(&parents).Error; err != nil {
+		// 2. Load guardians; the oldest one names the family
+		var guardians []models.Parent
+		if err := db.Conn().Where("household_id IN ?", householdIDs).Order("id asc").Find(&guardians).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
+		parents := make([]models.Parent, 0, len(householdIDs))
+		seen := make(map[uint]bool, len(householdIDs))
+		for _, g := range guardians {
+			if !seen[g.HouseholdID] {
+				seen[g.HouseholdID] = true
+				parents = append(parents, g)
+			}
+		}
 
-		// 3. First-ever confirmed class date per parent (to classify new vs returning)
-		// NOTE: MIN() on a date column returns a raw string in SQLite; scan as string then parse.
+		// 3. First-ever confirmed class date per family (to classify new vs returning)
 		type firstClassRow struct {
-			ParentID       uint
-			FirstClassDate string `gorm:"column:first_class_date"`
+			HouseholdID    uint
+			FirstClassDate db.AggTime `gorm:"column:first_class_date"`
 		}
 		var firsts []firstClassRow
 		if err := db.Conn().
 			Table("registrations").
-			Select("registrations.parent_id, MIN(classes.date) as first_class_date").
+			Where("registrations.deleted_at IS NULL").
+			Select("registrations.household_id, MIN(classes.date) as first_class_date").
 			Joins("JOIN classes ON classes.id = registrations.class_id").
-			Where("registrations.parent_id IN ? AND registrations.status = 'confirmed'", activeParentIDs).
-			Group("registrations.parent_id").
+			Where("registrations.household_id IN ? AND registrations.status IN ?", householdIDs, held).
+			Group("registrations.household_id").
 			Scan(&firsts).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
 		firstMap := make(map[uint]time.Time, len(firsts))
 		for _, f := range firsts {
-			for _, layout := range []string{
-				time.RFC3339Nano,
-				time.RFC3339,
-				"2006-01-02 15:04:05.999999999-07:00",
-				"2006-01-02 15:04:05-07:00",
-				"2006-01-02 15:04:05+00:00",
-				"2006-01-02 15:04:05",
-				"2006-01-02",
-			} {
-				if t, err := time.Parse(layout, f.FirstClassDate); err == nil {
-					firstMap[f.ParentID] = t
-					break
-				}
+			if f.FirstClassDate.Valid {
+				firstMap[f.HouseholdID] = f.FirstClassDate.Time
 			}
 		}
 
-		// 4. Distinct session count per parent in period
+		// 4. Distinct session count per family in period
 		type sessionCountRow struct {
-			ParentID uint
-			Sessions int
+			HouseholdID uint
+			Sessions    int
 		}
 		var sessionCounts []sessionCountRow
 		if err := db.Conn().
 			Table("registrations").
-			Select("registrations.parent_id, COUNT(DISTINCT registrations.class_id) as sessions").
+			Where("registrations.deleted_at IS NULL").
+			Select("registrations.household_id, COUNT(DISTINCT registrations.class_id) as sessions").
 			Joins("JOIN classes ON classes.id = registrations.class_id").
-			Where("registrations.parent_id IN ? AND registrations.status = 'confirmed' AND classes.date >= ? AND classes.date <= ?",
-				activeParentIDs, from.UTC(), toEnd.UTC()).
-			Group("registrations.parent_id").
+			Where("registrations.household_id IN ? AND registrations.status IN ? AND classes.date >= ? AND classes.date <= ?",
+				householdIDs, held, from.UTC(), toEnd.UTC()).
+			Group("registrations.household_id").
 			Scan(&sessionCounts).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
 		sessionMap := make(map[uint]int, len(sessionCounts))
 		for _, s := range sessionCounts {
-			sessionMap[s.ParentID] = s.Sessions
+			sessionMap[s.HouseholdID] = s.Sessions
 		}
 
-		// 5. Unique children per parent in the period (for display)
+		// 5. Unique children per family in the period (for display)
 		type kidInPeriod struct {
-			ParentID  uint
-			ChildName string
-			BirthDate time.Time
+			HouseholdID uint
+			ChildName   string
+			BirthDate   time.Time
 		}
 		var kidRows []kidInPeriod
 		if err := db.Conn().
 			Table("registrations").
-			Select("registrations.parent_id, children.name as child_name, children.birth_date").
+			Where("registrations.deleted_at IS NULL").
+			Select("registrations.household_id, children.name as child_name, children.birth_date").
 			Joins("JOIN classes ON classes.id = registrations.class_id").
 			Joins("JOIN children ON children.id = registrations.child_id").
-			Where("registrations.parent_id IN ? AND registrations.status = 'confirmed' AND classes.date >= ? AND classes.date <= ?",
-				activeParentIDs, from.UTC(), toEnd.UTC()).
-			Group("registrations.parent_id, registrations.child_id").
+			Where("registrations.household_id IN ? AND registrations.status IN ? AND classes.date >= ? AND classes.date <= ?",
+				householdIDs, held, from.UTC(), toEnd.UTC()).
+			Group("registrations.household_id, children.id").
 			Scan(&kidRows).Error; err != nil {
 			http.Error(w, "db error", 500)
 			return
 		}
 
-		// Group children by parent
+		// 6. No-shows per family over the policy window
+		noShows, err := svc.NoShowCounts(db.Conn(), householdIDs, policy.Since(time.Now()))
+		if err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+
+		// Group children by family
 		type kidInfo struct {
 			Name      string
 			BirthDate time.Time
 		}
-		kidMap := make(map[uint][]kidInfo, len(activeParentIDs))
+		kidMap := make(map[uint][]kidInfo, len(householdIDs))
 		for _, k := range kidRows {
-			kidMap[k.ParentID] = append(kidMap[k.ParentID], kidInfo{k.ChildName, k.BirthDate})
+			kidMap[k.HouseholdID] = append(kidMap[k.HouseholdID], kidInfo{k.ChildName, k.BirthDate})
 		}
 
 		ageStr := func(dob time.Time) string {
@@ -210,12 +234,12 @@ func AdminFamilies(t *template.Template) http.HandlerFunc {
 		newCount := 0
 
 		for _, p := range parents {
-			firstDate := firstMap[p.ID]
+			firstDate := firstMap[p.HouseholdID]
 			// "New" = their very first confirmed class falls within this period
 			isNew := !firstDate.Before(from.UTC()) && !firstDate.After(toEnd.UTC())
 
 			// Build children label
-			kids := kidMap[p.ID]
+			kids := kidMap[p.HouseholdID]
 			parts := make([]string, 0, len(kids))
 			for _, k := range kids {
 				age := ageStr(k.BirthDate)
@@ -231,10 +255,12 @@ func AdminFamilies(t *template.Template) http.HandlerFunc {
 				Name:         p.Name,
 				Phone:        p.Phone,
 				Children:     strings.Join(parts, ", "),
-				Sessions:     sessionMap[p.ID],
+				Sessions:     sessionMap[p.HouseholdID],
 				IsNew:        isNew,
 				FirstDateStr: firstDate.In(loc).Format("02 Jan 2006"),
+				NoShows:      noShows[p.HouseholdID],
 			}
+			row.Held = policy.Enabled() && row.NoShows >= policy.Limit
 			if isNew {
 				newCount++
 			}
@@ -265,9 +291,25 @@ func AdminFamilies(t *template.Template) http.HandlerFunc {
 			NewFamilies:       newCount,
 			ReturningFamilies: returning,
 			PctNew:            pctNew,
+			Policy:            policy,
+			Flash:             flash,
 		}
 		if err := view.ExecuteTemplate(w, "admin/families.tmpl", vm); err != nil {
 			http.Error(w, err.Error(), 500)
 		}
 	}
 }
+
+// POST /admin/families/noshow-policy
+func AdminNoShowPolicy(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	limit, err1 := strconv.Atoi(strings.TrimSpace(r.FormValue("limit")))
+	weeks, err2 := strconv.Atoi(strings.TrimSpace(r.FormValue("weeks")))
+	p := svc.NoShowPolicy{Limit: limit, Weeks: weeks}
+	if err1 != nil || err2 != nil || svc.SetNoShowPolicy(p) != nil {
+		http.Redirect(w, r, "/admin/families?error=noshow_policy", http.StatusSeeOther)
+		return
+	}
+	writeAudit(r, nil, "settings.noshow_policy", "policy:noshow", p.String())
+	http.Redirect(w, r, "/admin/families?ok=noshow_policy", http.StatusSeeOther)
+}
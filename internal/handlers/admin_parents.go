@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -12,7 +13,6 @@ import (
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
 	svc "github.com/lojf/nextgen/internal/services"
-	"gorm.io/gorm"
 )
 
 type parentRow struct {
@@ -51,26 +51,27 @@ func AdminParentsList(t *template.Template) http.HandlerFunc {
 				digits = strings.ReplaceAll(digits, ch, "")
 			}
 
-			// Parents whose children match the query (by name)
-			var childParentIDs []uint
+			// Households whose children match the query (by name); every
+			// guardian of such a family is a hit
+			var childHouseholdIDs []uint
 			_ = db.Conn().Model(&models.Child{}).
-				Distinct("parent_id").
+				Distinct("household_id").
 				Where("LOWER(name) LIKE ?", like).
-				Pluck("parent_id", &childParentIDs)
+				Pluck("household_id", &childHouseholdIDs)
 
 			// Base where: parent name/phone/email
 			where := `
 				LOWER(name)  LIKE ? OR
 				LOWER(phone) LIKE ? OR
-				REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(phone,'+',''),' ',''),'-',''),'(',''),')','') LIKE ? OR
+				` + db.Dialect().PhoneDigits("phone") + ` LIKE ? OR
 				LOWER(email) LIKE ?
 			`
 			args := []any{like, like, "%" + digits + "%", like}
 
-			// Add child-name hit (parent id in subquery) if any
-			if len(childParentIDs) > 0 {
-				where += " OR id IN ?"
-				args = append(args, childParentIDs)
+			// Add child-name hit (household id in subquery) if any
+			if len(childHouseholdIDs) > 0 {
+				where += " OR household_id IN ?"
+				args = append(args, childHouseholdIDs)
 			}
 
 			countQ = countQ.Where(where, args...)
@@ -93,29 +94,29 @@ func AdminParentsList(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		// ---- Build "Children (age)" summary per parent ----
+		// ---- Build "Children (age)" summary per household ----
 		childAges := map[uint]string{}
 		if len(parents) > 0 {
 			ids := make([]uint, 0, len(parents))
 			for _, p := range parents {
-				ids = append(ids, p.ID)
+				ids = append(ids, p.HouseholdID)
 			}
 
 			type kidRow struct {
-				ParentID  uint
-				Name      string
-				BirthDate time.Time
+				HouseholdID uint
+				Name        string
+				BirthDate   time.Time
 			}
 			var kids []kidRow
 			if err := db.Conn().Model(&models.Child{}).
-				Select("parent_id, name, birth_date").
-				Where("parent_id IN ?", ids).
+				Select("household_id, name, birth_date").
+				Where("household_id IN ?", ids).
 				Order("name asc").
 				Scan(&kids).Error; err == nil {
 
 				group := make(map[uint][]kidRow, len(ids))
 				for _, k := range kids {
-					group[k.ParentID] = append(group[k.ParentID], k)
+					group[k.HouseholdID] = append(group[k.HouseholdID], k)
 				}
 
 				loc, _ := time.LoadLocation("Asia/Jakarta")
@@ -135,7 +136,7 @@ func AdminParentsList(t *template.Template) http.HandlerFunc {
 					return strconv.Itoa(y)
 				}
 
-				for pid, arr := range group {
+				for hid, arr := range group {
 					parts := make([]string, 0, len(arr))
 					for _, k := range arr {
 						if k.BirthDate.IsZero() {
@@ -144,7 +145,7 @@ func AdminParentsList(t *template.Template) http.HandlerFunc {
 							parts = append(parts, k.Name+" ("+ageYears(k.BirthDate)+")")
 						}
 					}
-					childAges[pid] = strings.Join(parts, ", ")
+					childAges[hid] = strings.Join(parts, ", ")
 				}
 			}
 		}
@@ -196,6 +197,18 @@ type regHistoryRow struct {
 	CreatedAt     time.Time
 }
 
+// regEventRow is one line of the status history on the parent page.
+type regEventRow struct {
+	CreatedAt time.Time
+	Kind      string
+	Actor     string
+	Source    string
+	Note      string
+	RegCode   string
+	ChildName string
+	ClassName string
+}
+
 func AdminParentShowForm(t *template.Template) http.HandlerFunc {
 	view := template.Must(t.Clone())
 	template.Must(view.ParseFiles("templates/pages/admin/parent_show.tmpl"))
@@ -208,8 +221,11 @@ func AdminParentShowForm(t *template.Template) http.HandlerFunc {
 			http.NotFound(w, r)
 			return
 		}
-		var kids []models.Child
-		_ = db.Conn().Where("parent_id = ?", parent.ID).Order("name asc").Find(&kids).Error
+		kids := svc.HouseholdChildren(parent.HouseholdID)
+		pickups := make(map[uint][]models.PickupPerson, len(kids))
+		for _, k := range kids {
+			pickups[k.ID] = svc.PickupPersons(k.ID)
+		}
 
 		// Load registration history with class and child info
 		var regs []regHistoryRow
@@ -228,9 +244,30 @@ func AdminParentShowForm(t *template.Template) http.HandlerFunc {
 			FROM registrations r
 			JOIN classes c  ON c.id  = r.class_id
 			JOIN children ch ON ch.id = r.child_id
-			WHERE r.parent_id = ?
+			WHERE r.household_id = ? AND r.deleted_at IS NULL
 			ORDER BY c.date DESC, r.id DESC
-		`, parent.ID).Scan(&regs).Error
+		`, parent.HouseholdID).Scan(&regs).Error
+
+		// Status history, trashed registrations included: "who deleted
+		// this" is one of the questions it answers.
+		var events []regEventRow
+		_ = db.Conn().Raw(`
+			SELECT
+				e.created_at AS created_at,
+				e.kind       AS kind,
+				e.actor      AS actor,
+				e.source     AS source,
+				e.note       AS note,
+				r.code       AS reg_code,
+				ch.name      AS child_name,
+				c.name       AS class_name
+			FROM registration_events e
+			JOIN registrations r ON r.id  = e.registration_id
+			JOIN classes c       ON c.id  = r.class_id
+			JOIN children ch     ON ch.id = r.child_id
+			WHERE r.household_id = ?
+			ORDER BY e.created_at DESC, e.id DESC
+		`, parent.HouseholdID).Scan(&events).Error
 
 		loc, _ := time.LoadLocation("Asia/Jakarta")
 		for i := range regs {
@@ -246,11 +283,14 @@ func AdminParentShowForm(t *template.Template) http.HandlerFunc {
 		}
 
 		if err := view.ExecuteTemplate(w, "admin/parent_show.tmpl", map[string]any{
-			"Title":  "Admin • Parent",
-			"Parent": parent,
-			"Kids":   kids,
-			"Regs":   regs,
-			"Flash":  MakeFlash(r, errMsg, ""),
+			"Title":     "Admin • Parent",
+			"Parent":    parent,
+			"Guardians": svc.Guardians(parent.HouseholdID),
+			"Kids":      kids,
+			"Pickups":   pickups,
+			"Regs":      regs,
+			"Events":    events,
+			"Flash":     MakeFlash(r, errMsg, ""),
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
 		}
@@ -347,6 +387,8 @@ func AdminChildUpdate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	child.Gender = gender // NEW
+	child.Allergies = strings.TrimSpace(r.FormValue("allergies"))
+	child.MedicalNotes = strings.TrimSpace(r.FormValue("medical_notes"))
 
 	if err := db.Conn().Save(&child).Error; err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError); return
@@ -369,10 +411,11 @@ func AdminChildDelete(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	if err := db.Conn().Delete(&child).Error; err != nil {
+	if err := svc.DeleteChild(child.ID, staffActor(r)); err != nil {
 		http.Error(w, "db error", 500)
 		return
 	}
+	writeAudit(r, nil, "child.delete", fmt.Sprintf("child:%d (%s)", child.ID, child.Name), "")
 	http.Redirect(w, r, "/admin/parents/"+strconv.Itoa(parentID)+"?ok=child_deleted", http.StatusSeeOther)
 }
 
@@ -385,15 +428,26 @@ func AdminParentDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ---- SAFETY GUARD: block deletion if there are upcoming registrations
-	var future int64
-	if err := db.Conn().Table("registrations").
-		Joins("JOIN classes ON classes.id = registrations.class_id").
-		Where("registrations.parent_id = ? AND classes.date >= ?", parentID, time.Now()).
-		Count(&future).Error; err != nil {
-		http.Error(w, "db error", http.StatusInternalServerError)
+	var parent models.Parent
+	if err := db.Conn().First(&parent, parentID).Error; err != nil {
+		http.NotFound(w, r)
 		return
 	}
+
+	// ---- SAFETY GUARD: block deletion if there are upcoming registrations.
+	// Only the last guardian takes the family's registrations along; anyone
+	// else just hands theirs to the remaining guardians.
+	var future int64
+	if len(svc.Guardians(parent.HouseholdID)) < 2 {
+		if err := db.Conn().Table("registrations").
+			Where("registrations.deleted_at IS NULL").
+			Joins("JOIN classes ON classes.id = registrations.class_id").
+			Where("registrations.household_id = ? AND classes.date >= ?", parent.HouseholdID, time.Now()).
+			Count(&future).Error; err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+	}
 	if future > 0 {
 		// bounce back to detail page with error banner
 		http.Redirect(w, r, "/admin/parents/"+strconv.Itoa(parentID)+"?err=has_future", http.StatusSeeOther)
@@ -401,38 +455,35 @@ func AdminParentDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	// ---------------------------------------
 
-	// Gather impacted classes (to recompute waitlists after delete)
-	var regs []models.Registration
-	if err := db.Conn().Where("parent_id = ?", parentID).Find(&regs).Error; err != nil {
+	// Registrations and children go to the trash with the parent.
+	if err := svc.DeleteParent(uint(parentID), staffActor(r)); err != nil {
 		http.Error(w, "db error", http.StatusInternalServerError)
 		return
 	}
-	classSet := map[uint]struct{}{}
-	for _, r := range regs {
-		classSet[r.ClassID] = struct{}{}
-	}
+	writeAudit(r, nil, "parent.delete", fmt.Sprintf("parent:%d", parentID), "")
 
-	// Delete registrations, children, parent atomically
-	if err := db.Conn().Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("parent_id = ?", parentID).Delete(&models.Registration{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Where("parent_id = ?", parentID).Delete(&models.Child{}).Error; err != nil {
-			return err
-		}
-		if err := tx.Delete(&models.Parent{}, parentID).Error; err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
-		http.Error(w, "db error", http.StatusInternalServerError)
+	http.Redirect(w, r, "/admin/parents?ok=deleted", http.StatusSeeOther)
+}
+
+// POST /admin/parents/{id}/guardians
+func AdminGuardianAdd(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	idStr := chi.URLParam(r, "id")
+	var parent models.Parent
+	if err := db.Conn().First(&parent, idStr).Error; err != nil {
+		http.NotFound(w, r)
 		return
 	}
-
-	// Best-effort recompute for affected classes
-	for cid := range classSet {
-		_ = svc.RecomputeClass(cid)
+	email, ok := svc.NormEmail(r.FormValue("email"))
+	if !ok {
+		http.Redirect(w, r, "/admin/parents/"+idStr+"?error=invalid_email", http.StatusSeeOther)
+		return
 	}
-
-	http.Redirect(w, r, "/admin/parents?ok=deleted", http.StatusSeeOther)
+	g, err := svc.AddGuardian(parent.HouseholdID, r.FormValue("guardian_name"), r.FormValue("guardian_phone"), email)
+	if err != nil {
+		http.Redirect(w, r, "/admin/parents/"+idStr+"?error="+guardianErr(err), http.StatusSeeOther)
+		return
+	}
+	writeAudit(r, nil, "parent.guardian.add", fmt.Sprintf("parent:%d", g.ID), fmt.Sprintf("household:%d", parent.HouseholdID))
+	http.Redirect(w, r, "/admin/parents/"+idStr+"?ok=guardian_added", http.StatusSeeOther)
 }
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
@@ -21,6 +25,22 @@ func redirectBack(w http.ResponseWriter, r *http.Request, fallback string) {
 	http.Redirect(w, r, ref, http.StatusSeeOther)
 }
 
+// redirectBackError is redirectBack with an ?error= flash key for the page.
+func redirectBackError(w http.ResponseWriter, r *http.Request, fallback, reason string) {
+	ref := r.Header.Get("Referer")
+	if ref == "" {
+		ref = fallback
+	}
+	if u, err := url.Parse(ref); err == nil {
+		q := u.Query()
+		q.Del("ok")
+		q.Set("error", reason)
+		u.RawQuery = q.Encode()
+		ref = u.String()
+	}
+	http.Redirect(w, r, ref, http.StatusSeeOther)
+}
+
 // POST /admin/registrations/{id}/checkin
 //
 // Shared by the volunteer station and the admin roster. The check-in role is
@@ -32,33 +52,104 @@ func AdminRegCheckin(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", 404)
 		return
 	}
-	if reg.Status != "confirmed" {
-		checkinFail(w, r, "only_confirmed")
-		return
-	}
 	var class models.Class
-	if err := db.Conn().First(&class, reg.ClassID).Error; err != nil {
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
 		http.Error(w, "class not found", 404)
 		return
 	}
-	if err := guardCheckin(CurrentUser(r), class); err != nil {
+	if err := guardCheckinAt(CurrentUser(r), class, time.Now()); err != nil {
 		writeAudit(r, nil, "registration.checkin.denied", regTarget(reg), err.Error())
-		checkinFail(w, r, "not_allowed")
+		checkinFail(w, r, checkinDenied(err))
 		return
 	}
-	if reg.CheckInAt != nil {
+
+	before := roomBefore(class)
+	reg, err := svc.CheckIn(reg.ID, staffActor(r))
+	switch {
+	case errors.Is(err, svc.ErrAlreadyCheckedIn):
 		checkinFail(w, r, "already_checkedin")
 		return
+	case errors.Is(err, svc.ErrIllegalTransition):
+		checkinFail(w, r, "only_confirmed")
+		return
+	case err != nil:
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, "registration.checkin", regTarget(reg), "class:"+class.Name)
+	printed := printCheckinLabels(reg)
+	over := ratioAlert(r, class, before)
+	if strings.Contains(r.Header.Get("Referer"), "/station") {
+		var q string
+		if over {
+			q += "&warn=ratio_over"
+		}
+		if !printed {
+			q += printQuery([]uint{reg.ID})
+		}
+		if q != "" {
+			http.Redirect(w, r, "/station?"+q[1:], http.StatusSeeOther)
+			return
+		}
+	}
+	if !printed {
+		ref := r.Header.Get("Referer")
+		if ref == "" {
+			ref = "/admin/roster"
+		}
+		http.Redirect(w, r, withPrint(ref, []uint{reg.ID}), http.StatusSeeOther)
+		return
+	}
+	redirectBack(w, r, "/admin/roster")
+}
+
+// POST /admin/registrations/{id}/checkin/undo
+//
+// For when the wrong sibling's QR was scanned. Volunteers may only undo their
+// own shift's check-ins, shortly after; see guardUndoCheckin.
+func AdminRegUndoCheckin(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var reg models.Registration
+	if err := db.Conn().First(&reg, id).Error; err != nil {
+		http.Error(w, "not found", 404)
+		return
+	}
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		http.Error(w, "class not found", 404)
+		return
+	}
+	if err := guardUndoCheckin(CurrentUser(r), class, reg, actorLabel(r), time.Now()); err != nil {
+		writeAudit(r, nil, "registration.checkin.undo.denied", regTarget(reg), err.Error())
+		switch err {
+		case ErrUndoWindowClosed:
+			checkinFail(w, r, "undo_window")
+		case ErrUndoOtherShift:
+			checkinFail(w, r, "undo_other_shift")
+		default:
+			checkinFail(w, r, "not_allowed")
+		}
+		return
 	}
 
-	now := time.Now()
-	reg.CheckInAt = &now
-	reg.CheckedInBy = actorLabel(r)
-	if err := db.Conn().Save(&reg).Error; err != nil {
+	was := reg.CheckedInBy
+	reg, err := svc.UndoCheckIn(reg.ID, staffActor(r))
+	switch {
+	case errors.Is(err, svc.ErrNotCheckedIn):
+		checkinFail(w, r, "not_checked_in")
+		return
+	case errors.Is(err, svc.ErrIllegalTransition):
+		checkinFail(w, r, "undo_not_allowed")
+		return
+	case err != nil:
 		http.Error(w, "db error", 500)
 		return
 	}
-	writeAudit(r, nil, "registration.checkin", regTarget(reg), "class:"+class.Name)
+	writeAudit(r, nil, "registration.checkin.undo", regTarget(reg), "checked in by "+was)
+	if strings.Contains(r.Header.Get("Referer"), "/station") {
+		http.Redirect(w, r, "/station?ok=checkin_undone", http.StatusSeeOther)
+		return
+	}
 	redirectBack(w, r, "/admin/roster")
 }
 
@@ -85,7 +176,12 @@ func AdminRegCancel(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", 404)
 		return
 	}
-	if err := svc.CancelByCode(reg.Code); err != nil {
+	err := svc.CancelByCode(reg.Code, staffActor(r))
+	if errors.Is(err, svc.ErrIllegalTransition) {
+		redirectBackError(w, r, "/admin/roster", "cannot_cancel")
+		return
+	}
+	if err != nil {
 		http.Error(w, "unable to cancel", 500)
 		return
 	}
@@ -94,6 +190,8 @@ func AdminRegCancel(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST /admin/registrations/{id}/delete
+//
+// Moves the registration to the trash; /admin/trash can restore it.
 func AdminRegDelete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var reg models.Registration
@@ -101,12 +199,53 @@ func AdminRegDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not found", 404)
 		return
 	}
-	classID := reg.ClassID
-	if err := db.Conn().Delete(&reg).Error; err != nil {
+	if err := svc.DeleteRegistration(reg.ID, staffActor(r)); err != nil {
 		http.Error(w, "db error", 500)
 		return
 	}
 	writeAudit(r, nil, "registration.delete", regTarget(reg), "")
-	_ = svc.RecomputeClass(classID)
 	redirectBack(w, r, "/admin/roster")
 }
+
+// POST /admin/classes/{id}/register — adds a child from the roster. With
+// override_age=1 the class's age limits are waived for this registration;
+// the roster then marks it and the audit log says who waived them.
+func AdminClassRegister(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	classID, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	childID, _ := strconv.Atoi(r.FormValue("child_id"))
+	back := fmt.Sprintf("/admin/roster?class_id=%d&add_phone=%s", classID, url.QueryEscape(r.FormValue("add_phone")))
+	if classID <= 0 || childID <= 0 {
+		http.Redirect(w, r, back+"&error=missing_child", http.StatusSeeOther)
+		return
+	}
+	override := r.FormValue("override_age") == "1"
+
+	res, err := svc.AdminRegister(uint(childID), uint(classID), override, staffActor(r))
+	switch {
+	case errors.Is(err, svc.ErrAgeIneligible):
+		http.Redirect(w, r, back+"&error=age_override_needed", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrDuplicateReg):
+		http.Redirect(w, r, back+"&error=already_registered", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrSameDayReg):
+		http.Redirect(w, r, back+"&error=same_day_conflict", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrNotOpenYet):
+		http.Redirect(w, r, back+"&error=not_open_yet", http.StatusSeeOther)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, "db error", 500)
+		return
+	}
+	reg := res.Registration
+	writeAudit(r, nil, "registration.admin_add", regTarget(reg), fmt.Sprintf("class:%d status:%s", classID, res.Status))
+	if reg.AgeOverrideBy != "" {
+		writeAudit(r, nil, "registration.age_override", regTarget(reg), fmt.Sprintf("child:%d class:%d", childID, classID))
+	}
+	http.Redirect(w, r, fmt.Sprintf("/admin/roster?class_id=%d&ok=registered", classID), http.StatusSeeOther)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// TestAdminRegCancel_NoShowFlashes checks that cancelling a registration the
+// state machine will not cancel, a no-show, goes back to the roster with a
+// flash instead of a 500.
+func TestAdminRegCancel_NoShowFlashes(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	h := models.Household{Name: "Santoso"}
+	gdb.Create(&h)
+	p := models.Parent{Name: "Ibu Santoso", Phone: "+6281234567001", HouseholdID: h.ID}
+	gdb.Create(&p)
+	kid := models.Child{Name: "Budi", ParentID: p.ID, HouseholdID: h.ID}
+	gdb.Create(&kid)
+	cl := models.Class{Name: "Little Stars", Date: time.Now().AddDate(0, 0, -7), Capacity: 10}
+	gdb.Create(&cl)
+	reg := models.Registration{HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+		Status: "no_show", Code: "REG-NS"}
+	gdb.Create(&reg)
+
+	r := httptest.NewRequest("POST", "/admin/registrations/1/cancel", nil)
+	r.Header.Set("Referer", "/admin/roster?status=no_show")
+	rc := chi.NewRouteContext()
+	rc.URLParams.Add("id", strconv.Itoa(int(reg.ID)))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rc))
+	w := httptest.NewRecorder()
+	AdminRegCancel(w, r)
+
+	if loc := w.Header().Get("Location"); w.Code != http.StatusSeeOther || loc != "/admin/roster?error=cannot_cancel&status=no_show" {
+		t.Errorf("cancel = %d to %q, want a flash back on the roster", w.Code, loc)
+	}
+	var got models.Registration
+	gdb.First(&got, reg.ID)
+	if got.Status != "no_show" {
+		t.Errorf("status = %q, want no_show", got.Status)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 type rosterRow struct {
@@ -38,21 +39,76 @@ type rosterRow struct {
 	CreatedAt    time.Time
 	WaitlistRank int
 	IsFirstTimer bool
+	AgeOverrideBy string
+	WaitlistReason string // why the class's waitlist policy moved it
 }
 
 type rosterPageVM struct {
 	Title     string
 	Rows      []rosterRow
 	Classes   []models.Class
+	Campuses  []models.Campus
 	Filters   rosterFilters
 	HasResult bool
 	Answers   map[uint][]string // regID -> ["Label: Answer", ...]
+	Flash     *Flash
+
+	// Adding a child to the filtered class; see AdminClassRegister.
+	AddClass *models.Class
+	AddPhone string
+	AddKids  []rosterAddKid
+
+	// Waitlist is the filtered class's waitlist policy, to explain the ranks.
+	Waitlist *svc.WaitlistPolicyOption
+
+	// Print is the registrations just checked in whose labels no campus
+	// printer took; see withPrint.
+	Print []uint
+
+	Counts rosterCounts
+}
+
+// rosterCounts tallies the rows shown by the same buckets as the status
+// filter: confirmed means not yet checked in.
+type rosterCounts struct {
+	Confirmed  int
+	CheckedIn  int
+	Waitlisted int
+	Canceled   int
+	NoShow     int
+}
+
+func countRoster(rows []rosterRow) rosterCounts {
+	var c rosterCounts
+	for _, rw := range rows {
+		switch {
+		case rw.Status == string(svc.StatusConfirmed) && rw.CheckInAt != nil:
+			c.CheckedIn++
+		case rw.Status == string(svc.StatusConfirmed):
+			c.Confirmed++
+		case rw.Status == string(svc.StatusWaitlisted):
+			c.Waitlisted++
+		case rw.Status == string(svc.StatusCanceled):
+			c.Canceled++
+		case rw.Status == string(svc.StatusNoShow):
+			c.NoShow++
+		}
+	}
+	return c
+}
+
+// rosterAddKid is a household child offered for the class picked in the
+// roster filter, with why their age would need an override.
+type rosterAddKid struct {
+	Child   models.Child
+	AgeNote string
 }
 
 type rosterFilters struct {
 	From    string
 	To      string
 	ClassID string
+	Campus  string // campus_id
 	Status  string
 	Q       string
 }
@@ -102,11 +158,11 @@ func rosterWindow(fFrom, fTo string) (time.Time, time.Time) {
 
 func statusWeight(s string) int {
 	switch s {
-	case "confirmed":
+	case string(svc.StatusConfirmed):
 		return 0
-	case "waitlisted":
+	case string(svc.StatusWaitlisted):
 		return 1
-	case "canceled":
+	case string(svc.StatusCanceled):
 		return 2
 	default:
 		return 3
@@ -122,6 +178,7 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
         fFrom    := r.URL.Query().Get("from")
         fTo      := r.URL.Query().Get("to")
         fClassID := r.URL.Query().Get("class_id")
+        fCampus  := r.URL.Query().Get("campus_id")
         fStatus  := r.URL.Query().Get("status")
         fQ       := strings.TrimSpace(r.URL.Query().Get("q"))
 
@@ -135,7 +192,9 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
         _ = db.Conn().Order("date asc").Find(&classes).Error
 
         q := db.Conn().Table("registrations").
+            Where("registrations.deleted_at IS NULL").
             Select(`registrations.id, registrations.code, registrations.status, registrations.check_in_at, registrations.created_at,
+                    registrations.age_override_by,
                     registrations.parent_id as parent_id,
                     children.id as child_id, children.name as child_name, children.birth_date as birth_date, children.gender as gender,
                     classes.id as class_id, classes.name as class_name, classes.date as class_date,
@@ -150,17 +209,20 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
                 q = q.Where("classes.id = ?", cid)
             }
         }
+        if campusID := parseCampusID(fCampus); campusID != nil {
+            q = q.Where("classes.campus_id = ?", *campusID)
+        }
 
         if fStatus != "" {
             switch fStatus {
-            case "confirmed":
+            case string(svc.StatusConfirmed):
                 // Only confirmed and NOT yet checked in
-                q = q.Where("registrations.status = ? AND registrations.check_in_at IS NULL", "confirmed")
-            case "waitlisted", "canceled":
+                q = q.Where("registrations.status = ? AND registrations.check_in_at IS NULL", svc.StatusConfirmed)
+            case string(svc.StatusWaitlisted), string(svc.StatusCanceled), string(svc.StatusNoShow):
                 q = q.Where("registrations.status = ?", fStatus)
             case "checked-in":
                 // Only confirmed and already checked in
-                q = q.Where("registrations.status = ? AND registrations.check_in_at IS NOT NULL", "confirmed")
+                q = q.Where("registrations.status = ? AND registrations.check_in_at IS NOT NULL", svc.StatusConfirmed)
             }
         }
 
@@ -175,7 +237,7 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
                 LOWER(children.name)       LIKE ? OR
                 LOWER(parents.name)        LIKE ? OR
                 LOWER(registrations.code)  LIKE ? OR
-                REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(parents.phone,'+',''),' ',''),'-',''),'(',''),')','') LIKE ?
+                ` + db.Dialect().PhoneDigits("parents.phone") + ` LIKE ?
             `
             q = q.Where(where, like, like, like, digitsLike)
         }
@@ -205,20 +267,11 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
             }
             return statusWeight(rows[i].Status) < statusWeight(rows[j].Status)
         })
-/*
-        ranks := map[uint]int{}
-        for i := range rows {
-            if rows[i].Status == "waitlisted" {
-                ranks[rows[i].ClassID]++
-                rows[i].WaitlistRank = ranks[rows[i].ClassID]
-            }
-        }
-*/
-
-        // --- FIX WAITLIST RANK (FIFO) ---
-        // Current UI order is newest-first; rank must be oldest-first.
-        // Build a regID -> rank map using FIFO ordering inside each class.
+        // --- WAITLIST RANK ---
+        // Current UI order is newest-first; rank follows each class's
+        // waitlist policy (FIFO unless the class picked another).
         wlRankByRegID := map[uint]int{}
+        wlReasonByRegID := map[uint]string{}
 
         if len(rows) > 0 {
             // Collect class IDs present in the result
@@ -231,30 +284,27 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
                 }
             }
 
-            // Load ALL waitlisted regs for those classes in FIFO order
+            // Rank ALL waitlisted regs of those classes, in policy order.
             // NOTE: do NOT filter by date window here; classIDs already reflect it.
-            type wlRow struct {
-                ID      uint
-                ClassID uint
-            }
-            var wls []wlRow
-            _ = db.Conn().Table("registrations").
-                Select("id, class_id").
-                Where("class_id IN ? AND status = ?", classIDs, "waitlisted").
-                Order("class_id ASC, created_at ASC, id ASC").
-                Scan(&wls).Error
-
-            // Assign ranks per class in FIFO order
-            perClass := map[uint]int{}
-            for _, wr := range wls {
-                perClass[wr.ClassID]++
-                wlRankByRegID[wr.ID] = perClass[wr.ClassID]
+            var wlClasses []models.Class
+            _ = db.Conn().Where("id IN ?", classIDs).Find(&wlClasses).Error
+            for _, c := range wlClasses {
+                order, err := svc.WaitlistOrder(db.Conn(), c)
+                if err != nil {
+                    http.Error(w, "db error", http.StatusInternalServerError)
+                    return
+                }
+                for i, e := range order {
+                    wlRankByRegID[e.Registration.ID] = i + 1
+                    wlReasonByRegID[e.Registration.ID] = e.Reason
+                }
             }
 
             // Attach rank back to the displayed rows
             for i := range rows {
-                if rows[i].Status == "waitlisted" {
+                if rows[i].Status == string(svc.StatusWaitlisted) {
                     rows[i].WaitlistRank = wlRankByRegID[rows[i].ID]
+                    rows[i].WaitlistReason = wlReasonByRegID[rows[i].ID]
                 }
             }
         }
@@ -277,6 +327,7 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
             }
             var firstRegs []firstRegRow
             _ = db.Conn().Table("registrations").
+                Where("registrations.deleted_at IS NULL").
                 Select("child_id, MIN(id) as first_reg_id").
                 Where("child_id IN ?", childIDs).
                 Group("child_id").
@@ -325,16 +376,28 @@ func AdminRoster(t *template.Template) http.HandlerFunc {
         vm := rosterPageVM{
             Title:   "Admin • Roster",
             Rows:    rows,
-            Classes: classes,
+            Classes:  classes,
+            Campuses: allCampuses(),
             Filters: rosterFilters{
                 From:    fFrom,
                 To:      fTo,
                 ClassID: fClassID,
+                Campus:  fCampus,
                 Status:  fStatus,
                 Q:       fQ,
             },
             HasResult: len(rows) > 0,
             Answers:   answers,
+            Flash:     MakeFlash(r, "", ""),
+            Print:     printIDs(r),
+            Counts:    countRoster(rows),
+        }
+        if cid, err := strconv.Atoi(fClassID); err == nil && cid > 0 {
+            vm.AddClass, vm.AddPhone, vm.AddKids = rosterAddLookup(uint(cid), r.URL.Query().Get("add_phone"))
+            if vm.AddClass != nil {
+                wp := svc.DescribeWaitlistPolicy(vm.AddClass.WaitlistPolicy)
+                vm.Waitlist = &wp
+            }
         }
 
         if err := view.ExecuteTemplate(w, "admin/roster.tmpl", vm); err != nil {
@@ -347,6 +410,7 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 	fFrom    := r.URL.Query().Get("from")
 	fTo      := r.URL.Query().Get("to")
 	fClassID := r.URL.Query().Get("class_id")
+	fCampus  := r.URL.Query().Get("campus_id")
 	fStatus  := r.URL.Query().Get("status")
 	fQ       := strings.TrimSpace(r.URL.Query().Get("q"))
 
@@ -376,6 +440,7 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 	}
 
 	q := db.Conn().Table("registrations").
+		Where("registrations.deleted_at IS NULL").
 		Select(`registrations.id, registrations.code, registrations.status, registrations.check_in_at, registrations.created_at,
 		        registrations.parent_id as parent_id,
 		        children.id as child_id, children.name as child_name, children.gender as gender, children.birth_date as birth_date,
@@ -391,14 +456,17 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 			q = q.Where("classes.id = ?", cid)
 		}
 	}
+	if campusID := parseCampusID(fCampus); campusID != nil {
+		q = q.Where("classes.campus_id = ?", *campusID)
+	}
 
 	if fStatus != "" {
 		switch fStatus {
-		case "confirmed":
-			q = q.Where("registrations.status = 'confirmed' AND registrations.check_in_at IS NULL")
+		case string(svc.StatusConfirmed):
+			q = q.Where("registrations.status = ? AND registrations.check_in_at IS NULL", svc.StatusConfirmed)
 		case "checked-in":
-			q = q.Where("registrations.status = 'confirmed' AND registrations.check_in_at IS NOT NULL")
-		case "waitlisted", "canceled":
+			q = q.Where("registrations.status = ? AND registrations.check_in_at IS NOT NULL", svc.StatusConfirmed)
+		case string(svc.StatusWaitlisted), string(svc.StatusCanceled), string(svc.StatusNoShow):
 			q = q.Where("registrations.status = ?", fStatus)
 		}
 	}
@@ -417,7 +485,7 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 			LOWER(classes.name) LIKE ? OR
 			LOWER(registrations.code) LIKE ? OR
 			LOWER(registrations.status) LIKE ? OR
-			REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(parents.phone,'+',''),' ',''),'-',''),'(',''),')','') LIKE ?
+			` + db.Dialect().PhoneDigits("parents.phone") + ` LIKE ?
 		`
 		q = q.Where(where, like, like, like, like, like, digitsLike)
 	}
@@ -446,6 +514,7 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 		}
 		var firstRegs []firstRegRowCSV
 		_ = db.Conn().Table("registrations").
+			Where("registrations.deleted_at IS NULL").
 			Select("child_id, MIN(id) as first_reg_id").
 			Where("child_id IN ?", childIDs).
 			Group("child_id").
@@ -536,3 +605,30 @@ func AdminRosterCSV(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 }
+
+// rosterAddLookup loads the class the roster is filtered to and, once a
+// phone is typed, that family's children with their age against the class.
+func rosterAddLookup(classID uint, phone string) (*models.Class, string, []rosterAddKid) {
+	var class models.Class
+	if err := db.Conn().First(&class, classID).Error; err != nil {
+		return nil, "", nil
+	}
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return &class, "", nil
+	}
+	parent, err := svc.FindParentByAny(phone)
+	if err != nil || parent == nil {
+		return &class, phone, nil
+	}
+	var kids []models.Child
+	_ = db.Conn().Where("household_id = ?", parent.HouseholdID).Order("name asc").Find(&kids).Error
+	out := make([]rosterAddKid, len(kids))
+	for i, k := range kids {
+		out[i] = rosterAddKid{Child: k}
+		if err := svc.CheckAge(k, class); err != nil {
+			out[i].AgeNote = err.Error()
+		}
+	}
+	return &class, phone, out
+}
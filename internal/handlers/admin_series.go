@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// maxSeriesWeeks caps how far ahead one Generate may fill in sessions.
+const maxSeriesWeeks = 52
+
+// GET /admin/series — every class series with its upcoming sessions.
+func AdminSeries(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/series.tmpl"))
+
+	type seriesRow struct {
+		models.ClassSeries
+		Upcoming  int
+		Next      *models.Class
+		LastDate  *time.Time
+		Questions int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var series []models.ClassSeries
+		if err := db.Conn().Preload("Campus").Order("LOWER(name) asc").Find(&series).Error; err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+		rows := make([]seriesRow, len(series))
+		for i, s := range series {
+			rows[i] = seriesRow{ClassSeries: s}
+			if next, err := svc.RemainingSessions(s.ID); err == nil && len(next) > 0 {
+				rows[i].Upcoming = len(next)
+				rows[i].Next = &next[0]
+				rows[i].LastDate = &next[len(next)-1].Date
+			}
+			var n int64
+			db.Conn().Model(&models.ClassQuestion{}).Where("series_id = ?", s.ID).Count(&n)
+			rows[i].Questions = int(n)
+		}
+		if err := view.ExecuteTemplate(w, "admin/series.tmpl", map[string]any{
+			"Title":  "Admin • Series",
+			"Series": rows,
+			"Flash":  MakeFlash(r, "", ""),
+		}); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// GET /admin/series/new
+func AdminNewSeries(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/series_new.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := view.ExecuteTemplate(w, "admin/series_new.tmpl", map[string]any{
+			"Title":    "Admin • New Series",
+			"Campuses": allCampuses(),
+		}); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// POST /admin/series — saves the series and its shared questions, then
+// generates the first weeks of sessions.
+func AdminCreateSeries(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	bad := func(msg string) { http.Error(w, msg, http.StatusBadRequest) }
+
+	name := normalizeClassName(r.FormValue("name"))
+	if name == "" {
+		bad("missing name")
+		return
+	}
+	// The first date and the times are the campus's clock.
+	campusID, ok := classCampusFromForm(r)
+	if !ok {
+		bad("pick a campus")
+		return
+	}
+	first, err := time.ParseInLocation("2006-01-02", r.FormValue("first_date"), campusLoc(campusID))
+	if err != nil {
+		bad("invalid first date")
+		return
+	}
+	interval, err := strconv.Atoi(strings.TrimSpace(r.FormValue("interval_weeks")))
+	if err != nil || interval < 1 || interval > 8 {
+		bad("repeat every 1 to 8 weeks")
+		return
+	}
+	weeks, err := strconv.Atoi(strings.TrimSpace(r.FormValue("weeks")))
+	if err != nil || weeks < 1 || weeks > maxSeriesWeeks {
+		bad(fmt.Sprintf("generate 1 to %d weeks", maxSeriesWeeks))
+		return
+	}
+	// Times are checked the way the class form checks them.
+	if _, _, ok := classTimesFromForm(r, first); !ok {
+		bad("invalid start/end time")
+		return
+	}
+	capacity, err := strconv.Atoi(r.FormValue("capacity"))
+	if err != nil || capacity < 0 {
+		bad("invalid capacity")
+		return
+	}
+	walkIns, ok := walkInSeatsFromForm(r)
+	if !ok {
+		bad("invalid walk-in seats")
+		return
+	}
+	kidsPerAdult, ok := kidsPerAdultFromForm(r)
+	if !ok {
+		bad("invalid ratio")
+		return
+	}
+	minAge, maxAge, ok := ageLimitsFromForm(r)
+	if !ok {
+		bad("invalid age range")
+		return
+	}
+	blackouts, ok := blackoutsFromForm(r.FormValue("blackouts"))
+	if !ok {
+		bad("blackout dates must be YYYY-MM-DD")
+		return
+	}
+
+	s := models.ClassSeries{
+		Name:          name,
+		Description:   strings.TrimSpace(r.FormValue("description")),
+		CampusID:      campusID,
+		FirstDate:     first,
+		IntervalWeeks: interval,
+		StartTime:     strings.TrimSpace(r.FormValue("time")),
+		EndTime:       strings.TrimSpace(r.FormValue("end_time")),
+		Blackouts:     blackouts,
+		Room:          strings.TrimSpace(r.FormValue("room")),
+		Capacity:      capacity,
+		WalkInSeats:   walkIns,
+		KidsPerAdult:  kidsPerAdult,
+		MinAge:        minAge,
+		MaxAge:        maxAge,
+	}
+	err = db.Conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&s).Error; err != nil {
+			return err
+		}
+		for _, q := range questionsFromForm(r) {
+			q.SeriesID = &s.ID
+			if err := tx.Create(&q).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	made, err := svc.GenerateSessions(s.ID, weeks)
+	if err != nil {
+		http.Error(w, "db error (sessions)", http.StatusInternalServerError)
+		return
+	}
+	writeAudit(r, nil, "series.create", fmt.Sprintf("series:%d (%s)", s.ID, s.Name), fmt.Sprintf("%d sessions", len(made)))
+	http.Redirect(w, r, "/admin/series?ok=series_generated", http.StatusSeeOther)
+}
+
+// POST /admin/series/{id}/generate — fills in sessions for the next weeks
+// weeks; days that already have one are left alone.
+func AdminGenerateSeries(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	var s models.ClassSeries
+	if err := db.Conn().First(&s, chi.URLParam(r, "id")).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	weeks, err := strconv.Atoi(strings.TrimSpace(r.FormValue("weeks")))
+	if err != nil || weeks < 1 || weeks > maxSeriesWeeks {
+		http.Error(w, fmt.Sprintf("generate 1 to %d weeks", maxSeriesWeeks), http.StatusBadRequest)
+		return
+	}
+	made, err := svc.GenerateSessions(s.ID, weeks)
+	if err != nil {
+		http.Error(w, "db error", http.StatusInternalServerError)
+		return
+	}
+	writeAudit(r, nil, "series.generate", fmt.Sprintf("series:%d (%s)", s.ID, s.Name), fmt.Sprintf("%d sessions", len(made)))
+	http.Redirect(w, r, "/admin/series?ok=series_generated", http.StatusSeeOther)
+}
+
+// blackoutsFromForm normalizes blackout dates typed one per line or comma
+// separated into sorted "2006-01-02,2006-01-09".
+func blackoutsFromForm(s string) (string, bool) {
+	seen := map[string]bool{}
+	var out []string
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' || r == ' ' }) {
+		d, err := time.Parse("2006-01-02", f)
+		if err != nil {
+			return "", false
+		}
+		if k := d.Format("2006-01-02"); !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ","), true
+}
@@ -55,7 +55,12 @@ func AdminTemplatesCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tpl := models.ClassTemplate{Name: name, Description: desc}
+	minAge, maxAge, ok := ageLimitsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid age range", http.StatusBadRequest); return
+	}
+
+	tpl := models.ClassTemplate{Name: name, Description: desc, MinAge: minAge, MaxAge: maxAge}
 	if err := db.Conn().Create(&tpl).Error; err != nil {
 		http.Error(w, "db error", 500); return
 	}
@@ -138,6 +143,12 @@ func AdminTemplatesUpdate(w http.ResponseWriter, r *http.Request) {
 	// Update template header
 	tpl.Name = strings.TrimSpace(r.FormValue("name"))
 	tpl.Description = strings.TrimSpace(r.FormValue("description"))
+	minAge, maxAge, ok := ageLimitsFromForm(r)
+	if !ok {
+		http.Error(w, "invalid age range", http.StatusBadRequest)
+		return
+	}
+	tpl.MinAge, tpl.MaxAge = minAge, maxAge
 	if err := db.Conn().Save(&tpl).Error; err != nil {
 		http.Error(w, "db error (template)", http.StatusInternalServerError)
 		return
@@ -269,6 +280,8 @@ func AdminTemplatesShowJSON(w http.ResponseWriter, r *http.Request) {
 		ID          uint   `json:"id"`
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		MinAge      *int   `json:"min_age"`
+		MaxAge      *int   `json:"max_age"`
 		Questions   []struct {
 			Label    string `json:"label"`
 			Kind     string `json:"kind"`
@@ -277,7 +290,7 @@ func AdminTemplatesShowJSON(w http.ResponseWriter, r *http.Request) {
 			Position int    `json:"position"`
 		} `json:"questions"`
 	}
-	out := jq{ID: tpl.ID, Name: tpl.Name, Description: tpl.Description}
+	out := jq{ID: tpl.ID, Name: tpl.Name, Description: tpl.Description, MinAge: tpl.MinAge, MaxAge: tpl.MaxAge}
 	for _, q := range qs {
 		out.Questions = append(out.Questions, struct {
 			Label    string `json:"label"`
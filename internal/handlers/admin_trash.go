@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+type trashRow struct {
+	ID        uint
+	Label     string
+	Detail    string
+	DeletedAt time.Time
+}
+
+type trashSection struct {
+	Title string
+	Kind  svc.TrashKind
+	Rows  []trashRow
+}
+
+type trashVM struct {
+	Title    string
+	Sections []trashSection
+	Flash    *Flash
+}
+
+// GET /admin/trash
+//
+// Rows deleted together with a parent, child or class are not listed on their
+// own: they come back when that owner is restored.
+func AdminTrash(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/trash.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		parentsS := trashSection{Title: "Parents", Kind: svc.TrashParent}
+		childrenS := trashSection{Title: "Children", Kind: svc.TrashChild}
+		classesS := trashSection{Title: "Classes", Kind: svc.TrashClass}
+		regsS := trashSection{Title: "Registrations", Kind: svc.TrashRegistration}
+		trashed := func() *gorm.DB { return db.Conn().Unscoped() }
+
+		var parents []models.Parent
+		if err := trashed().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&parents).Error; err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+		for _, p := range parents {
+			parentsS.Rows = append(parentsS.Rows, trashRow{p.ID, p.Name, p.Phone, p.DeletedAt.Time})
+		}
+
+		if err := trashed().Table("children").
+			Select("children.id, children.name AS label, parents.name AS detail, children.deleted_at").
+			Joins("JOIN parents ON parents.id = children.parent_id AND parents.deleted_at IS NULL").
+			Where("children.deleted_at IS NOT NULL").
+			Order("children.deleted_at desc").
+			Scan(&childrenS.Rows).Error; err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+
+		var classes []models.Class
+		if err := trashed().Where("deleted_at IS NOT NULL").Order("deleted_at desc").Find(&classes).Error; err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+		for _, c := range classes {
+			classesS.Rows = append(classesS.Rows, trashRow{c.ID, c.Name, fmtDate(c.Date), c.DeletedAt.Time})
+		}
+
+		var regs []struct {
+			ID        uint
+			Code      string
+			Status    string
+			ChildName string
+			ClassName string
+			DeletedAt time.Time
+		}
+		if err := trashed().Table("registrations").
+			Select(`registrations.id, registrations.code, registrations.status, registrations.deleted_at,
+			        children.name AS child_name, classes.name AS class_name`).
+			Joins("JOIN children ON children.id = registrations.child_id AND children.deleted_at IS NULL").
+			Joins("JOIN classes  ON classes.id  = registrations.class_id AND classes.deleted_at IS NULL").
+			Where("registrations.deleted_at IS NOT NULL").
+			Order("registrations.deleted_at desc").
+			Scan(&regs).Error; err != nil {
+			http.Error(w, "db error", 500)
+			return
+		}
+		for _, rg := range regs {
+			regsS.Rows = append(regsS.Rows, trashRow{
+				rg.ID, rg.Code + " · " + rg.ChildName, rg.ClassName + " (" + rg.Status + ")", rg.DeletedAt,
+			})
+		}
+
+		vm := trashVM{
+			Title:    "Admin • Trash",
+			Sections: []trashSection{parentsS, childrenS, classesS, regsS},
+			Flash:    MakeFlash(r, "", ""),
+		}
+		if err := view.ExecuteTemplate(w, "admin/trash.tmpl", vm); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// POST /admin/trash/{kind}/{id}/restore
+func AdminTrashRestore(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := trashTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := svc.Restore(kind, id, staffActor(r)); err != nil {
+		trashFail(w, r, err)
+		return
+	}
+	writeAudit(r, nil, string(kind)+".restore", fmt.Sprintf("%s:%d", kind, id), "")
+	http.Redirect(w, r, "/admin/trash?ok=restored", http.StatusSeeOther)
+}
+
+// POST /admin/trash/{kind}/{id}/purge
+func AdminTrashPurge(w http.ResponseWriter, r *http.Request) {
+	kind, id, ok := trashTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := svc.Purge(kind, id); err != nil {
+		trashFail(w, r, err)
+		return
+	}
+	writeAudit(r, nil, string(kind)+".purge", fmt.Sprintf("%s:%d", kind, id), "")
+	http.Redirect(w, r, "/admin/trash?ok=purged", http.StatusSeeOther)
+}
+
+func trashTarget(w http.ResponseWriter, r *http.Request) (svc.TrashKind, uint, bool) {
+	kind := svc.TrashKind(chi.URLParam(r, "kind"))
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	switch kind {
+	case svc.TrashParent, svc.TrashChild, svc.TrashClass, svc.TrashRegistration:
+	default:
+		err = svc.ErrUnknownKind
+	}
+	if err != nil || id <= 0 {
+		http.NotFound(w, r)
+		return "", 0, false
+	}
+	return kind, uint(id), true
+}
+
+func trashFail(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, svc.ErrOwnerDeleted):
+		http.Redirect(w, r, "/admin/trash?error=owner_deleted", http.StatusSeeOther)
+	case errors.Is(err, svc.ErrPhoneInUse):
+		http.Redirect(w, r, "/admin/trash?error=phone_in_use", http.StatusSeeOther)
+	case errors.Is(err, svc.ErrDuplicateReg):
+		http.Redirect(w, r, "/admin/trash?error=already_registered", http.StatusSeeOther)
+	default:
+		http.Error(w, "db error", http.StatusInternalServerError)
+	}
+}
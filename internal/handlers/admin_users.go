@@ -24,7 +24,7 @@ type usersVM struct {
 	Me      string
 	Flash   *Flash
 	Roles   []string
-	Campus  []string
+	Campus  []models.Campus
 }
 
 // GET /admin/users
@@ -34,7 +34,7 @@ func AdminUsers(t *template.Template) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		var us []models.AdminUser
-		if err := db.Conn().Order("role ASC, username ASC").Find(&us).Error; err != nil {
+		if err := db.Conn().Preload("Campus").Order("role ASC, username ASC").Find(&us).Error; err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
@@ -55,7 +55,7 @@ func AdminUsers(t *template.Template) http.HandlerFunc {
 			Users:  rows,
 			Me:     me,
 			Roles:  []string{models.RoleAdmin, models.RoleCheckin},
-			Campus: []string{"", "FJB", "FJU"},
+			Campus: allCampuses(),
 			Flash:  MakeFlash(r, r.URL.Query().Get("error"), r.URL.Query().Get("ok")),
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
@@ -69,7 +69,7 @@ func AdminUserCreate(w http.ResponseWriter, r *http.Request) {
 	username := strings.ToLower(strings.TrimSpace(r.FormValue("username")))
 	pw := r.FormValue("password")
 	role := r.FormValue("role")
-	campus := strings.ToUpper(strings.TrimSpace(r.FormValue("campus")))
+	campusID := parseCampusID(r.FormValue("campus_id"))
 
 	if username == "" || len(pw) < 8 {
 		http.Redirect(w, r, "/admin/users?error=username+wajib+dan+password+minimal+8+karakter", http.StatusSeeOther)
@@ -81,7 +81,11 @@ func AdminUserCreate(w http.ResponseWriter, r *http.Request) {
 	}
 	// Campus only constrains check-in accounts; admins always see everything.
 	if role == models.RoleAdmin {
-		campus = ""
+		campusID = nil
+	}
+	if campusID != nil && campusLabel(campusID) == "" {
+		http.Redirect(w, r, "/admin/users?error=campus+tidak+dikenal", http.StatusSeeOther)
+		return
 	}
 	var n int64
 	db.Conn().Model(&models.AdminUser{}).Where("username = ?", username).Count(&n)
@@ -94,12 +98,12 @@ func AdminUserCreate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "hash error", 500)
 		return
 	}
-	u := models.AdminUser{Username: username, PassHash: string(hash), Role: role, Campus: campus, Active: true}
+	u := models.AdminUser{Username: username, PassHash: string(hash), Role: role, CampusID: campusID, Active: true}
 	if err := db.Conn().Create(&u).Error; err != nil {
 		http.Error(w, "db error", 500)
 		return
 	}
-	writeAudit(r, nil, "user.create", "user:"+username, "role="+role+" campus="+campus)
+	writeAudit(r, nil, "user.create", "user:"+username, "role="+role+" campus="+campusLabel(campusID))
 	http.Redirect(w, r, "/admin/users?ok=akun+"+username+"+dibuat", http.StatusSeeOther)
 }
 
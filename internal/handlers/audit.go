@@ -6,6 +6,7 @@ import (
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 // writeAudit records a mutating action. It never fails the request: an audit
@@ -30,3 +31,15 @@ func writeAudit(r *http.Request, u *models.AdminUser, action, target, detail str
 		log.Printf("audit write failed (%s %s): %v", action, target, err)
 	}
 }
+
+// staffActor is who goes into a registration's history for an admin or
+// check-in action: the same name the audit log blames.
+func staffActor(r *http.Request) svc.Actor {
+	return svc.Actor{Name: actorLabel(r), Source: models.SourceWeb}
+}
+
+// parentActor is the history actor for a change a parent made on the website.
+func parentActor(r *http.Request) svc.Actor {
+	phone, _ := readParentCookies(r)
+	return svc.ParentActor(svc.NormPhone(phone), models.SourceWeb)
+}
@@ -1,30 +1,66 @@
 package handlers
 
-import "strings"
+import (
+	"net/http"
+	"strconv"
+	"strings"
 
-// CampusOf derives the campus code from a class name.
-//
-// Class names are consistently prefixed with the campus:
-//
-//	"FJB Little Stars (Feast Jakarta Barat)"  -> "FJB"
-//	"FJB - Stars Club (Feast Jakarta Barat)"  -> "FJB"
-//	"FJU Awesome Kids (Feast Jakarta Utara)"  -> "FJU"
-//
-// Taking the first whitespace-separated token survives the "FJB - ..." variant.
-// If classes ever gain a real campus column, this is the single place to change.
-func CampusOf(className string) string {
-	f := strings.Fields(strings.TrimSpace(className))
-	if len(f) == 0 {
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// campusAllows reports whether an account scoped to userCampus may act on a
+// class in classCampus. A nil userCampus means "all campuses"; a class with no
+// campus is only reachable by unscoped accounts.
+func campusAllows(userCampus, classCampus *uint) bool {
+	if userCampus == nil {
+		return true
+	}
+	return classCampus != nil && *classCampus == *userCampus
+}
+
+// allCampuses lists campuses for dropdowns, ordered by code.
+func allCampuses() []models.Campus {
+	var cs []models.Campus
+	_ = db.Conn().Order("code asc").Find(&cs).Error
+	return cs
+}
+
+// campusLabel resolves a campus ID to its code for display. Nil or unknown
+// IDs come back as "".
+func campusLabel(id *uint) string {
+	if id == nil {
 		return ""
 	}
-	return strings.ToUpper(f[0])
+	var c models.Campus
+	if err := db.Conn().First(&c, *id).Error; err != nil {
+		return ""
+	}
+	return c.Code
 }
 
-// campusAllows reports whether an account scoped to userCampus may act on a
-// class named className. An empty userCampus means "all campuses".
-func campusAllows(userCampus, className string) bool {
-	if strings.TrimSpace(userCampus) == "" {
-		return true
+// classCampusFromForm reads the campus for a class or series being saved
+// from the campus_id field. The admin must pick one: a class name says
+// nothing reliable about where it meets, so ok is false when the field is
+// blank or names no campus.
+func classCampusFromForm(r *http.Request) (id *uint, ok bool) {
+	id = parseCampusID(r.FormValue("campus_id"))
+	if id == nil {
+		return nil, false
+	}
+	var n int64
+	if err := db.Conn().Model(&models.Campus{}).Where("id = ?", *id).Count(&n).Error; err != nil || n == 0 {
+		return nil, false
+	}
+	return id, true
+}
+
+// parseCampusID reads a campus_id form/query value. "" and "0" mean none.
+func parseCampusID(s string) *uint {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil || n == 0 {
+		return nil
 	}
-	return strings.EqualFold(userCampus, CampusOf(className))
+	id := uint(n)
+	return &id
 }
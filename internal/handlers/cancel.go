@@ -67,7 +67,7 @@ func CancelSubmit(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		if err := svc.CancelByCode(code); err != nil {
+		if err := svc.CancelByCode(code, parentActor(r)); err != nil {
 			http.Error(w, "unable to cancel: "+err.Error(), 500)
 			return
 		}
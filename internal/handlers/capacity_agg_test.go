@@ -1,28 +1,20 @@
 package handlers
 
 import (
-	"path/filepath"
 	"testing"
 	"time"
 
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 
+	"github.com/lojf/nextgen/internal/db/dbtest"
 	"github.com/lojf/nextgen/internal/models"
 )
 
-// openTestDB returns an isolated in-file SQLite database in a temp directory.
+// openTestDB returns an isolated database: SQLite in a temp directory, or a
+// private Postgres schema when TEST_DATABASE_URL is set.
 func openTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
-	dir := t.TempDir()
-	dsn := filepath.Join(dir, "test.db")
-	gdb, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("open test db: %v", err)
-	}
+	gdb := dbtest.Open(t)
 	if err := gdb.AutoMigrate(
 		&models.Parent{},
 		&models.Child{},
@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
 type checkinRow struct {
+	RegID     uint
 	Code      string
 	Status    string
 	ChildName string
@@ -18,6 +22,9 @@ type checkinRow struct {
 	ClassDate time.Time
 	CheckInAt *time.Time
 	DateStr   string
+	// Labels says there is a tag and stub to print, once checked in. The
+	// security code on them is not shown: checkout asks the parent for it.
+	Labels bool
 }
 
 type checkinVM struct {
@@ -25,6 +32,7 @@ type checkinVM struct {
 	Code  string
 	Reg   *checkinRow
 	Flash *Flash
+	Print []uint // registrations whose labels the browser prints (printQuery)
 }
 
 func CheckinForm(t *template.Template) http.HandlerFunc {
@@ -33,30 +41,63 @@ func CheckinForm(t *template.Template) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		code := strings.TrimSpace(r.URL.Query().Get("code"))
+		errMsg := ""
+		// A scanned QR carries a signed token; nothing about the child is
+		// shown until it verifies. Staff may still type a plain code read
+		// off the parent's screen, but that only finds what the station
+		// search would: a confirmed registration in today's class at the
+		// account's campus.
+		signed := false
+		if tok := qrToken(r); tok != "" {
+			claim, err := svc.ResolveQR(tok)
+			if err != nil {
+				code = ""
+				errMsg = "This QR code is not valid. Ask the parent to open it again from My Registrations."
+			} else {
+				code = claim.Code
+				signed = true
+			}
+		}
+		if svc.IsFamilyCode(code) {
+			http.Redirect(w, r, "/station/family?code="+url.QueryEscape(code), http.StatusSeeOther)
+			return
+		}
 
 		var row *checkinRow
-		errMsg := ""
 
 		if code != "" {
 			var reg models.Registration
-			if err := db.Conn().Where("code = ?", code).First(&reg).Error; err == nil && reg.ID != 0 {
+			var class models.Class
+			found := db.Conn().Where("code = ?", code).First(&reg).Error == nil && reg.ID != 0 &&
+				db.Conn().Preload("Campus").First(&class, reg.ClassID).Error == nil
+			if found && !signed && svc.StatusOf(reg) != svc.StatusConfirmed {
+				found = false
+			}
+			if !found {
+				errMsg = errText["code_not_found"]
+			} else if err := guardCheckin(CurrentUser(r), class); err != nil {
+				errMsg = errText[checkinDenied(err)]
+			} else {
 				var child models.Child
 				_ = db.Conn().First(&child, reg.ChildID).Error
-				var class models.Class
-				_ = db.Conn().First(&class, reg.ClassID).Error
 
 				rr := checkinRow{
+					RegID:     reg.ID,
 					Code:      reg.Code,
 					Status:    reg.Status,
 					ChildName: child.Name,
 					ClassName: class.Name,
 					ClassDate: class.Date,
 					CheckInAt: reg.CheckInAt,
+					Labels:    reg.SecurityCode != "",
+				}
+				loc := rosterLoc
+				if class.Campus != nil {
+					loc = class.Campus.Location()
 				}
-				loc, _ := time.LoadLocation("Asia/Jakarta")
 				rr.DateStr = rr.ClassDate.In(loc).Format("Mon, 02 Jan 2006 15:04")
 
-				if rr.Status != "confirmed" {
+				if svc.StatusOf(reg) != svc.StatusConfirmed {
 					errMsg = "Only CONFIRMED registrations can be checked in."
 				}
 				row = &rr
@@ -68,6 +109,7 @@ func CheckinForm(t *template.Template) http.HandlerFunc {
 			Code:  code,
 			Reg:   row,
 			Flash: MakeFlash(r, errMsg, ""),
+			Print: printIDs(r),
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -90,38 +132,42 @@ func CheckinConfirm(t *template.Template) http.HandlerFunc {
 			http.Redirect(w, r, "/checkin?error=code_not_found", http.StatusSeeOther)
 			return
 		}
-		// Business rules for eligibility
-		if reg.Status != "confirmed" {
-			http.Redirect(w, r, "/checkin?error=invalid_checkin&code="+code, http.StatusSeeOther)
-			return
-		}
-		if reg.CheckInAt != nil {
-			http.Redirect(w, r, "/checkin?error=already_checkedin&code="+code, http.StatusSeeOther)
-			return
-		}
 		// Same fence as the roster button: check-in accounts may only mark
 		// attendance for today's classes at their own campus.
 		var class models.Class
-		if err := db.Conn().First(&class, reg.ClassID).Error; err != nil {
+		if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
 			http.Redirect(w, r, "/checkin?error=invalid_checkin&code="+code, http.StatusSeeOther)
 			return
 		}
-		if err := guardCheckin(CurrentUser(r), class); err != nil {
+		if err := guardCheckinAt(CurrentUser(r), class, time.Now()); err != nil {
 			writeAudit(r, nil, "registration.checkin.denied", regTarget(reg), err.Error())
-			http.Redirect(w, r, "/checkin?error=not_allowed&code="+code, http.StatusSeeOther)
+			http.Redirect(w, r, "/checkin?error="+checkinDenied(err)+"&code="+code, http.StatusSeeOther)
 			return
 		}
 
-		now := time.Now()
-		reg.CheckInAt = &now
-		reg.CheckedInBy = actorLabel(r)
-		if err := db.Conn().Save(&reg).Error; err != nil {
+		// Business rules for eligibility live in the registration state
+		// machine: confirmed and not yet checked in.
+		before := roomBefore(class)
+		reg, err := svc.CheckIn(reg.ID, staffActor(r))
+		if errors.Is(err, svc.ErrAlreadyCheckedIn) {
+			http.Redirect(w, r, "/checkin?error=already_checkedin&code="+code, http.StatusSeeOther)
+			return
+		}
+		if err != nil {
 			http.Redirect(w, r, "/checkin?error=invalid_checkin&code="+code, http.StatusSeeOther)
 			return
 		}
 		writeAudit(r, nil, "registration.checkin", regTarget(reg), "class:"+class.Name)
+		var browser []uint
+		if !printCheckinLabels(reg) {
+			browser = append(browser, reg.ID)
+		}
+		if ratioAlert(r, class, before) {
+			http.Redirect(w, r, "/checkin?warn=ratio_over&code="+code+printQuery(browser), http.StatusSeeOther)
+			return
+		}
 
 		// success → back to GET so the page can show details + green flash
-		http.Redirect(w, r, "/checkin?ok=checked_in&code="+code, http.StatusSeeOther)
+		http.Redirect(w, r, "/checkin?ok=checked_in&code="+code+printQuery(browser), http.StatusSeeOther)
 	}
 }
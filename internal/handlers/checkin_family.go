@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+type familyKid struct {
+	RegID     uint
+	ChildName string
+	ClassName string
+	CheckInAt *time.Time
+	TimeStr   string
+	Labels    bool   // has a tag and stub to print; the code itself stays on them
+	Denied    string // why this account may not check the child in, if it may not
+}
+
+type familyVM struct {
+	Title     string
+	StaffName string
+	Code      string
+	Family    string
+	Kids      []familyKid
+	Flash     *Flash
+	Print     []uint // registrations whose labels the browser prints (printQuery)
+}
+
+// familyToday is the day, at loc, a family code must be issued for to be
+// accepted at the station.
+func familyToday(loc *time.Location) string {
+	return time.Now().In(loc).Format("2006-01-02")
+}
+
+// loadFamily resolves a family code to today's confirmed registrations of its
+// household, each with its class, and says which this account may check in.
+// "Today" is the day at the account's campus.
+func loadFamily(r *http.Request, code string) (models.FamilyCode, []familyKid, map[uint]models.Class, error) {
+	u := CurrentUser(r)
+	loc := accountLoc(u)
+	fc, err := svc.FamilyByCode(code, familyToday(loc))
+	if err != nil {
+		return fc, nil, nil, err
+	}
+	start, end := todayWindowIn(loc)
+	var regs []models.Registration
+	if err := db.Conn().
+		Joins("JOIN classes ON classes.id = registrations.class_id AND classes.deleted_at IS NULL").
+		Where("registrations.household_id = ? AND registrations.status = ?", fc.HouseholdID, svc.StatusConfirmed).
+		Where("classes.date BETWEEN ? AND ?", start, end).
+		Order("registrations.id ASC").
+		Find(&regs).Error; err != nil {
+		return fc, nil, nil, err
+	}
+
+	classes := map[uint]models.Class{}
+	kids := make([]familyKid, 0, len(regs))
+	for _, reg := range regs {
+		class, ok := classes[reg.ClassID]
+		if !ok {
+			if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+				return fc, nil, nil, err
+			}
+			classes[class.ID] = class
+		}
+		var child models.Child
+		_ = db.Conn().First(&child, reg.ChildID).Error
+		k := familyKid{
+			RegID:     reg.ID,
+			ChildName: child.Name,
+			ClassName: class.Name,
+			CheckInAt: reg.CheckInAt,
+			Labels:    reg.SecurityCode != "",
+		}
+		if reg.CheckInAt != nil {
+			k.TimeStr = reg.CheckInAt.In(campusLoc(class.CampusID)).Format("15:04")
+		}
+		if err := guardCheckinAt(u, class, time.Now()); err != nil {
+			k.Denied = err.Error()
+		}
+		kids = append(kids, k)
+	}
+	return fc, kids, classes, nil
+}
+
+// GET /station/family?code=FAM-… — every sibling registered today, ready to
+// check in together. Reached by scanning a family QR at /checkin.
+func FamilyCheckinForm(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/family_checkin.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get("code")
+		fc, kids, _, err := loadFamily(r, code)
+		if errors.Is(err, svc.ErrFamilyCode) {
+			http.Redirect(w, r, "/station?error=family_code", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		var parent models.Parent
+		_ = db.Conn().First(&parent, fc.ParentID).Error
+		if err := view.ExecuteTemplate(w, "admin/family_checkin.tmpl", familyVM{
+			Title:     "Family check-in",
+			StaffName: StaffName(r),
+			Code:      fc.Code,
+			Family:    parent.Name,
+			Kids:      kids,
+			Flash:     MakeFlash(r, "", ""),
+			Print:     printIDs(r),
+		}); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// POST /station/family — check in the ticked siblings, one by one: each
+// passes guardCheckin and gets its own audit entry.
+func FamilyCheckinSubmit(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	code := r.FormValue("code")
+	fc, kids, classes, err := loadFamily(r, code)
+	if errors.Is(err, svc.ErrFamilyCode) {
+		http.Redirect(w, r, "/station?error=family_code", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	back := "/station/family?code=" + url.QueryEscape(fc.Code)
+
+	picked := map[uint]bool{}
+	for _, s := range r.Form["reg_id"] {
+		if id, err := strconv.Atoi(s); err == nil {
+			picked[uint(id)] = true
+		}
+	}
+	if len(picked) == 0 {
+		http.Redirect(w, r, back+"&error=family_none", http.StatusSeeOther)
+		return
+	}
+
+	u := CurrentUser(r)
+	failed := 0
+	rooms := map[uint]bool{}
+	var browser []uint // labels for the station to print itself
+	before := map[uint]svc.Ratio{}
+	for id, class := range classes {
+		before[id] = roomBefore(class)
+	}
+	for _, k := range kids {
+		if !picked[k.RegID] || k.CheckInAt != nil {
+			continue
+		}
+		var reg models.Registration
+		if err := db.Conn().First(&reg, k.RegID).Error; err != nil {
+			failed++
+			continue
+		}
+		class := classes[reg.ClassID]
+		if err := guardCheckinAt(u, class, time.Now()); err != nil {
+			writeAudit(r, nil, "registration.checkin.denied", regTarget(reg), err.Error())
+			failed++
+			continue
+		}
+		reg, err := svc.CheckIn(reg.ID, staffActor(r))
+		if err != nil {
+			failed++
+			continue
+		}
+		writeAudit(r, nil, "registration.checkin", regTarget(reg), "class:"+class.Name+"; family "+fc.Code)
+		if !printCheckinLabels(reg) {
+			browser = append(browser, reg.ID)
+		}
+		rooms[class.ID] = true
+	}
+	over := false
+	for id := range rooms {
+		if ratioAlert(r, classes[id], before[id]) {
+			over = true
+		}
+	}
+	if failed > 0 {
+		http.Redirect(w, r, back+"&error=family_partial"+printQuery(browser), http.StatusSeeOther)
+		return
+	}
+	if over {
+		http.Redirect(w, r, "/station?warn=ratio_over"+printQuery(browser), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/station?ok=family_checked_in"+printQuery(browser), http.StatusSeeOther)
+}
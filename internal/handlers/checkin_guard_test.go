@@ -1,17 +1,17 @@
 package handlers
 
 import (
-	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/lojf/nextgen/internal/config"
 	"github.com/lojf/nextgen/internal/models"
 )
 
 func init() {
 	// Pin the signing key so token tests never touch the database.
-	os.Setenv("SESSION_SECRET", "test-secret-do-not-use-in-production")
+	conf.SessionSecret = "test-secret-do-not-use-in-production"
 }
 
 // jakartaMidnight builds a class date the way the app stores them.
@@ -20,27 +20,18 @@ func jakartaMidnight(offsetDays int) time.Time {
 	return time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, rosterLoc)
 }
 
-func TestCampusOf(t *testing.T) {
-	cases := map[string]string{
-		"FJB Little Stars (Feast Jakarta Barat)": "FJB",
-		"FJB - Stars Club (Feast Jakarta Barat)": "FJB", // the stray dashed variant
-		"FJU Awesome Kids (Feast Jakarta Utara)": "FJU",
-		"  FJU Stars Club":                       "FJU",
-		"":                                       "",
-	}
-	for name, want := range cases {
-		if got := CampusOf(name); got != want {
-			t.Errorf("CampusOf(%q) = %q, want %q", name, got, want)
-		}
-	}
-}
+// Campus IDs as the backfill would assign them on a fresh database.
+var (
+	campusFJB = uint(1)
+	campusFJU = uint(2)
+)
 
 // TestGuardCheckinRejectsEarlyCheckin replicates the 2026-08-07 incident:
 // registrations 3123/3124 belonged to a class dated 2026-08-08 but were checked
 // in the day before. Under the check-in role that must now fail.
 func TestGuardCheckinRejectsEarlyCheckin(t *testing.T) {
-	vol := &models.AdminUser{Role: models.RoleCheckin, Campus: "FJB"}
-	tomorrow := models.Class{Name: "FJB Awesome Kids (Feast Jakarta Barat)", Date: jakartaMidnight(1)}
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	tomorrow := models.Class{CampusID: &campusFJB, Name: "FJB Awesome Kids (Feast Jakarta Barat)", Date: jakartaMidnight(1)}
 
 	if err := guardCheckin(vol, tomorrow); err != ErrCheckinNotToday {
 		t.Fatalf("check-in a day early: got %v, want ErrCheckinNotToday", err)
@@ -48,8 +39,8 @@ func TestGuardCheckinRejectsEarlyCheckin(t *testing.T) {
 }
 
 func TestGuardCheckinRejectsPastClass(t *testing.T) {
-	vol := &models.AdminUser{Role: models.RoleCheckin, Campus: "FJB"}
-	lastWeek := models.Class{Name: "FJB Awesome Kids (Feast Jakarta Barat)", Date: jakartaMidnight(-7)}
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	lastWeek := models.Class{CampusID: &campusFJB, Name: "FJB Awesome Kids (Feast Jakarta Barat)", Date: jakartaMidnight(-7)}
 
 	if err := guardCheckin(vol, lastWeek); err != ErrCheckinNotToday {
 		t.Fatalf("check-in for a past class: got %v, want ErrCheckinNotToday", err)
@@ -57,8 +48,8 @@ func TestGuardCheckinRejectsPastClass(t *testing.T) {
 }
 
 func TestGuardCheckinRejectsOtherCampus(t *testing.T) {
-	vol := &models.AdminUser{Role: models.RoleCheckin, Campus: "FJB"}
-	other := models.Class{Name: "FJU Awesome Kids (Feast Jakarta Utara)", Date: jakartaMidnight(0)}
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	other := models.Class{CampusID: &campusFJU, Name: "FJU Awesome Kids (Feast Jakarta Utara)", Date: jakartaMidnight(0)}
 
 	if err := guardCheckin(vol, other); err != ErrCheckinWrongCampus {
 		t.Fatalf("cross-campus check-in: got %v, want ErrCheckinWrongCampus", err)
@@ -66,18 +57,39 @@ func TestGuardCheckinRejectsOtherCampus(t *testing.T) {
 }
 
 func TestGuardCheckinAllowsTodaySameCampus(t *testing.T) {
-	vol := &models.AdminUser{Role: models.RoleCheckin, Campus: "FJB"}
-	today := models.Class{Name: "FJB Little Stars (Feast Jakarta Barat)", Date: jakartaMidnight(0)}
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	today := models.Class{CampusID: &campusFJB, Name: "FJB Little Stars (Feast Jakarta Barat)", Date: jakartaMidnight(0)}
 
 	if err := guardCheckin(vol, today); err != nil {
 		t.Fatalf("today at own campus should be allowed, got %v", err)
 	}
 }
 
+// The campus comes from the class's campus_id, not its name: a class whose
+// name was typed with the wrong prefix stays where the admin put it.
+func TestGuardCheckinUsesCampusIDNotName(t *testing.T) {
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	mislabelled := models.Class{CampusID: &campusFJU, Name: "FJB Awesome Kids", Date: jakartaMidnight(0)}
+
+	if err := guardCheckin(vol, mislabelled); err != ErrCheckinWrongCampus {
+		t.Fatalf("FJB-named class at FJU: got %v, want ErrCheckinWrongCampus", err)
+	}
+}
+
+// A class nobody has assigned to a campus is off limits to scoped accounts.
+func TestGuardCheckinRejectsUnassignedClassForScopedAccount(t *testing.T) {
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	orphan := models.Class{Name: "FJB Awesome Kids", Date: jakartaMidnight(0)}
+
+	if err := guardCheckin(vol, orphan); err != ErrCheckinWrongCampus {
+		t.Fatalf("class without campus: got %v, want ErrCheckinWrongCampus", err)
+	}
+}
+
 // Admins keep the ability to fix records after the fact.
 func TestGuardCheckinAdminBypassesBothFences(t *testing.T) {
 	adm := &models.AdminUser{Role: models.RoleAdmin}
-	future := models.Class{Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(30)}
+	future := models.Class{CampusID: &campusFJU, Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(30)}
 
 	if err := guardCheckin(adm, future); err != nil {
 		t.Fatalf("admin should bypass the fences, got %v", err)
@@ -87,18 +99,98 @@ func TestGuardCheckinAdminBypassesBothFences(t *testing.T) {
 // A check-in account with no campus set is allowed anywhere, but is still
 // fenced to today.
 func TestGuardCheckinEmptyCampusStillFencedToToday(t *testing.T) {
-	vol := &models.AdminUser{Role: models.RoleCheckin, Campus: ""}
+	vol := &models.AdminUser{Role: models.RoleCheckin}
 
-	today := models.Class{Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(0)}
+	today := models.Class{CampusID: &campusFJU, Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(0)}
 	if err := guardCheckin(vol, today); err != nil {
 		t.Fatalf("empty campus should allow any campus today, got %v", err)
 	}
-	tomorrow := models.Class{Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(1)}
+	tomorrow := models.Class{CampusID: &campusFJU, Name: "FJU Stars Club (Feast Jakarta Utara)", Date: jakartaMidnight(1)}
 	if err := guardCheckin(vol, tomorrow); err != ErrCheckinNotToday {
 		t.Fatalf("empty campus must still be fenced to today, got %v", err)
 	}
 }
 
+// A timed class takes check-ins only around its start, so a 9:00 scan does
+// not land in the 16:00 session. Untimed classes stay open all day.
+func TestGuardCheckinAtWindow(t *testing.T) {
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	adm := &models.AdminUser{Role: models.RoleAdmin}
+	day := jakartaMidnight(0)
+	start := day.Add(16 * time.Hour)
+	class := models.Class{CampusID: &campusFJB, Name: "FJB Little Stars (Feast Jakarta Barat)", Date: day, StartsAt: &start}
+
+	cases := []struct {
+		at   time.Time
+		want error
+	}{
+		{day.Add(9 * time.Hour), ErrCheckinWindow},
+		{start.Add(-conf.CheckinOpensBefore), nil},
+		{start.Add(30 * time.Minute), nil},
+		{start.Add(conf.CheckinClosesAfter + time.Minute), ErrCheckinWindow},
+	}
+	for _, c := range cases {
+		if err := guardCheckinAt(vol, class, c.at); err != c.want {
+			t.Errorf("check-in at %s: got %v, want %v", c.at.In(rosterLoc).Format("15:04"), err, c.want)
+		}
+	}
+	if err := guardCheckinAt(adm, class, day.Add(9*time.Hour)); err != nil {
+		t.Errorf("admin should bypass the window, got %v", err)
+	}
+	untimed := models.Class{CampusID: &campusFJB, Name: class.Name, Date: day}
+	if err := guardCheckinAt(vol, untimed, day.Add(9*time.Hour)); err != nil {
+		t.Errorf("untimed class should be open all day, got %v", err)
+	}
+	if got := checkinDenied(ErrCheckinWindow); got != "checkin_window" {
+		t.Errorf("flash key = %q", got)
+	}
+}
+
+// The wrong sibling was scanned: the same shift may take it back shortly
+// after, nobody else may, and an admin always can.
+func TestGuardUndoCheckin(t *testing.T) {
+	vol := &models.AdminUser{Role: models.RoleCheckin, CampusID: &campusFJB}
+	adm := &models.AdminUser{Role: models.RoleAdmin}
+	today := models.Class{CampusID: &campusFJB, Name: "FJB Little Stars (Feast Jakarta Barat)", Date: jakartaMidnight(0)}
+	now := time.Now()
+	at := now.Add(-2 * time.Minute)
+	reg := models.Registration{CheckInAt: &at, CheckedInBy: "Rina"}
+
+	if err := guardUndoCheckin(vol, today, reg, "Rina", now); err != nil {
+		t.Errorf("same shift within window: got %v", err)
+	}
+	if err := guardUndoCheckin(vol, today, reg, "Budi", now); err != ErrUndoOtherShift {
+		t.Errorf("other shift: got %v, want ErrUndoOtherShift", err)
+	}
+	late := now.Add(checkinUndoWindow() + time.Minute)
+	if err := guardUndoCheckin(vol, today, reg, "Rina", late); err != ErrUndoWindowClosed {
+		t.Errorf("after the window: got %v, want ErrUndoWindowClosed", err)
+	}
+	yesterday := models.Class{CampusID: &campusFJB, Name: today.Name, Date: jakartaMidnight(-1)}
+	if err := guardUndoCheckin(vol, yesterday, reg, "Rina", now); err != ErrCheckinNotToday {
+		t.Errorf("yesterday's class: got %v, want ErrCheckinNotToday", err)
+	}
+	if err := guardUndoCheckin(adm, yesterday, reg, "admin", late); err != nil {
+		t.Errorf("admin should bypass, got %v", err)
+	}
+}
+
+// TestCheckinUndoWindowFromConfig: CHECKIN_UNDO_WINDOW reaches the guard
+// through the config; config rejects bad values at startup.
+func TestCheckinUndoWindowFromConfig(t *testing.T) {
+	if got := checkinUndoWindow(); got != 10*time.Minute {
+		t.Errorf("default window = %v, want 10m", got)
+	}
+	prev := conf
+	defer Configure(prev)
+	c := config.Default()
+	c.CheckinUndoWindow = 3 * time.Minute
+	Configure(c)
+	if got := checkinUndoWindow(); got != 3*time.Minute {
+		t.Errorf("window = %v, want 3m", got)
+	}
+}
+
 func TestSessionTokenRoundTrip(t *testing.T) {
 	tok := signToken(42, time.Now().Add(time.Hour))
 	id, ok := parseToken(tok)
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/labels"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// checkinLabels builds the child tag and parent stub for a checked-in
+// registration.
+func checkinLabels(reg models.Registration) ([]labels.Label, error) {
+	var child models.Child
+	if err := db.Conn().First(&child, reg.ChildID).Error; err != nil {
+		return nil, err
+	}
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		return nil, err
+	}
+	l := labels.Label{
+		ChildName:    child.Name,
+		ClassName:    class.Name,
+		DateStr:      class.Date.In(campusLoc(class.CampusID)).Format("02 Jan 2006"),
+		SecurityCode: reg.SecurityCode,
+	}
+	if child.Allergies != "" {
+		l.Flags = append(l.Flags, "ALERGI: "+child.Allergies)
+	}
+	if child.MedicalNotes != "" {
+		l.Flags = append(l.Flags, "MEDIS: "+child.MedicalNotes)
+	}
+	return labels.Pair(l), nil
+}
+
+// campusPrinter is the LAN label printer set on the class's campus, or nil
+// when it has none. A setting that no longer parses counts as none.
+func campusPrinter(class models.Class) *labels.Printer {
+	if class.Campus == nil {
+		return nil
+	}
+	p, err := labels.ParsePrinter(class.Campus.LabelPrinter)
+	if err != nil {
+		log.Printf("labels: campus %s: %v", class.Campus.Code, err)
+		return nil
+	}
+	return p
+}
+
+// printCheckinLabels sends the labels to the class's campus printer and
+// reports whether the printer took them. Sending waits at most a couple of
+// seconds (see labels.Printer.Print). When the campus has no printer, or it
+// did not answer, the caller passes the registration on with printQuery and
+// the station prints in the browser.
+func printCheckinLabels(reg models.Registration) bool {
+	if reg.SecurityCode == "" {
+		return false
+	}
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		log.Printf("labels: registration %d: %v", reg.ID, err)
+		return false
+	}
+	p := campusPrinter(class)
+	if p == nil {
+		return false
+	}
+	ls, err := checkinLabels(reg)
+	if err != nil {
+		log.Printf("labels: registration %d: %v", reg.ID, err)
+		return false
+	}
+	if err := p.Print(ls); err != nil {
+		log.Printf("labels: print %s to %s: %v", reg.Code, p.Addr, err)
+		return false
+	}
+	return true
+}
+
+// printQuery is the "&print=" part of a redirect after check-in: the
+// registrations whose labels no LAN printer took, for the next page to print
+// in the browser. Blank when there are none.
+func printQuery(regIDs []uint) string {
+	var b strings.Builder
+	for _, id := range regIDs {
+		b.WriteString("&print=" + strconv.FormatUint(uint64(id), 10))
+	}
+	return b.String()
+}
+
+// withPrint is ref with its "print" parameters set to regIDs, for going back
+// to a page that is not a station (the roster) with labels still to print.
+func withPrint(ref string, regIDs []uint) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	q := u.Query()
+	q.Del("print")
+	for _, id := range regIDs {
+		q.Add("print", strconv.FormatUint(uint64(id), 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// printIDs reads printQuery back on the page it led to.
+func printIDs(r *http.Request) []uint {
+	var ids []uint
+	for _, s := range r.URL.Query()["print"] {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// GET /station/labels/{id}.{format} — pdf for the browser, zpl or escpos for
+// a printer the station drives itself.
+func CheckinLabels(w http.ResponseWriter, r *http.Request) {
+	var reg models.Registration
+	if err := db.Conn().First(&reg, chi.URLParam(r, "id")).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := guardCheckin(CurrentUser(r), class); err != nil {
+		http.Error(w, "not allowed", http.StatusForbidden)
+		return
+	}
+	if reg.CheckInAt == nil || reg.SecurityCode == "" {
+		http.Error(w, "not checked in", http.StatusConflict)
+		return
+	}
+	ls, err := checkinLabels(reg)
+	if err != nil {
+		http.Error(w, "db error", 500)
+		return
+	}
+
+	var buf bytes.Buffer
+	switch chi.URLParam(r, "format") {
+	case "pdf":
+		err = labels.PDF(&buf, ls)
+		w.Header().Set("Content-Type", "application/pdf")
+	case "zpl":
+		err = labels.ZPL(&buf, ls)
+		w.Header().Set("Content-Type", "application/vnd.zebra-zpl")
+	case "escpos":
+		err = labels.ESCPOS(&buf, ls)
+		w.Header().Set("Content-Type", "application/octet-stream")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Disposition", "inline; filename="+template.URLQueryEscaper(reg.Code)+"."+chi.URLParam(r, "format"))
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(buf.Bytes())
+}
@@ -7,27 +7,88 @@ import (
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
-// todayWindow returns the [start, end] of the current Jakarta day in UTC.
-// Class dates are stored at Jakarta midnight, so a naive UTC comparison drops
-// same-day classes — the bug fixed in dbfd111 for the roster.
-func todayWindow() (time.Time, time.Time) {
-	nowJ := time.Now().In(rosterLoc)
-	start := time.Date(nowJ.Year(), nowJ.Month(), nowJ.Day(), 0, 0, 0, 0, rosterLoc)
-	end := time.Date(nowJ.Year(), nowJ.Month(), nowJ.Day(), 23, 59, 59, 0, rosterLoc)
+// todayWindowIn returns the [start, end] of the current day at loc, a campus
+// time zone, in UTC. Class dates are stored at campus midnight, so a naive
+// UTC comparison drops same-day classes — the bug fixed in dbfd111 for the
+// roster.
+func todayWindowIn(loc *time.Location) (time.Time, time.Time) {
+	nowL := time.Now().In(loc)
+	start := time.Date(nowL.Year(), nowL.Month(), nowL.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(nowL.Year(), nowL.Month(), nowL.Day(), 23, 59, 59, 0, loc)
 	return start.UTC(), end.UTC()
 }
 
+// accountLoc is the time zone of u's campus: Jakarta for an account with
+// none, or nobody signed in.
+func accountLoc(u *models.AdminUser) *time.Location {
+	if u == nil {
+		return rosterLoc
+	}
+	return campusLoc(u.CampusID)
+}
+
+// campusLoc returns the time zone of a campus, or Jakarta when unknown.
+func campusLoc(id *uint) *time.Location {
+	if id == nil {
+		return rosterLoc
+	}
+	var c models.Campus
+	if err := db.Conn().First(&c, *id).Error; err != nil {
+		return rosterLoc
+	}
+	return c.Location()
+}
+
 // ErrCheckinNotToday / ErrCheckinWrongCampus are the two guards that stop a
 // check-in volunteer from marking attendance outside their shift.
 var (
 	ErrCheckinNotToday    = errors.New("kelas ini bukan hari ini")
 	ErrCheckinWrongCampus = errors.New("kelas ini bukan campus akun ini")
+	ErrCheckinWindow      = errors.New("di luar jam check-in kelas ini")
+)
+
+// ErrUndoWindowClosed / ErrUndoOtherShift stop a check-in volunteer from
+// taking back someone else's check-in, or their own long after the fact.
+var (
+	ErrUndoWindowClosed = errors.New("batas waktu undo sudah lewat")
+	ErrUndoOtherShift   = errors.New("check-in ini dicatat penjaga lain")
 )
 
+// checkinUndoWindow is how long after a check-in a volunteer may undo it:
+// CHECKIN_UNDO_WINDOW as a Go duration ("10m"), ten minutes by default.
+func checkinUndoWindow() time.Duration {
+	return conf.CheckinUndoWindow
+}
+
+// guardUndoCheckin is guardCheckin for taking a check-in back. On top of the
+// day and campus fence, the check-in role may only undo within the window and
+// only a check-in made under its own shift name. Admins bypass all of it.
+func guardUndoCheckin(u *models.AdminUser, class models.Class, reg models.Registration, shift string, now time.Time) error {
+	if u == nil || u.Role == models.RoleAdmin {
+		return nil
+	}
+	if err := guardCheckin(u, class); err != nil {
+		return err
+	}
+	if reg.CheckInAt == nil {
+		return nil // nothing to undo; the state machine says so
+	}
+	if now.Sub(*reg.CheckInAt) > checkinUndoWindow() {
+		return ErrUndoWindowClosed
+	}
+	if reg.CheckedInBy != shift {
+		return ErrUndoOtherShift
+	}
+	return nil
+}
+
 // guardCheckin enforces the check-in role's boundaries. Admins bypass both
 // checks so they can still fix records after the fact.
 //
@@ -37,10 +98,14 @@ func guardCheckin(u *models.AdminUser, class models.Class) error {
 	if u == nil || u.Role == models.RoleAdmin {
 		return nil
 	}
-	if !campusAllows(u.Campus, class.Name) {
+	if !campusAllows(u.CampusID, class.CampusID) {
 		return ErrCheckinWrongCampus
 	}
-	start, end := todayWindow()
+	loc := rosterLoc
+	if class.Campus != nil {
+		loc = class.Campus.Location()
+	}
+	start, end := todayWindowIn(loc)
 	d := class.Date.UTC()
 	if d.Before(start) || d.After(end) {
 		return ErrCheckinNotToday
@@ -48,125 +113,292 @@ func guardCheckin(u *models.AdminUser, class models.Class) error {
 	return nil
 }
 
+// guardCheckinAt is guardCheckin for marking a child in at now. A class with
+// a start time also only takes check-ins from CHECKIN_OPENS_BEFORE it until
+// CHECKIN_CLOSES_AFTER it, so the 9:00 and 16:00 sessions of one day do not
+// mix. Checkout, labels and undo keep the day-wide guardCheckin.
+func guardCheckinAt(u *models.AdminUser, class models.Class, now time.Time) error {
+	if err := guardCheckin(u, class); err != nil {
+		return err
+	}
+	if u == nil || u.Role == models.RoleAdmin || class.StartsAt == nil {
+		return nil
+	}
+	if now.Before(class.StartsAt.Add(-conf.CheckinOpensBefore)) ||
+		now.After(class.StartsAt.Add(conf.CheckinClosesAfter)) {
+		return ErrCheckinWindow
+	}
+	return nil
+}
+
+// checkinDenied is the flash key for a guardCheckinAt refusal.
+func checkinDenied(err error) string {
+	if errors.Is(err, ErrCheckinWindow) {
+		return "checkin_window"
+	}
+	return "not_allowed"
+}
+
+// stationKid and stationClass double as the offline snapshot's JSON.
 type stationKid struct {
-	RegID       uint
-	Code        string
-	ChildName   string
-	CheckInAt   *time.Time
-	CheckedInBy string
-	TimeStr     string
+	RegID       uint       `json:"reg_id"`
+	Code        string     `json:"code"`
+	ChildName   string     `json:"child_name"`
+	ClassName   string     `json:"class_name"`
+	CheckInAt   *time.Time `json:"check_in_at,omitempty"`
+	CheckedInBy string     `json:"checked_in_by,omitempty"`
+	TimeStr     string     `json:"-"`
+	CanUndo     bool       `json:"can_undo"` // this shift may still take the check-in back
+	// Labels says the child has a tag and parent stub to print. The code
+	// on them is never shown or sent: checkout asks the parent for it.
+	Labels     bool       `json:"labels,omitempty"`
+	CheckOutAt *time.Time `json:"check_out_at,omitempty"`
+	PickedUpBy string     `json:"picked_up_by,omitempty"`
+	OutStr     string     `json:"-"`
 }
 
 type stationClass struct {
-	ClassID  uint
-	Name     string
-	Total    int
-	Checked  int
-	Kids     []stationKid
+	ClassID uint         `json:"class_id"`
+	Name    string       `json:"name"`
+	Hours   string       `json:"hours,omitempty"` // "09:00–10:30" at the campus
+	Room    string       `json:"room,omitempty"`
+	Total   int          `json:"total"`
+	Checked int          `json:"checked"`
+	Kids    []stationKid `json:"kids"`
+
+	// Ratio is who is in the room against the class's child-to-adult
+	// ratio; Here says this shift is one of the adults.
+	Ratio svc.Ratio `json:"-"`
+	Here  bool      `json:"-"`
 }
 
 type stationVM struct {
 	Title     string
 	StaffName string
 	Campus    string
+	Day       string // YYYY-MM-DD at the campus
 	DateStr   string
 	Classes   []stationClass
+	StillIn   []stationKid // checked in, not picked up yet
+	Query     string       // ?q=, a partial phone or child name
+	Results   []stationKid // what Query matched, today at this campus only
 	Flash     *Flash
 	Username  string
+	Print     []uint // registrations whose labels the browser prints (printQuery)
 }
 
-// GET /station — the volunteer check-in screen.
-func CheckinStation(t *template.Template) http.HandlerFunc {
-	view := template.Must(t.Clone())
-	template.Must(view.ParseFiles("templates/pages/admin/station.tmpl"))
+// loadStation builds today's station for the signed-in account's campus:
+// every confirmed registration by class, and who is still in a room. The
+// page and the offline snapshot are the same data.
+func loadStation(r *http.Request) (stationVM, error) {
+	u := CurrentUser(r)
+	var scope *uint
+	if u != nil {
+		scope = u.CampusID
+	}
+	loc := accountLoc(u)
+	start, end := todayWindowIn(loc)
 
-	return func(w http.ResponseWriter, r *http.Request) {
-		u := CurrentUser(r)
-		staff := StaffName(r)
-
-		start, end := todayWindow()
-
-		type row struct {
-			RegID       uint
-			Code        string
-			Status      string
-			CheckInAt   *time.Time
-			CheckedInBy string
-			ChildName   string
-			ClassID     uint
-			ClassName   string
-		}
-		var rows []row
+	type row struct {
+		RegID       uint
+		Code        string
+		Status      string
+		CheckInAt   *time.Time
+		CheckedInBy string
+		CheckOutAt  *time.Time
+		PickedUpBy  string
+		Security    string
+		ChildName   string
+		ClassID     uint
+		ClassName   string
+		ClassDate   time.Time
+		CampusID    *uint
+		StartsAt    *time.Time
+		EndsAt      *time.Time
+		Room        string
+	}
+	// base is today's confirmed registrations at this campus: everything
+	// the station may show, search results included.
+	base := func() *gorm.DB {
 		q := db.Conn().Table("registrations").
-			Select(`registrations.id AS reg_id,
-			        registrations.code AS code,
-			        registrations.status AS status,
-			        registrations.check_in_at AS check_in_at,
-			        registrations.checked_in_by AS checked_in_by,
-			        children.name AS child_name,
-			        classes.id AS class_id,
-			        classes.name AS class_name`).
 			Joins("JOIN children ON children.id = registrations.child_id").
 			Joins("JOIN classes  ON classes.id  = registrations.class_id").
-			Where("registrations.status = ?", "confirmed").
-			Where("classes.date BETWEEN ? AND ?", start, end).
-			Order("classes.name ASC, children.name ASC")
-		if err := q.Scan(&rows).Error; err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+			Where("registrations.deleted_at IS NULL").
+			Where("registrations.status = ?", svc.StatusConfirmed).
+			Where("classes.date BETWEEN ? AND ?", start, end)
+		if scope != nil {
+			q = q.Where("classes.campus_id = ?", *scope)
 		}
+		return q
+	}
+
+	var rows []row
+	q := base().
+		Select(`registrations.id AS reg_id,
+		        registrations.code AS code,
+		        registrations.status AS status,
+		        registrations.check_in_at AS check_in_at,
+		        registrations.checked_in_by AS checked_in_by,
+		        registrations.check_out_at AS check_out_at,
+		        registrations.picked_up_by AS picked_up_by,
+		        registrations.security_code AS security,
+		        children.name AS child_name,
+		        classes.id AS class_id,
+		        classes.name AS class_name,
+		        classes.date AS class_date,
+		        classes.campus_id AS campus_id,
+		        classes.starts_at AS starts_at,
+		        classes.ends_at AS ends_at,
+		        classes.room AS room`).
+		Order("COALESCE(classes.starts_at, classes.date) ASC, classes.name ASC, children.name ASC")
+	if err := q.Scan(&rows).Error; err != nil {
+		return stationVM{}, err
+	}
 
-		byClass := map[uint]*stationClass{}
-		var order []uint
-		for _, rw := range rows {
-			if u != nil && !campusAllows(u.Campus, rw.ClassName) {
-				continue
+	// Search: a partial phone of any guardian, or part of the child's name.
+	// It only ever narrows base, so the check-in role cannot reach families
+	// outside today's classes at its campus.
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	var matched map[uint]bool
+	if query != "" {
+		sq := base()
+		if frags := svc.PhoneFragments(query); frags != nil {
+			digits := db.Dialect().PhoneDigits("parents.phone")
+			cond := db.Conn().Where(digits+" LIKE ?", "%"+frags[0]+"%")
+			for _, f := range frags[1:] {
+				cond = cond.Or(digits+" LIKE ?", "%"+f+"%")
 			}
-			sc := byClass[rw.ClassID]
-			if sc == nil {
-				sc = &stationClass{ClassID: rw.ClassID, Name: rw.ClassName}
-				byClass[rw.ClassID] = sc
-				order = append(order, rw.ClassID)
+			sq = sq.Where("EXISTS (?)", db.Conn().Table("parents").Select("1").
+				Where("parents.household_id = registrations.household_id AND parents.deleted_at IS NULL").
+				Where(cond))
+		} else {
+			name := strings.NewReplacer("%", "", "_", "").Replace(strings.ToLower(query))
+			sq = sq.Where("LOWER(children.name) LIKE ?", "%"+name+"%")
+		}
+		var ids []uint
+		if err := sq.Pluck("registrations.id", &ids).Error; err != nil {
+			return stationVM{}, err
+		}
+		matched = make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			matched[id] = true
+		}
+	}
+
+	byClass := map[uint]*stationClass{}
+	ends := map[uint]time.Time{} // by class; see models.Class.End
+	var order []uint
+	var stillIn, results []stationKid
+	for _, rw := range rows {
+		sc := byClass[rw.ClassID]
+		if sc == nil {
+			class := models.Class{Date: rw.ClassDate, EndsAt: rw.EndsAt, CampusID: rw.CampusID}
+			if rw.CampusID != nil {
+				var c models.Campus
+				if db.Conn().First(&c, *rw.CampusID).Error == nil {
+					class.Campus = &c
+				}
 			}
-			k := stationKid{
-				RegID:       rw.RegID,
-				Code:        rw.Code,
-				ChildName:   rw.ChildName,
-				CheckInAt:   rw.CheckInAt,
-				CheckedInBy: rw.CheckedInBy,
+			ends[rw.ClassID] = class.End()
+			sc = &stationClass{ClassID: rw.ClassID, Name: rw.ClassName, Room: rw.Room}
+			if rw.StartsAt != nil {
+				sc.Hours = rw.StartsAt.In(loc).Format("15:04")
+				if rw.EndsAt != nil {
+					sc.Hours += "–" + rw.EndsAt.In(loc).Format("15:04")
+				}
 			}
-			if rw.CheckInAt != nil {
-				k.TimeStr = rw.CheckInAt.In(rosterLoc).Format("15:04")
-				sc.Checked++
+			byClass[rw.ClassID] = sc
+			order = append(order, rw.ClassID)
+		}
+		k := stationKid{
+			RegID:       rw.RegID,
+			Code:        rw.Code,
+			ChildName:   rw.ChildName,
+			ClassName:   rw.ClassName,
+			CheckInAt:   rw.CheckInAt,
+			CheckedInBy: rw.CheckedInBy,
+			Labels:      rw.Security != "",
+			CheckOutAt:  rw.CheckOutAt,
+			PickedUpBy:  rw.PickedUpBy,
+		}
+		if rw.CheckInAt != nil {
+			k.TimeStr = rw.CheckInAt.In(loc).Format("15:04")
+			sc.Checked++
+			if rw.CheckOutAt == nil {
+				// The day and campus fence already holds for every
+				// row on this page; only window and shift vary.
+				k.CanUndo = u == nil || u.Role == models.RoleAdmin ||
+					(time.Since(*rw.CheckInAt) <= checkinUndoWindow() && rw.CheckedInBy == actorLabel(r))
 			}
-			sc.Total++
-			sc.Kids = append(sc.Kids, k)
 		}
+		if rw.CheckOutAt != nil {
+			k.OutStr = rw.CheckOutAt.In(loc).Format("15:04")
+		} else if rw.CheckInAt != nil && !time.Now().Before(ends[rw.ClassID]) {
+			// Once a class has ended, anyone checked in and not
+			// collected is still waiting in a room. A class without an
+			// end time runs to the end of its day at its campus.
+			stillIn = append(stillIn, k)
+		}
+		sc.Total++
+		sc.Kids = append(sc.Kids, k)
+		if matched[rw.RegID] {
+			results = append(results, k)
+		}
+	}
 
-		classes := make([]stationClass, 0, len(order))
-		for _, id := range order {
-			classes = append(classes, *byClass[id])
+	ratios, err := svc.RoomRatios(db.Conn(), order)
+	if err != nil {
+		return stationVM{}, err
+	}
+	shift := StaffName(r)
+	classes := make([]stationClass, 0, len(order))
+	for _, id := range order {
+		sc := byClass[id]
+		sc.Ratio = ratios[id]
+		for _, name := range sc.Ratio.Staff {
+			sc.Here = sc.Here || (shift != "" && name == shift)
 		}
+		classes = append(classes, *sc)
+	}
 
-		campus := ""
-		username := ""
-		if u != nil {
-			campus = u.Campus
-			username = u.Username
-			if campus == "" {
-				campus = "Semua campus"
-			}
+	campus := ""
+	username := ""
+	if u != nil {
+		campus = campusLabel(u.CampusID)
+		username = u.Username
+		if campus == "" {
+			campus = "Semua campus"
 		}
+	}
+	now := time.Now().In(loc)
+	return stationVM{
+		Title:     "Check-in",
+		StaffName: StaffName(r),
+		Campus:    campus,
+		Username:  username,
+		Day:       now.Format("2006-01-02"),
+		DateStr:   now.Format("Mon, 02 Jan 2006"),
+		Classes:   classes,
+		StillIn:   stillIn,
+		Query:     query,
+		Results:   results,
+	}, nil
+}
 
-		if err := view.ExecuteTemplate(w, "admin/station.tmpl", stationVM{
-			Title:     "Check-in",
-			StaffName: staff,
-			Campus:    campus,
-			Username:  username,
-			DateStr:   time.Now().In(rosterLoc).Format("Mon, 02 Jan 2006"),
-			Classes:   classes,
-			Flash:     MakeFlash(r, r.URL.Query().Get("error"), r.URL.Query().Get("ok")),
-		}); err != nil {
+// GET /station — the volunteer check-in screen.
+func CheckinStation(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/station.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		vm, err := loadStation(r)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		vm.Flash = MakeFlash(r, r.URL.Query().Get("error"), r.URL.Query().Get("ok"))
+		vm.Print = printIDs(r)
+		if err := view.ExecuteTemplate(w, "admin/station.tmpl", vm); err != nil {
 			http.Error(w, err.Error(), 500)
 		}
 	}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// TestLoadStationSearch checks the lost-QR search: a partial phone (local
+// "08…" form against the stored "+62…") or part of a name finds today's
+// registration at the volunteer's campus, and never one from another campus
+// or another day.
+func TestLoadStationSearch(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	campuses := []models.Campus{{Code: "FJB"}, {Code: "FJU"}}
+	gdb.Create(&campuses)
+	fjb, fju := campuses[0].ID, campuses[1].ID
+	today := time.Now().In(rosterLoc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, rosterLoc)
+	mk := func(name, phone string, campus *uint, day time.Time, code string) {
+		h := models.Household{Name: name}
+		gdb.Create(&h)
+		p := models.Parent{Name: "Ortu " + name, Phone: phone, HouseholdID: h.ID}
+		gdb.Create(&p)
+		kid := models.Child{Name: name, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		cl := models.Class{Name: "Little Stars", Date: day, Capacity: 10, CampusID: campus}
+		gdb.Create(&cl)
+		gdb.Create(&models.Registration{
+			HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: code,
+		})
+	}
+	mk("Budi Santoso", "+6281234567001", &fjb, today, "REG-A")
+	mk("Budi Hartono", "+6281234567002", &fju, today, "REG-B")             // other campus
+	mk("Budiman", "+6281234567003", &fjb, today.AddDate(0, 0, 1), "REG-C") // tomorrow
+
+	volunteer := &models.AdminUser{Username: "fjb-door", Role: models.RoleCheckin, CampusID: &fjb}
+	search := func(q string) []string {
+		r := httptest.NewRequest("GET", "/station?q="+url.QueryEscape(q), nil)
+		r = r.WithContext(context.WithValue(r.Context(), ctxUserKey, volunteer))
+		vm, err := loadStation(r)
+		if err != nil {
+			t.Fatalf("loadStation(%q): %v", q, err)
+		}
+		var codes []string
+		for _, k := range vm.Results {
+			codes = append(codes, k.Code)
+		}
+		return codes
+	}
+
+	for _, q := range []string{"budi", "0812-3456-7001", "567001", "SANTOSO"} {
+		if got := search(q); len(got) != 1 || got[0] != "REG-A" {
+			t.Errorf("search %q = %v, want [REG-A]", q, got)
+		}
+	}
+	for _, q := range []string{"567002", "567003", "Hartono", "812"} {
+		if got := search(q); len(got) != 0 {
+			t.Errorf("search %q = %v, want nothing", q, got)
+		}
+	}
+}
+
+// TestLoadStationStillIn checks that a child only counts as still in a room
+// once the class is over: past its end time, or past the end of its day when
+// it has none.
+func TestLoadStationStillIn(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	campus := models.Campus{Code: "FJB"}
+	gdb.Create(&campus)
+	today := time.Now().In(rosterLoc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, rosterLoc)
+	ended := time.Now().Add(-time.Minute)
+	in := time.Now().Add(-time.Hour)
+	mk := func(name string, ends *time.Time) {
+		h := models.Household{Name: name}
+		gdb.Create(&h)
+		p := models.Parent{Name: "Ortu " + name, Phone: "+62" + name, HouseholdID: h.ID}
+		gdb.Create(&p)
+		kid := models.Child{Name: name, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		cl := models.Class{Name: "Class " + name, Date: today, EndsAt: ends, Capacity: 10, CampusID: &campus.ID}
+		gdb.Create(&cl)
+		gdb.Create(&models.Registration{
+			HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: "REG-" + name, CheckInAt: &in,
+		})
+	}
+	mk("Budi", &ended)
+	mk("Sari", nil)
+
+	volunteer := &models.AdminUser{Username: "fjb-door", Role: models.RoleCheckin, CampusID: &campus.ID}
+	r := httptest.NewRequest("GET", "/station", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxUserKey, volunteer))
+	vm, err := loadStation(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vm.StillIn) != 1 || vm.StillIn[0].Code != "REG-Budi" {
+		t.Errorf("StillIn = %+v, want only REG-Budi", vm.StillIn)
+	}
+}
+
+// TestCheckinRatioAlert checks a 1:1 room: with one volunteer in it the first
+// child goes in quietly, the second is checked in too but pushes the room
+// over, so the station is warned and the alert is logged. The third goes into
+// a room that is already over and is warned about and logged again. The
+// campus has no label printer, so every check-in hands its labels to the
+// browser.
+func TestCheckinRatioAlert(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	campus := models.Campus{Code: "FJB"}
+	gdb.Create(&campus)
+	today := time.Now().In(rosterLoc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, rosterLoc)
+	h := models.Household{Name: "Santoso"}
+	gdb.Create(&h)
+	p := models.Parent{Name: "Ibu Santoso", Phone: "+6281234567001", HouseholdID: h.ID}
+	gdb.Create(&p)
+	cl := models.Class{Name: "Little Stars", Date: today, Capacity: 10, CampusID: &campus.ID, KidsPerAdult: 1}
+	gdb.Create(&cl)
+	var regs []models.Registration
+	for i, name := range []string{"Budi", "Sari", "Tono"} {
+		kid := models.Child{Name: name, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		reg := models.Registration{HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: "REG-R" + string(rune('1'+i))}
+		gdb.Create(&reg)
+		regs = append(regs, reg)
+	}
+
+	volunteer := &models.AdminUser{Username: "fjb-door", Role: models.RoleCheckin, CampusID: &campus.ID}
+	request := func(method, target string, id uint) *http.Request {
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("Referer", "/station")
+		r.AddCookie(&http.Cookie{Name: staffCookieName, Value: "Kak Rina"})
+		rc := chi.NewRouteContext()
+		rc.URLParams.Add("id", strconv.Itoa(int(id)))
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rc)
+		return r.WithContext(context.WithValue(ctx, ctxUserKey, volunteer))
+	}
+
+	w := httptest.NewRecorder()
+	StationRoomStaff(w, request("POST", "/station/rooms/1/staff", cl.ID))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("staff in: %d %s", w.Code, w.Body)
+	}
+
+	checkin := func(reg models.Registration) string {
+		w := httptest.NewRecorder()
+		AdminRegCheckin(w, request("POST", "/admin/registrations/1/checkin", reg.ID))
+		return w.Header().Get("Location")
+	}
+	print := func(reg models.Registration) string { return "print=" + strconv.Itoa(int(reg.ID)) }
+	if loc, want := checkin(regs[0]), "/station?"+print(regs[0]); loc != want {
+		t.Errorf("first check-in went to %q, want %q", loc, want)
+	}
+	if loc, want := checkin(regs[1]), "/station?warn=ratio_over&"+print(regs[1]); loc != want {
+		t.Errorf("second check-in went to %q, want the ratio warning %q", loc, want)
+	}
+	if loc, want := checkin(regs[2]), "/station?warn=ratio_over&"+print(regs[2]); loc != want {
+		t.Errorf("third check-in went to %q, want the ratio warning %q", loc, want)
+	}
+	var alerts int64
+	gdb.Model(&models.AuditLog{}).Where("action = ?", "room.ratio_alert").Count(&alerts)
+	if alerts != 2 {
+		t.Errorf("ratio alerts logged = %d, want 2", alerts)
+	}
+
+	vm, err := loadStation(request("GET", "/station", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vm.Classes) != 1 || !vm.Classes[0].Here || !vm.Classes[0].Ratio.Over() || vm.Classes[0].Ratio.Kids != 3 {
+		t.Errorf("station room = %+v", vm.Classes)
+	}
+}
+
+// TestStationPickupPhoto_FencedToTodaysFamilies checks that a volunteer only
+// gets the photo of a pickup adult whose family has a child in one of today's
+// classes at their campus; anyone else's is a 404.
+func TestStationPickupPhoto_FencedToTodaysFamilies(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	campuses := []models.Campus{{Code: "FJB"}, {Code: "FJU"}}
+	gdb.Create(&campuses)
+	fjb, fju := campuses[0].ID, campuses[1].ID
+	today := time.Now().In(rosterLoc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, rosterLoc)
+	mk := func(name string, campus *uint, day time.Time) uint {
+		h := models.Household{Name: name}
+		gdb.Create(&h)
+		p := models.Parent{Name: "Ortu " + name, Phone: "+62" + name, HouseholdID: h.ID}
+		gdb.Create(&p)
+		kid := models.Child{Name: name, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		cl := models.Class{Name: "Little Stars", Date: day, Capacity: 10, CampusID: campus}
+		gdb.Create(&cl)
+		gdb.Create(&models.Registration{
+			HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: "REG-" + name,
+		})
+		pp := models.PickupPerson{ChildID: kid.ID, Name: "Oma " + name, Photo: []byte{0xff, 0xd8}, PhotoType: "image/jpeg"}
+		gdb.Create(&pp)
+		return pp.ID
+	}
+	here := mk("Budi", &fjb, today)
+	elsewhere := mk("Tono", &fju, today)
+	tomorrow := mk("Sari", &fjb, today.AddDate(0, 0, 1))
+
+	get := func(u *models.AdminUser, id uint) int {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", strconv.Itoa(int(id)))
+		r := httptest.NewRequest("GET", "/station/pickups/x/photo", nil)
+		r = r.WithContext(context.WithValue(context.WithValue(r.Context(), chi.RouteCtxKey, rctx), ctxUserKey, u))
+		w := httptest.NewRecorder()
+		StationPickupPhoto(w, r)
+		return w.Code
+	}
+	volunteer := &models.AdminUser{Username: "fjb-door", Role: models.RoleCheckin, CampusID: &fjb}
+	for id, want := range map[uint]int{here: http.StatusOK, elsewhere: http.StatusNotFound, tomorrow: http.StatusNotFound} {
+		if got := get(volunteer, id); got != want {
+			t.Errorf("volunteer photo %d = %d, want %d", id, got, want)
+		}
+	}
+	if got := get(&models.AdminUser{Username: "admin", Role: models.RoleAdmin}, elsewhere); got != http.StatusOK {
+		t.Errorf("admin photo = %d, want 200", got)
+	}
+}
+
+// TestPrintCheckinLabels_PerCampus checks that labels go to the printer set
+// on the class's campus, and that a campus without one leaves them to the
+// browser.
+func TestPrintCheckinLabels_PerCampus(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	printer, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer printer.Close()
+	got := make(chan string, 1)
+	go func() {
+		conn, err := printer.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		got <- string(b)
+	}()
+	off, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	off.Close() // nothing answers here any more
+
+	campuses := []models.Campus{
+		{Code: "FJB", LabelPrinter: "zpl://" + printer.Addr().String()},
+		{Code: "FJU"},
+		{Code: "FJT", LabelPrinter: "zpl://" + off.Addr().String()},
+	}
+	gdb.Create(&campuses)
+	mk := func(campus *uint, code string) models.Registration {
+		h := models.Household{Name: code}
+		gdb.Create(&h)
+		p := models.Parent{Name: "Ortu " + code, Phone: "+62" + code, HouseholdID: h.ID}
+		gdb.Create(&p)
+		kid := models.Child{Name: code, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		cl := models.Class{Name: "Little Stars", Date: time.Now(), Capacity: 10, CampusID: campus}
+		gdb.Create(&cl)
+		reg := models.Registration{HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID, Status: "confirmed", Code: code, SecurityCode: "K7"}
+		gdb.Create(&reg)
+		return reg
+	}
+	if !printCheckinLabels(mk(&campuses[0].ID, "REG-P")) {
+		t.Error("campus with a printer: labels not sent")
+	} else if zpl := <-got; !strings.Contains(zpl, "K7") {
+		t.Errorf("printer got %q, want the labels", zpl)
+	}
+	if printCheckinLabels(mk(&campuses[1].ID, "REG-B")) {
+		t.Error("campus without a printer: labels sent, want the browser")
+	}
+	if printCheckinLabels(mk(&campuses[2].ID, "REG-T")) {
+		t.Error("printer that is off: reported sent, want the browser")
+	}
+	if got := withPrint("http://host/admin/roster?class_id=3&print=1", []uint{7}); got != "http://host/admin/roster?class_id=3&print=7" {
+		t.Errorf("withPrint = %q", got)
+	}
+	if got := printQuery([]uint{4, 9}); got != "&print=4&print=9" {
+		t.Errorf("printQuery = %q", got)
+	}
+}
+
+// TestLoadFamily_UsesAccountCampusDay checks that a family code and the
+// classes it brings up are for today at the volunteer's campus, not today in
+// Jakarta. The campus sits in whichever far zone is on another date than
+// Jakarta right now.
+func TestLoadFamily_UsesAccountCampusDay(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	tz := "Pacific/Kiritimati" // UTC+14
+	if time.Now().In(rosterLoc).Format("2006-01-02") == time.Now().In(mustLoc(t, tz)).Format("2006-01-02") {
+		tz = "Pacific/Pago_Pago" // UTC-11
+	}
+	campus := models.Campus{Code: "FAR", TimeZone: tz}
+	gdb.Create(&campus)
+	loc := campus.Location()
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	h := models.Household{Name: "Santoso"}
+	gdb.Create(&h)
+	p := models.Parent{Name: "Ibu Santoso", Phone: "+6281234567001", HouseholdID: h.ID}
+	gdb.Create(&p)
+	kid := models.Child{Name: "Budi", ParentID: p.ID, HouseholdID: h.ID}
+	gdb.Create(&kid)
+	cl := models.Class{Name: "Little Stars", Date: today, Capacity: 10, CampusID: &campus.ID}
+	gdb.Create(&cl)
+	gdb.Create(&models.Registration{HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+		Status: "confirmed", Code: "REG-F1"})
+	code, err := svc.FamilyCodeFor(p, today.Format("2006-01-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volunteer := &models.AdminUser{Username: "far-door", Role: models.RoleCheckin, CampusID: &campus.ID}
+	r := httptest.NewRequest("GET", "/station/family?code="+code, nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxUserKey, volunteer))
+	_, kids, _, err := loadFamily(r, code)
+	if err != nil {
+		t.Fatalf("loadFamily: %v", err)
+	}
+	if len(kids) != 1 || kids[0].ChildName != "Budi" {
+		t.Errorf("family kids = %+v, want Budi", kids)
+	}
+}
+
+func mustLoc(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no tzdata for %s: %v", name, err)
+	}
+	return loc
+}
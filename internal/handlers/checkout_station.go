@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+type checkoutVM struct {
+	Title     string
+	StaffName string
+	Reg       models.Registration
+	ChildName string
+	ClassName string
+	InStr     string
+	Allowed   []svc.Pickup
+	Blocked   []models.PickupPerson
+	Flash     *Flash
+}
+
+// loadCheckout fetches a registration with its class and child and applies the
+// same fence as check-in: volunteers only release today's children at their
+// own campus.
+func loadCheckout(w http.ResponseWriter, r *http.Request) (models.Registration, models.Class, models.Child, bool) {
+	var reg models.Registration
+	var class models.Class
+	var child models.Child
+	if err := db.Conn().First(&reg, chi.URLParam(r, "id")).Error; err != nil {
+		http.Error(w, "not found", 404)
+		return reg, class, child, false
+	}
+	if err := db.Conn().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		http.Error(w, "class not found", 404)
+		return reg, class, child, false
+	}
+	if err := db.Conn().First(&child, reg.ChildID).Error; err != nil {
+		http.Error(w, "child not found", 404)
+		return reg, class, child, false
+	}
+	if err := guardCheckin(CurrentUser(r), class); err != nil {
+		writeAudit(r, nil, "registration.checkout.denied", regTarget(reg), err.Error())
+		http.Redirect(w, r, "/station?error=not_allowed", http.StatusSeeOther)
+		return reg, class, child, false
+	}
+	return reg, class, child, true
+}
+
+// GET /station/checkout/{id} — who may take this child home.
+func CheckoutForm(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/checkout.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reg, class, child, ok := loadCheckout(w, r)
+		if !ok {
+			return
+		}
+		allowed, blocked := svc.PickupsFor(child)
+		vm := checkoutVM{
+			Title:     "Check-out",
+			StaffName: StaffName(r),
+			Reg:       reg,
+			ChildName: child.Name,
+			ClassName: class.Name,
+			Allowed:   allowed,
+			Blocked:   blocked,
+			Flash:     MakeFlash(r, "", ""),
+		}
+		if reg.CheckInAt != nil {
+			vm.InStr = reg.CheckInAt.In(campusLoc(class.CampusID)).Format("15:04")
+		}
+		if err := view.ExecuteTemplate(w, "admin/checkout.tmpl", vm); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// POST /station/checkout/{id}
+func CheckoutSubmit(w http.ResponseWriter, r *http.Request) {
+	reg, _, _, ok := loadCheckout(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	back := "/station/checkout/" + strconv.Itoa(int(reg.ID))
+
+	out, err := svc.CheckOut(reg.ID, r.FormValue("pickup"), r.FormValue("security_code"), staffActor(r))
+	switch {
+	case errors.Is(err, svc.ErrSecurityCode):
+		writeAudit(r, nil, "registration.checkout.denied", regTarget(reg), "security code mismatch")
+		http.Redirect(w, r, back+"?error=security_code", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrDoNotRelease):
+		// Worth a trail: someone tried to release a child to a flagged person.
+		writeAudit(r, nil, "registration.checkout.denied", regTarget(reg), "do_not_release "+r.FormValue("pickup"))
+		http.Redirect(w, r, back+"?error=do_not_release", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrUnknownPickup):
+		http.Redirect(w, r, back+"?error=unknown_pickup", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrNotCheckedIn):
+		http.Redirect(w, r, "/station?error=not_checked_in", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrAlreadyCheckedOut):
+		http.Redirect(w, r, "/station?error=already_checkedout", http.StatusSeeOther)
+		return
+	case errors.Is(err, svc.ErrIllegalTransition):
+		http.Redirect(w, r, "/station?error=only_confirmed", http.StatusSeeOther)
+		return
+	case err != nil:
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, "registration.checkout", regTarget(out), "picked up by "+out.PickedUpBy)
+	http.Redirect(w, r, "/station?ok=checked_out", http.StatusSeeOther)
+}
+
+// GET /station/pickups/{id}/photo
+//
+// Volunteers only see the photos of families they may release a child of
+// right now: a confirmed registration in the household passing the checkout
+// fence. Anything else is a 404, so IDs cannot be stepped through. Admins
+// see every photo (the parent page links here).
+func StationPickupPhoto(w http.ResponseWriter, r *http.Request) {
+	var p models.PickupPerson
+	if err := db.Conn().First(&p, chi.URLParam(r, "id")).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if u := CurrentUser(r); u == nil || u.Role != models.RoleAdmin {
+		if !pickupAtStation(u, p) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	writePickupPhoto(w, r, p)
+}
+
+// pickupAtStation reports whether u may release a child of p's household
+// today: one of its confirmed registrations passes guardCheckin.
+func pickupAtStation(u *models.AdminUser, p models.PickupPerson) bool {
+	var child models.Child
+	if err := db.Conn().First(&child, p.ChildID).Error; err != nil || child.HouseholdID == 0 {
+		return false
+	}
+	var classIDs []uint
+	if err := db.Conn().Model(&models.Registration{}).
+		Where("household_id = ? AND status = ?", child.HouseholdID, svc.StatusConfirmed).
+		Distinct().Pluck("class_id", &classIDs).Error; err != nil || len(classIDs) == 0 {
+		return false
+	}
+	var classes []models.Class
+	_ = db.Conn().Preload("Campus").Where("id IN ?", classIDs).Find(&classes).Error
+	for _, c := range classes {
+		if guardCheckin(u, c) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// writePickupPhoto serves a pickup person's photo. It is personal data:
+// never cached by shared proxies.
+func writePickupPhoto(w http.ResponseWriter, r *http.Request, p models.PickupPerson) {
+	if p.PhotoType == "" || len(p.Photo) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", p.PhotoType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	_, _ = w.Write(p.Photo)
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import "github.com/lojf/nextgen/internal/config"
+
+// conf is the server config. web.Router sets it; tests run on the defaults.
+var conf = config.Default()
+
+// Configure hands the handlers the config loaded at startup. Call it before
+// serving.
+func Configure(c config.Config) {
+	conf = c
+}
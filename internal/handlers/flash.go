@@ -7,7 +7,7 @@ import (
 )
 
 type Flash struct {
-	Kind string // "ok" or "error"
+	Kind string // "ok", "warn" or "error"
 	Text string
 }
 
@@ -17,10 +17,25 @@ var okText = map[string]string{
 	"child_deleted": "Child deleted.",
 	"registered":    "Registration completed.",
 	"checked_in":    "Checked in.",
+	"checked_out":   "Checked out.",
+	"checkin_undone": "Check-in dibatalkan.",
+	"walkin_done":    "Walk-in terdaftar dan sudah di-check-in.",
+	"family_checked_in": "Semua anak yang dipilih sudah di-check-in.",
 	"deleted":        "Class deleted.",
 	"canceled":      "Registration canceled.",
 	"linked":        "Telegram linked.",
 	"unlinked":      "Telegram has been unlinked.",
+	"restored":      "Restored from trash.",
+	"purged":        "Permanently deleted.",
+	"guardian_added":   "Guardian added. They can now log in with their own phone.",
+	"guardian_removed": "Guardian removed.",
+	"noshow_policy":    "No-show policy saved.",
+	"series_generated": "Series sessions generated.",
+}
+
+// warnText is for actions that went through but need someone's attention.
+var warnText = map[string]string{
+	"ratio_over": "Check-in tercatat, tapi ruangan ini kekurangan pendamping. Panggil volunteer tambahan.",
 }
 
 var errText = map[string]string{
@@ -30,21 +45,54 @@ var errText = map[string]string{
 	"class_not_found":     "Class not found.",
 	"no_upcoming_classes": "No upcoming classes.",
 	"already_registered":  "This child is already registered for this class.",
-	"same_day_conflict":   "This child is already registered for another class that day.",
+	"same_day_conflict":   "This child is already registered for another class at that time.",
+	"age_ineligible":      "This class is not for your child's age.",
+	"age_override_needed": "This child is outside the class's ages. Tick \"Override age limit\" to add them anyway.",
+	"not_open_yet":        "Registration for this class is not open yet.",
+	"missing_child":       "Pick a child to add.",
+	"series_full":         "There is no session of this series left to sign up for.",
 	"invalid_code":        "Invalid or missing code.",
 	"code_not_found":      "Code not found.",
 	"invalid_checkin":     "Code is not eligible for check-in.",
 	"already_checkedin":   "Already checked in.",
+	"cannot_cancel":       "Only confirmed or waitlisted registrations can be canceled.",
 	"has_future":          "Cannot delete: parent has upcoming registrations. Cancel them first.",
 	"has_roster":          "Cannot delete: class still has active registrations. Delete all roster entries first.",
 	"not_allowed":         "Akun ini tidak boleh check-in kelas tersebut (bukan hari ini, atau beda campus).",
+	"staff_name":          "Isi nama pendamping.",
+	"checkin_window":      "Belum atau sudah lewat jam check-in kelas ini. Minta admin bila perlu.",
 	"only_confirmed":      "Hanya registrasi CONFIRMED yang bisa di-check-in.",
+	"not_checked_in":      "Anak ini belum di-check-in.",
+	"already_checkedout":  "Anak ini sudah dijemput.",
+	"do_not_release":      "JANGAN serahkan anak ke orang ini. Hubungi koordinator.",
+	"unknown_pickup":      "Pilih penjemput dari daftar.",
+	"security_code":       "Kode keamanan tidak cocok dengan stub orang tua.",
+	"undo_window":         "Batas waktu undo sudah lewat. Minta admin untuk membatalkan.",
+	"undo_other_shift":    "Check-in ini dicatat penjaga lain. Minta admin untuk membatalkan.",
+	"undo_not_allowed":    "Anak ini sudah dijemput; check-in tidak bisa dibatalkan.",
+	"walkin_missing":      "Isi nomor HP, nama orang tua, dan pilih atau isi nama anak.",
+	"walkin_full":         "Kelas penuh, kursi walk-in juga sudah habis.",
+	"walkin_child":        "Anak ini bukan bagian dari keluarga tersebut.",
+	"walkin_age":          "Usia anak di luar batas kelas ini. Minta admin menambahkan dari roster.",
+	"family_code":         "Kode keluarga tidak dikenal atau bukan untuk hari ini.",
+	"family_none":         "Pilih minimal satu anak.",
+	"family_partial":      "Sebagian anak tidak bisa di-check-in. Lihat daftar di bawah.",
 	"invalid":             "Username atau password salah.",
 	"locked":              "Terlalu banyak percobaan gagal. Coba lagi 15 menit lagi.",
+	"owner_deleted":       "Restore the parent, child or class it belongs to first.",
+	"phone_in_use":        "Cannot restore: another parent now uses that phone number.",
+	"guardian_phone_taken": "That phone number already belongs to another family. Ask an admin to merge them.",
+	"guardian_exists":      "That phone number is already a guardian of this family.",
+	"guardian_self":        "You cannot remove yourself.",
+	"last_guardian":        "A family needs at least one guardian.",
+	"guardian_failed":      "Could not save the guardian.",
+	"pickup_missing":       "Pickup name is required.",
+	"pickup_photo":         "Photo must be a JPEG or PNG under 1 MB.",
+	"noshow_policy":        "No-show policy needs a limit of 0 or more and 1 to 52 weeks.",
 }
 
 // MakeFlash reads query params and/or explicit strings to build a Flash.
-// Supports both new (?ok= / ?error=) and legacy (?msg= / ?err=) parameters.
+// Supports both new (?ok= / ?warn= / ?error=) and legacy (?msg= / ?err=) parameters.
 func MakeFlash(r *http.Request, errStr, msgStr string) *Flash {
 	q := r.URL.Query()
 
@@ -53,6 +101,7 @@ func MakeFlash(r *http.Request, errStr, msgStr string) *Flash {
 	if errRaw == "" {
 		errRaw = strings.TrimSpace(q.Get("err"))
 	}
+	warnRaw := strings.TrimSpace(q.Get("warn"))
 	okRaw := strings.TrimSpace(q.Get("ok"))
 	if okRaw == "" {
 		okRaw = strings.TrimSpace(q.Get("msg"))
@@ -65,6 +114,13 @@ func MakeFlash(r *http.Request, errStr, msgStr string) *Flash {
 		}
 		return &Flash{Kind: "error", Text: errRaw}
 	}
+	if warnRaw != "" {
+		key := strings.ToLower(warnRaw)
+		if t, ok := warnText[key]; ok {
+			return &Flash{Kind: "warn", Text: t}
+		}
+		return &Flash{Kind: "warn", Text: warnRaw}
+	}
 	if okRaw != "" {
 		key := strings.ToLower(okRaw)
 		if t, ok := okText[key]; ok {
@@ -90,17 +90,24 @@ func MyList(t *template.Template) http.HandlerFunc {
 		}
 		var rows []row
 		db.Conn().Table("registrations").
+			Where("registrations.deleted_at IS NULL").
 			Select(`registrations.code, registrations.status,
 			        classes.name as class_name, classes.date as class_date,
 			        children.name as child_name`).
 			Joins("JOIN classes ON classes.id = registrations.class_id").
 			Joins("JOIN children ON children.id = registrations.child_id").
-			Where("registrations.parent_id = ? AND classes.date >= ?", parent.ID, startUTC).
+			Where("registrations.household_id = ? AND classes.date >= ?", parent.HouseholdID, startUTC).
 			Order("classes.date asc, children.name asc").
 			Scan(&rows)
 
+		// Siblings confirmed for today share one family QR at the door.
+		endJak := startJak.AddDate(0, 0, 1)
+		familyCode := ""
 		out := make([]myRow, 0, len(rows))
 		for _, rrow := range rows {
+			if familyCode == "" && rrow.Status == string(svc.StatusConfirmed) && rrow.ClassDate.Before(endJak) {
+				familyCode, _ = svc.FamilyCodeFor(parent, nowJak.Format("2006-01-02"))
+			}
 			out = append(out, myRow{
 				Code:      rrow.Code,
 				Status:    rrow.Status,
@@ -119,6 +126,8 @@ func MyList(t *template.Template) http.HandlerFunc {
 			"Phone":  parent.Phone,
 			"Rows":   out,
 			"Parent": parent,
+
+			"FamilyCode": familyCode,
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
 		}
@@ -155,9 +164,9 @@ func MyQR(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		// Registration must belong to this parent
+		// Registration must belong to this family
 		var reg models.Registration
-		if err := db.Conn().Where("code = ? AND parent_id = ?", code, parent.ID).First(&reg).Error; err != nil {
+		if err := db.Conn().Where("code = ? AND household_id = ?", code, parent.HouseholdID).First(&reg).Error; err != nil {
 			http.NotFound(w, r)
 			return
 		}
@@ -167,16 +176,10 @@ func MyQR(t *template.Template) http.HandlerFunc {
 		var class models.Class
 		_ = db.Conn().First(&class, reg.ClassID).Error
 
-		// If waitlisted, compute rank = number of waitlisted regs for this class
-		// with created_at <= this reg's created_at (FIFO).
+		// If waitlisted, its place in the order the class's policy promotes.
 		waitRank := 0
-		if reg.Status == "waitlisted" {
-			var cnt int64
-			_ = db.Conn().Model(&models.Registration{}).
-				Where("class_id = ? AND status = ? AND created_at <= ?",
-					reg.ClassID, "waitlisted", reg.CreatedAt).
-				Count(&cnt).Error
-			waitRank = int(cnt)
+		if svc.StatusOf(reg) == svc.StatusWaitlisted {
+			waitRank, _ = svc.WaitlistRank(db.Conn(), reg)
 		}
 
 		_ = view.ExecuteTemplate(w, "parents/my_qr.tmpl", map[string]any{
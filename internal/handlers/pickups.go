@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// pickupFromForm reads the add-pickup form shared by the parent and admin
+// pages. The photo is optional; a bad one returns the "pickup_photo" flash key.
+func pickupFromForm(r *http.Request, childID uint) (models.PickupPerson, string) {
+	_ = r.ParseMultipartForm(svc.MaxPickupPhoto + 64<<10)
+	p := models.PickupPerson{
+		ChildID:      childID,
+		Name:         r.FormValue("pickup_name"),
+		Relation:     r.FormValue("relation"),
+		Phone:        r.FormValue("pickup_phone"),
+		Note:         r.FormValue("note"),
+		DoNotRelease: r.FormValue("do_not_release") == "1",
+	}
+	f, _, err := r.FormFile("photo")
+	if err != nil {
+		return p, "" // no photo
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, svc.MaxPickupPhoto+1))
+	if err != nil || len(b) > svc.MaxPickupPhoto {
+		return p, "pickup_photo"
+	}
+	if len(b) == 0 {
+		return p, ""
+	}
+	switch ct := http.DetectContentType(b); ct {
+	case "image/jpeg", "image/png":
+		p.Photo, p.PhotoType = b, ct
+	default:
+		return p, "pickup_photo"
+	}
+	return p, ""
+}
+
+func pickupErr(err error) string {
+	if errors.Is(err, svc.ErrMissingPickup) {
+		return "pickup_missing"
+	}
+	return "guardian_failed"
+}
+
+// accountChild loads a child of the signed-in guardian's household.
+func accountChild(w http.ResponseWriter, r *http.Request, idStr string) (models.Child, bool) {
+	var child models.Child
+	parent, ok := cookieParent(w, r)
+	if !ok {
+		return child, false
+	}
+	id, _ := strconv.Atoi(idStr)
+	if err := db.Conn().First(&child, id).Error; err != nil || child.HouseholdID != parent.HouseholdID {
+		http.Error(w, "child not found", http.StatusNotFound)
+		return child, false
+	}
+	return child, true
+}
+
+// POST /account/children/pickups
+func AccountPickupAdd(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseMultipartForm(svc.MaxPickupPhoto + 64<<10)
+	child, ok := accountChild(w, r, r.FormValue("child_id"))
+	if !ok {
+		return
+	}
+	back := "/account/children/edit?id=" + strconv.Itoa(int(child.ID))
+	p, bad := pickupFromForm(r, child.ID)
+	if bad != "" {
+		http.Redirect(w, r, back+"&error="+bad, http.StatusSeeOther)
+		return
+	}
+	if err := svc.AddPickupPerson(&p); err != nil {
+		http.Redirect(w, r, back+"&error="+pickupErr(err), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, back+"&ok=saved", http.StatusSeeOther)
+}
+
+// POST /account/children/pickups/remove
+func AccountPickupRemove(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	child, ok := accountChild(w, r, r.FormValue("child_id"))
+	if !ok {
+		return
+	}
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if err := svc.RemovePickupPerson(child.ID, uint(id)); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/account/children/edit?id="+strconv.Itoa(int(child.ID))+"&ok=saved", http.StatusSeeOther)
+}
+
+// GET /account/pickups/{id}/photo
+func AccountPickupPhoto(w http.ResponseWriter, r *http.Request) {
+	var p models.PickupPerson
+	if err := db.Conn().First(&p, chi.URLParam(r, "id")).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := accountChild(w, r, strconv.Itoa(int(p.ChildID))); !ok {
+		return
+	}
+	writePickupPhoto(w, r, p)
+}
+
+// POST /admin/parents/{id}/children/pickups
+func AdminPickupAdd(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseMultipartForm(svc.MaxPickupPhoto + 64<<10)
+	back := "/admin/parents/" + chi.URLParam(r, "id")
+	childID, _ := strconv.Atoi(r.FormValue("child_id"))
+	var child models.Child
+	if err := db.Conn().First(&child, childID).Error; err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	p, bad := pickupFromForm(r, child.ID)
+	if bad != "" {
+		http.Redirect(w, r, back+"?error="+bad, http.StatusSeeOther)
+		return
+	}
+	if err := svc.AddPickupPerson(&p); err != nil {
+		http.Redirect(w, r, back+"?error="+pickupErr(err), http.StatusSeeOther)
+		return
+	}
+	action := "child.pickup.add"
+	if p.DoNotRelease {
+		action = "child.pickup.do_not_release"
+	}
+	writeAudit(r, nil, action, "child:"+strconv.Itoa(int(child.ID))+" ("+child.Name+")", p.Label())
+	http.Redirect(w, r, back+"?ok=saved", http.StatusSeeOther)
+}
+
+// POST /admin/parents/{id}/children/pickups/remove
+func AdminPickupRemove(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	childID, _ := strconv.Atoi(r.FormValue("child_id"))
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	if err := svc.RemovePickupPerson(uint(childID), uint(id)); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeAudit(r, nil, "child.pickup.remove", "child:"+strconv.Itoa(childID), "pickup:"+strconv.Itoa(id))
+	http.Redirect(w, r, "/admin/parents/"+chi.URLParam(r, "id")+"?ok=saved", http.StatusSeeOther)
+}
@@ -2,14 +2,26 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	qrcode "github.com/skip2/go-qrcode"
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
 )
 
+func init() {
+	// QR payloads and image links share the session key.
+	svc.QRKey = sessionSecret
+}
+
+// QR serves the PNG for a registration or family code. The image opens the
+// child's details at check-in, so only the owning parent session, a staff
+// session, or a signed link (svc.QRImagePath) may fetch it.
 func QR(w http.ResponseWriter, r *http.Request) {
 	code := chi.URLParam(r, "code")
 	if code == "" {
@@ -17,21 +29,84 @@ func QR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// ensure code exists
-	var reg models.Registration
-	if err := db.Conn().Where("code = ?", code).First(&reg).Error; err != nil {
+	var payload string
+	var householdID uint
+	if svc.IsFamilyCode(code) {
+		var fc models.FamilyCode
+		if err := db.Conn().Where("code = ?", code).First(&fc).Error; err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		householdID = fc.HouseholdID
+		payload = svc.FamilyQR(fc)
+	} else {
+		var reg models.Registration
+		if err := db.Conn().Where("code = ?", code).First(&reg).Error; err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		householdID = reg.HouseholdID
+		tok, err := svc.RegistrationQR(reg)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		payload = tok
+	}
+	if !canSeeQR(r, code, householdID) {
+		// Same answer as a missing code, so codes cannot be probed.
 		http.NotFound(w, r)
 		return
 	}
 
 	// Encode a URL so scanning opens check-in directly
-	url := "http://" + r.Host + "/checkin?code=" + code
+	link := conf.URL("/checkin?t=" + payload)
 
-	png, err := qrcode.Encode(url, qrcode.Medium, 256)
+	png, err := qrcode.Encode(link, qrcode.Medium, 256)
 	if err != nil {
 		http.Error(w, "failed to generate qr", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-store")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(png)
 }
+
+// canSeeQR is the gate in front of QR images: a valid signed link, any
+// staff session, or the parent session of the owning household.
+func canSeeQR(r *http.Request, code string, householdID uint) bool {
+	q := r.URL.Query()
+	if svc.QRImageLinkValid(code, q.Get("exp"), q.Get("sig"), time.Now()) {
+		return true
+	}
+	if _, ok := sessionUser(r); ok {
+		return true
+	}
+	phone, _ := readParentCookies(r)
+	if strings.TrimSpace(phone) == "" || householdID == 0 {
+		return false
+	}
+	var p models.Parent
+	if err := db.Conn().Where("phone = ?", phone).First(&p).Error; err != nil {
+		return false
+	}
+	return p.HouseholdID == householdID
+}
+
+// qrToken pulls the signed payload out of what a station received: the t
+// parameter itself, or a full /checkin?t=… URL typed in by a scanner.
+func qrToken(r *http.Request) string {
+	if t := strings.TrimSpace(r.URL.Query().Get("t")); t != "" {
+		return t
+	}
+	code := strings.TrimSpace(r.URL.Query().Get("code"))
+	if !strings.Contains(code, "/checkin?") {
+		return ""
+	}
+	u, err := url.Parse(code)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("t")
+}
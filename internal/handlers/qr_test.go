@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// TestQRImageGate checks that a QR image is served only to the owning
+// parent, a staff session or a signed link, and that a stranger gets the
+// same 404 as for a code that does not exist.
+func TestQRImageGate(t *testing.T) {
+	gdb := dbtest.Init(t)
+
+	mk := func(name, phone, code string) {
+		h := models.Household{Name: name}
+		gdb.Create(&h)
+		p := models.Parent{Name: name, Phone: phone, HouseholdID: h.ID}
+		gdb.Create(&p)
+		kid := models.Child{Name: "Anak " + name, ParentID: p.ID, HouseholdID: h.ID}
+		gdb.Create(&kid)
+		cl := models.Class{Name: "Little Stars", Date: time.Now().AddDate(0, 0, 2), Capacity: 10}
+		gdb.Create(&cl)
+		gdb.Create(&models.Registration{
+			HouseholdID: h.ID, ParentID: p.ID, ChildID: kid.ID, ClassID: cl.ID,
+			Status: "confirmed", Code: code,
+		})
+	}
+	mk("Sari", "+628111", "REG-OWN")
+	mk("Tono", "+628222", "REG-OTHER")
+
+	staff := models.AdminUser{Username: "door", Role: models.RoleCheckin, Active: true}
+	gdb.Create(&staff)
+
+	get := func(target, code string, setup func(*http.Request)) int {
+		r := httptest.NewRequest("GET", target, nil)
+		rc := chi.NewRouteContext()
+		rc.URLParams.Add("code", code)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rc))
+		if setup != nil {
+			setup(r)
+		}
+		w := httptest.NewRecorder()
+		QR(w, r)
+		return w.Code
+	}
+	parent := func(phone string) func(*http.Request) {
+		return func(r *http.Request) { r.AddCookie(&http.Cookie{Name: parentPhoneCookie, Value: phone}) }
+	}
+
+	cases := []struct {
+		name   string
+		target string
+		code   string
+		setup  func(*http.Request)
+		want   int
+	}{
+		{"anonymous", "/qr/REG-OWN.png", "REG-OWN", nil, http.StatusNotFound},
+		{"owner", "/qr/REG-OWN.png", "REG-OWN", parent("+628111"), http.StatusOK},
+		{"other parent", "/qr/REG-OWN.png", "REG-OWN", parent("+628222"), http.StatusNotFound},
+		{"staff", "/qr/REG-OWN.png", "REG-OWN", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: adminCookieName, Value: signToken(staff.ID, time.Now().Add(time.Hour))})
+		}, http.StatusOK},
+		{"signed link", svc.QRImagePath("REG-OWN", time.Now().Add(time.Hour)), "REG-OWN", nil, http.StatusOK},
+		{"link for another code", svc.QRImagePath("REG-OTHER", time.Now().Add(time.Hour)), "REG-OWN", nil, http.StatusNotFound},
+		{"expired link", svc.QRImagePath("REG-OWN", time.Now().Add(-time.Minute)), "REG-OWN", nil, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if got := get(c.target, c.code, c.setup); got != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, got, c.want)
+		}
+	}
+}
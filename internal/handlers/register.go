@@ -1,8 +1,7 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -11,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
 	svc "github.com/lojf/nextgen/internal/services"
@@ -158,7 +159,7 @@ func RegisterOnboardSubmit(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		parent = models.Parent{Name: parentName, Phone: phone, Email: email}
-		if err := db.Conn().Create(&parent).Error; err != nil {
+		if err := svc.CreateParent(db.Conn(), &parent); err != nil {
 			le := strings.ToLower(err.Error())
 			if strings.Contains(le, "unique") && strings.Contains(le, "email") {
 				http.Error(w, "email already used by another account", http.StatusConflict); return
@@ -169,10 +170,11 @@ func RegisterOnboardSubmit(w http.ResponseWriter, r *http.Request) {
 
 	// Create first child with Gender (NEW)
 	child := models.Child{
-		Name:      childName,
-		BirthDate: d,
-		ParentID:  parent.ID,
-		Gender:    childGender, // NEW
+		Name:        childName,
+		BirthDate:   d,
+		HouseholdID: parent.HouseholdID,
+		ParentID:    parent.ID,
+		Gender:      childGender, // NEW
 	}
 	if err := db.Conn().Create(&child).Error; err != nil {
 		http.Error(w, "save child failed", http.StatusInternalServerError)
@@ -215,8 +217,8 @@ func RegisterKidsForm(t *template.Template) http.HandlerFunc {
 		// Safe now to refresh cookies (parent exists)
 		setParentCookies(w, parent.Phone, parent.Name)
 
-		var kids []models.Child
-		_ = db.Conn().Where("parent_id = ?", parent.ID).Order("name asc").Find(&kids).Error
+		// Every guardian of the household sees the same children.
+		kids := svc.HouseholdChildren(parent.HouseholdID)
 
 		_ = view.ExecuteTemplate(w, "parents/kids.tmpl", map[string]any{
 			"Title":  "Welcome back",
@@ -228,7 +230,8 @@ func RegisterKidsForm(t *template.Template) http.HandlerFunc {
 	}
 }
 
-// RegisterKidsSubmit handles child selection or "add new child"
+// RegisterKidsSubmit handles child selection (one or several siblings) or
+// "add new child"
 func RegisterKidsSubmit(w http.ResponseWriter, r *http.Request) {
 	_ = r.ParseForm()
 	phone := svc.NormPhone(r.FormValue("phone"))
@@ -242,32 +245,106 @@ func RegisterKidsSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	childSel := r.FormValue("child_id")
-	if childSel == "" {
+	childSel := r.Form["child_id"]
+	if len(childSel) == 0 {
 		http.Error(w, "please choose a child or add new", http.StatusBadRequest)
 		return
 	}
 
-	if childSel == "new" {
-		http.Redirect(w, r, "/register/newchild?phone="+url.QueryEscape(phone), http.StatusSeeOther)
-		return
+	for _, sel := range childSel {
+		if sel == "new" {
+			http.Redirect(w, r, "/register/newchild?phone="+url.QueryEscape(phone), http.StatusSeeOther)
+			return
+		}
 	}
 
-	childID, err := strconv.Atoi(childSel)
-	if err != nil || childID <= 0 {
+	ids, ok := siblingIDs(r)
+	if !ok {
 		http.Error(w, "invalid child", http.StatusBadRequest)
 		return
 	}
 
-	// (Optional safety) ensure the child belongs to this parent
+	// (Optional safety) ensure every child belongs to this parent's household
 	var cnt int64
-	db.Conn().Model(&models.Child{}).Where("id = ? AND parent_id = (SELECT id FROM parents WHERE phone = ?)", childID, phone).Count(&cnt)
-	if cnt == 0 {
+	db.Conn().Model(&models.Child{}).
+		Where("id IN ? AND household_id = (SELECT household_id FROM parents WHERE phone = ? AND deleted_at IS NULL)", ids, phone).
+		Count(&cnt)
+	if int(cnt) != len(ids) {
 		http.Error(w, "child not found for this parent", http.StatusNotFound)
 		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/register/classes?child_id=%d", childID), http.StatusSeeOther)
+	http.Redirect(w, r, "/register/classes?"+siblingQuery(ids), http.StatusSeeOther)
+}
+
+// siblingIDs reads the child_id values of r's form — one child, or several
+// siblings signing up together — in order and without repeats.
+func siblingIDs(r *http.Request) ([]uint, bool) {
+	_ = r.ParseForm()
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, v := range r.Form["child_id"] {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || id == 0 {
+			return nil, false
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, len(ids) > 0
+}
+
+// loadSiblings loads the children named by r's child_id values. Siblings
+// must share a household.
+func loadSiblings(r *http.Request) ([]models.Child, bool) {
+	ids, ok := siblingIDs(r)
+	if !ok {
+		return nil, false
+	}
+	var kids []models.Child
+	if err := db.Conn().Where("id IN ?", ids).Find(&kids).Error; err != nil || len(kids) != len(ids) {
+		return nil, false
+	}
+	byID := make(map[uint]models.Child, len(kids))
+	for _, k := range kids {
+		if k.HouseholdID != kids[0].HouseholdID {
+			return nil, false
+		}
+		byID[k.ID] = k
+	}
+	for i, id := range ids {
+		kids[i] = byID[id]
+	}
+	return kids, true
+}
+
+// siblingQuery is the child_id=…&child_id=… part of a URL for ids.
+func siblingQuery(ids []uint) string {
+	q := url.Values{}
+	for _, id := range ids {
+		q.Add("child_id", strconv.FormatUint(uint64(id), 10))
+	}
+	return q.Encode()
+}
+
+// childIDs lists kids' IDs.
+func childIDs(kids []models.Child) []uint {
+	ids := make([]uint, len(kids))
+	for i, k := range kids {
+		ids[i] = k.ID
+	}
+	return ids
+}
+
+// childNames joins kids' names for a heading.
+func childNames(kids []models.Child) string {
+	names := make([]string, len(kids))
+	for i, k := range kids {
+		names[i] = k.Name
+	}
+	return strings.Join(names, " & ")
 }
 
 // ------------------- STEP 2c: add a new child -------------------
@@ -315,10 +392,11 @@ func RegisterNewChildSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	child := models.Child{
-		Name:      childName,
-		BirthDate: d,
-		ParentID:  parent.ID,
-		Gender:    gender, // NEW
+		Name:        childName,
+		BirthDate:   d,
+		HouseholdID: parent.HouseholdID,
+		ParentID:    parent.ID,
+		Gender:      gender, // NEW
 	}
 	if err := db.Conn().Create(&child).Error; err != nil {
 		http.Error(w, "save child failed", http.StatusInternalServerError); return
@@ -344,6 +422,10 @@ func SelectClassForm(t *template.Template) http.HandlerFunc {
 		OpensAtUnix    int64
 		OpensInSeconds int64
 		CanRegister    bool
+		For            string // the selected siblings this class is open to
+		Ages           string // "4–6", blank when any age may come
+		AgeBlocked     bool   // every free sibling is outside Ages
+		NotFor         string // the free siblings outside Ages
 	}
 	type classRow struct {
 		ID            uint
@@ -354,30 +436,39 @@ func SelectClassForm(t *template.Template) http.HandlerFunc {
 		Waitlisted    int64
 		Description   string
 		SignupOpensAt *time.Time
+		StartsAt      *time.Time
+		EndsAt        *time.Time
+		Room          string
+		SeriesID      *uint
+		MinAge        *int
+		MaxAge        *int
+	}
+	// seriesOffer lets the parent take every remaining session at once.
+	type seriesOffer struct {
+		ID       uint
+		Name     string
+		Sessions int
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		childIDStr := r.URL.Query().Get("child_id")
-		if childIDStr == "" { http.Error(w, "missing child_id", http.StatusBadRequest); return }
-		childID, err := strconv.Atoi(childIDStr); if err != nil || childID <= 0 {
-			http.Error(w, "invalid child_id", http.StatusBadRequest); return
-		}
-
-		var child models.Child
-		if err := db.Conn().First(&child, childID).Error; err != nil {
+		if r.URL.Query().Get("child_id") == "" { http.Error(w, "missing child_id", http.StatusBadRequest); return }
+		kids, ok := loadSiblings(r)
+		if !ok {
 			http.Error(w, "child not found", http.StatusNotFound); return
 		}
 		var parent models.Parent
-		_ = db.Conn().First(&parent, child.ParentID).Error
+		_ = db.Conn().First(&parent, kids[0].ParentID).Error
 
 		errStr := ""
 		switch r.URL.Query().Get("error") {
 		case "already_registered":
 			errStr = "This child is already registered for this class."
 		case "same_day_conflict":
-			errStr = "This child already has a registration on that day."
+			errStr = "This child already has a registration at that time."
 		case "not_open_yet":
 			errStr = "Registration for this class is not open yet."
+		case "age_ineligible":
+			errStr = "This class is not for your child's age."
 		}
 
 		// ---- Time window: include ALL of "today" in Jakarta ----
@@ -392,19 +483,71 @@ func SelectClassForm(t *template.Template) http.HandlerFunc {
 			Table("classes AS c").
 			Select(`
 				c.id, c.name, c.date, c.capacity, c.description, c.signup_opens_at,
-				COALESCE(SUM(CASE WHEN r.status = 'confirmed'  THEN 1 ELSE 0 END), 0) AS confirmed,
-				COALESCE(SUM(CASE WHEN r.status = 'waitlisted' THEN 1 ELSE 0 END), 0) AS waitlisted
-			`).
-			Joins(`LEFT JOIN registrations r ON r.class_id = c.id AND r.status IN ('confirmed','waitlisted')`).
-			Where("c.date BETWEEN ? AND ?", fromUTC, toUTC).
+				c.starts_at, c.ends_at, c.room, c.series_id, c.min_age, c.max_age,
+				COALESCE(SUM(CASE WHEN r.status = ?  THEN 1 ELSE 0 END), 0) AS confirmed,
+				COALESCE(SUM(CASE WHEN r.status = ? THEN 1 ELSE 0 END), 0) AS waitlisted
+			`, svc.StatusConfirmed, svc.StatusWaitlisted).
+			Joins(`LEFT JOIN registrations r ON r.class_id = c.id AND r.status IN ? AND r.deleted_at IS NULL`,
+				[]svc.Status{svc.StatusConfirmed, svc.StatusWaitlisted}).
+			Where("c.deleted_at IS NULL AND c.date BETWEEN ? AND ?", fromUTC, toUTC).
 			Group("c.id").
-			Order("c.date ASC").
+			Order("COALESCE(c.starts_at, c.date) ASC").
 			Scan(&rows).Error; err != nil {
 			http.Error(w, "db error", http.StatusInternalServerError); return
 		}
 
+		// Each child is only offered the classes they may still join.
+		classIDs := make([]uint, len(rows))
+		for i, rr := range rows {
+			classIDs[i] = rr.ID
+		}
+		eligible, err := svc.EligibleChildren(childIDs(kids), classIDs)
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError); return
+		}
+		byID := make(map[uint]models.Child, len(kids))
+		for _, k := range kids {
+			byID[k.ID] = k
+		}
+
 		opts := make([]classOption, 0, len(rows))
+		var series []seriesOffer
+		seriesAt := map[uint]int{}
 		for _, rr := range rows {
+			if len(eligible[rr.ID]) == 0 {
+				continue
+			}
+			// Kids outside the class's ages still see it, greyed out
+			// with the reason, rather than wondering where it went.
+			ages := models.Class{Date: rr.Date, MinAge: rr.MinAge, MaxAge: rr.MaxAge}
+			var forNames, notFor []string
+			for _, id := range eligible[rr.ID] {
+				if svc.CheckAge(byID[id], ages) == nil {
+					forNames = append(forNames, byID[id].Name)
+				} else {
+					notFor = append(notFor, byID[id].Name)
+				}
+			}
+			start := rr.Date
+			if rr.StartsAt != nil {
+				start = *rr.StartsAt
+			}
+			if rr.SeriesID != nil && len(forNames) > 0 && start.After(nowJKT) {
+				i, seen := seriesAt[*rr.SeriesID]
+				if !seen {
+					var s models.ClassSeries
+					if err := db.Conn().Select("id, name").First(&s, *rr.SeriesID).Error; err == nil {
+						i = len(series)
+						seriesAt[s.ID] = i
+						series = append(series, seriesOffer{ID: s.ID, Name: s.Name})
+						seen = true
+					}
+				}
+				if seen {
+					series[i].Sessions++
+				}
+			}
+
 			left := rr.Capacity - int(rr.Confirmed)
 			if left < 0 { left = 0 }
 
@@ -422,7 +565,7 @@ func SelectClassForm(t *template.Template) http.HandlerFunc {
 			opts = append(opts, classOption{
 				ID:             rr.ID,
 				Name:           rr.Name,
-				DateStr:        fmtDate(rr.Date), // your helper (Jakarta formatting)
+				DateStr:        fmtClassWhen(rr.Date, rr.StartsAt, rr.EndsAt, rr.Room),
 				Capacity:       rr.Capacity,
 				Confirmed:      int(rr.Confirmed),
 				Waitlisted:     int(rr.Waitlisted),
@@ -433,15 +576,21 @@ func SelectClassForm(t *template.Template) http.HandlerFunc {
 				OpensAtUnix:    opensAtUnix,
 				OpensInSeconds: opensIn,
 				CanRegister:    canRegister,
+				For:            strings.Join(forNames, ", "),
+				Ages:           ages.AgeRange(),
+				AgeBlocked:     len(forNames) == 0,
+				NotFor:         strings.Join(notFor, ", "),
 			})
 		}
 
 		_ = view.ExecuteTemplate(w, "parents/select_class.tmpl", map[string]any{
 			"Title":        "Select Class",
-			"Child":        child,
+			"Kids":         kids,
+			"Names":        childNames(kids),
 			"Parent":       parent,
 			"Phone":        parent.Phone,
 			"ClassOptions": opts,
+			"Series":       series,
 			"Flash":        MakeFlash(r, errStr, ""),
 		})
 	}
@@ -456,117 +605,203 @@ func SelectClassSubmit(t *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_ = r.ParseForm()
 
-		childID, _ := strconv.Atoi(r.FormValue("child_id"))
 		classID, _ := strconv.Atoi(r.FormValue("class_id"))
-		if childID <= 0 { http.Error(w, "missing child_id", http.StatusBadRequest); return }
+		if r.FormValue("child_id") == "" { http.Error(w, "missing child_id", http.StatusBadRequest); return }
 		if classID <= 0 { http.Error(w, "no class selected", http.StatusBadRequest); return }
 
-		// Load child & class
-		var child models.Child
-		if err := db.Conn().First(&child, childID).Error; err != nil {
+		// Load children & class
+		kids, ok := loadSiblings(r)
+		if !ok {
 			http.Error(w, "child not found", http.StatusNotFound); return
 		}
 		var class models.Class
 		if err := db.Conn().First(&class, classID).Error; err != nil {
 			http.Error(w, "class not found", http.StatusNotFound); return
 		}
+		back := "/register/classes?" + siblingQuery(childIDs(kids)) + "&error="
 
 		// HARD GATE: enforce signup open time (server-side)
 		if class.SignupOpensAt != nil && time.Now().Before(class.SignupOpensAt.UTC()) {
-			http.Redirect(w, r,
-				"/register/classes?child_id="+strconv.Itoa(childID)+"&error=not_open_yet",
-				http.StatusSeeOther)
+			http.Redirect(w, r, back+"not_open_yet", http.StatusSeeOther)
 			return
 		}
 
-		// Validate conflicts (duplicate class / same-day)
-		if err := svc.CheckRegistrationConflicts(uint(childID), uint(classID)); err != nil {
-			switch err {
-			case svc.ErrDuplicateReg:
-				http.Redirect(w, r,
-					"/register/classes?child_id="+strconv.Itoa(childID)+"&error=already_registered",
-					http.StatusSeeOther)
-				return
-			case svc.ErrSameDayReg:
-				http.Redirect(w, r,
-					"/register/classes?child_id="+strconv.Itoa(childID)+"&error=same_day_conflict",
-					http.StatusSeeOther)
-				return
+		// Validate conflicts (duplicate class / same-day / ages). Siblings
+		// who cannot join are left out, and the result page says why.
+		var open []models.Child
+		var skipped []skippedRow
+		var skipIDs []uint
+		var conflict error
+		for _, k := range kids {
+			switch err := svc.CheckRegistrationConflicts(k.ID, class.ID); {
+			case err == nil:
+				open = append(open, k)
+			case errors.Is(err, svc.ErrDuplicateReg), errors.Is(err, svc.ErrSameDayReg), errors.Is(err, svc.ErrAgeIneligible):
+				conflict = err
+				skipped = append(skipped, skippedRow{ChildName: k.Name, Reason: skipReason(err)})
+				skipIDs = append(skipIDs, k.ID)
 			default:
 				http.Error(w, "validation error", http.StatusBadRequest); return
 			}
 		}
+		switch {
+		case len(open) > 0:
+		case errors.Is(conflict, svc.ErrDuplicateReg):
+			http.Redirect(w, r, back+"already_registered", http.StatusSeeOther)
+			return
+		case errors.Is(conflict, svc.ErrAgeIneligible):
+			http.Redirect(w, r, back+"age_ineligible", http.StatusSeeOther)
+			return
+		default:
+			http.Redirect(w, r, back+"same_day_conflict", http.StatusSeeOther)
+			return
+		}
+		together := r.FormValue("together") == "1"
 
 		// >>> Robust question check: actually load questions (don’t rely on COUNT)
-		var qs []models.ClassQuestion
-		if err := db.Conn().
-			Where("class_id = ?", class.ID).
-			Order("position asc, id asc").
-			Find(&qs).Error; err == nil && len(qs) > 0 {
+		if qs := classQuestions(class); len(qs) > 0 {
 			// send to confirm page
-			http.Redirect(w, r,
-				"/register/classes/confirm?child_id="+strconv.Itoa(childID)+"&class_id="+strconv.Itoa(classID),
-				http.StatusSeeOther)
+			confirm := "/register/classes/confirm?" + siblingQuery(childIDs(open)) + "&class_id=" + strconv.Itoa(classID)
+			if together {
+				confirm += "&together=1"
+			}
+			for _, id := range skipIDs {
+				confirm += "&skip=" + strconv.FormatUint(uint64(id), 10)
+			}
+			http.Redirect(w, r, confirm, http.StatusSeeOther)
 			return
 		}
 
-		// No questions → create the registration now (original flow)
-		var confirmedCnt int64
-		_ = db.Conn().Model(&models.Registration{}).
-			Where("class_id = ? AND status = ?", class.ID, "confirmed").
-			Count(&confirmedCnt).Error
+		// No questions → create the registrations now (original flow)
+		registerAndShow(w, r, view, open, class, nil, together, skipped)
+	}
+}
 
-		status := "waitlisted"
-		if int(confirmedCnt) < class.Capacity { status = "confirmed" }
+// doneRow is one child's line on the registration result page.
+type doneRow struct {
+	ChildName string
+	ClassName string
+	Date      string
+	Status    string
+	Code      string
+	QRURL     string
+	Rank      int
+}
 
-		code := generateRegCode()
-		if code == "" {
-			http.Error(w, "failed to generate code", http.StatusInternalServerError); return
-		}
+// skippedRow is a sibling the parent picked who was left out of the class.
+type skippedRow struct {
+	ChildName string
+	Reason    string
+}
 
-		reg := models.Registration{
-			ParentID: child.ParentID,
-			ChildID:  child.ID,
-			ClassID:  class.ID,
-			Status:   status,
-			Code:     code,
+// skipReason says, for the result page, why CheckRegistrationConflicts
+// kept a child out.
+func skipReason(err error) string {
+	var ae *svc.AgeError
+	switch {
+	case errors.As(err, &ae):
+		return fmt.Sprintf("will be %d on the class day; this class is for ages %s", ae.Age, ae.Range)
+	case errors.Is(err, svc.ErrDuplicateReg):
+		return "already registered for this class"
+	case errors.Is(err, svc.ErrSameDayReg):
+		return "already registered for another class at that time"
+	}
+	return "could not be registered"
+}
+
+// skippedFromForm reads back the siblings SelectClassSubmit left out
+// ("skip" values) after the confirm page, with why they still cannot join.
+// Only kids' own household counts.
+func skippedFromForm(r *http.Request, kids []models.Child, class models.Class) []skippedRow {
+	var ids []uint
+	for _, s := range r.Form["skip"] {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+			ids = append(ids, uint(id))
 		}
-		if err := db.Conn().Create(&reg).Error; err != nil {
-			http.Error(w, "failed to save registration", http.StatusInternalServerError); return
+	}
+	if len(ids) == 0 || len(kids) == 0 {
+		return nil
+	}
+	var sibs []models.Child
+	if err := db.Conn().Where("id IN ? AND household_id = ?", ids, kids[0].HouseholdID).Find(&sibs).Error; err != nil {
+		return nil
+	}
+	var out []skippedRow
+	for _, k := range sibs {
+		err := svc.CheckRegistrationConflicts(k.ID, class.ID)
+		if err == nil {
+			out = append(out, skippedRow{ChildName: k.Name, Reason: "was not signed up; pick the class again for them"})
+			continue
 		}
+		out = append(out, skippedRow{ChildName: k.Name, Reason: skipReason(err)})
+	}
+	return out
+}
 
-		_ = svc.RecomputeClass(uint(class.ID))
-		_ = db.Conn().First(&reg, reg.ID).Error
-		status = reg.Status
+// registerAndShow signs kids up for class through svc.RegisterSiblings and
+// renders the result page, or sends the parent back to the class list with
+// why not. answers are keyed by child ID, then question ID. skipped are the
+// siblings left out beforehand; the result page lists them with the reason.
+func registerAndShow(w http.ResponseWriter, r *http.Request, view *template.Template, kids []models.Child, class models.Class, answers map[uint]map[uint]string, together bool, skipped []skippedRow) {
+	signups := make([]svc.Signup, len(kids))
+	for i, k := range kids {
+		signups[i] = svc.Signup{ChildID: k.ID, Answers: answers[k.ID]}
+	}
+	results, err := svc.RegisterSiblings(class.ID, signups, together, parentActor(r))
+	back := func(reason string) {
+		http.Redirect(w, r,
+			"/register/classes?"+siblingQuery(childIDs(kids))+"&error="+reason,
+			http.StatusSeeOther)
+	}
+	switch {
+	case errors.Is(err, svc.ErrDuplicateReg):
+		back("already_registered")
+		return
+	case errors.Is(err, svc.ErrSameDayReg):
+		back("same_day_conflict")
+		return
+	case errors.Is(err, svc.ErrAgeIneligible):
+		back("age_ineligible")
+		return
+	case errors.Is(err, svc.ErrNotOpenYet):
+		back("not_open_yet")
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "class not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "failed to save registration", http.StatusInternalServerError)
+		return
+	}
 
-		var rank int64
-		if status == "waitlisted" {
-			_ = db.Conn().Model(&models.Registration{}).
-				Where("class_id = ? AND status = 'waitlisted' AND (created_at < ? OR (created_at = ? AND id <= ?))",
-					class.ID, reg.CreatedAt, reg.CreatedAt, reg.ID).
-				Count(&rank).Error
+	rows := make([]doneRow, len(results))
+	for i, res := range results {
+		rows[i] = doneRow{
+			ChildName: kids[i].Name,
+			ClassName: class.Name,
+			Date:      fmtDate(class.Date),
+			Status:    string(res.Status),
+			Code:      res.Code,
+			QRURL:     svc.QRImagePath(res.Code, time.Now().Add(svc.QRImageLinkTTL)),
+			Rank:      res.Rank,
 		}
-
-		_ = view.ExecuteTemplate(w, "parents/registration_done.tmpl", map[string]any{
-			"Title":     "Registration Result",
-			"ChildName": child.Name,
-			"ClassName": class.Name,
-			"Date":      fmtDate(class.Date),
-			"Status":    status,
-			"Code":      code,
-			"Rank":      rank,
-		})
 	}
+	_ = view.ExecuteTemplate(w, "parents/registration_done.tmpl", map[string]any{
+		"Title":    "Registration Result",
+		"Rows":     rows,
+		"Skipped":  skipped,
+		"Together": together && len(rows) > 1,
+	})
 }
 
-// generateRegCode creates a cryptographically random REG-xxxxxxxx code.
-// 32 bits of entropy makes collisions statistically impossible at this scale.
+// generateRegCode creates a cryptographically random REG-xxxxxxxx code, or
+// "" if the system has no randomness to give.
 func generateRegCode() string {
-	var b [4]byte
-	if _, err := rand.Read(b[:]); err != nil {
+	code, err := svc.NewRegCode()
+	if err != nil {
 		return ""
 	}
-	return fmt.Sprintf("REG-%08X", binary.BigEndian.Uint32(b[:]))
+	return code
 }
 
 func normGender(s string) string {
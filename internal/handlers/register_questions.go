@@ -1,14 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"html/template"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
-	"time"
 
 	"github.com/lojf/nextgen/internal/db"
 	"github.com/lojf/nextgen/internal/models"
@@ -24,20 +25,111 @@ type qVM struct {
 	Choices  []string
 }
 
+// kidQs is one child's set of answers on the confirm page. Inputs are named
+// q_<child ID>_<question ID> so siblings answer side by side.
+type kidQs struct {
+	Child models.Child
+	Qs    []qVM
+}
+
+// answerKey is the form field for child's answer to question q.
+func answerKey(childID, qID uint) string {
+	return "q_" + strconv.FormatUint(uint64(childID), 10) + "_" + strconv.FormatUint(uint64(qID), 10)
+}
+
+// classQuestions are the questions asked when signing up for class: its own,
+// then those its series shares.
+func classQuestions(class models.Class) []models.ClassQuestion {
+	var qs []models.ClassQuestion
+	q := db.Conn().Where("class_id = ?", class.ID)
+	if class.SeriesID != nil {
+		q = q.Or("series_id = ?", *class.SeriesID)
+	}
+	_ = q.Order("position asc, id asc").Find(&qs).Error
+	return qs
+}
+
+// seriesQuestions are the questions asked when signing up for all of
+// sessions, a series' remaining sessions: the ones the series shares, then
+// each session's own, labelled with its date. RegisterSeries keeps each
+// session's answers to its own questions.
+func seriesQuestions(seriesID uint, sessions []models.Class) []models.ClassQuestion {
+	var qs []models.ClassQuestion
+	_ = db.Conn().Where("series_id = ?", seriesID).Order("position asc, id asc").Find(&qs).Error
+	for _, s := range sessions {
+		var own []models.ClassQuestion
+		_ = db.Conn().Where("class_id = ?", s.ID).Order("position asc, id asc").Find(&own).Error
+		for _, q := range own {
+			q.Label += " (" + fmtDate(s.Date) + ")"
+			qs = append(qs, q)
+		}
+	}
+	return qs
+}
+
+// questionsFor lays qs out once per kid for class_confirm.tmpl.
+func questionsFor(kids []models.Child, qs []models.ClassQuestion) []kidQs {
+	items := make([]qVM, 0, len(qs))
+	for _, q := range qs {
+		v := qVM{ID: q.ID, Label: q.Label, Kind: q.Kind, Required: q.Required}
+		if q.Kind == "radio" && strings.TrimSpace(q.Options) != "" {
+			parts := strings.Split(q.Options, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			v.Choices = parts
+		}
+		items = append(items, v)
+	}
+	perKid := make([]kidQs, len(kids))
+	for i, k := range kids {
+		perKid[i] = kidQs{Child: k, Qs: items}
+	}
+	return perKid
+}
+
+// answersFromForm validates every kid's answers to qs. On a missing or
+// invalid answer it returns the message to show instead.
+func answersFromForm(r *http.Request, kids []models.Child, qs []models.ClassQuestion) (map[uint]map[uint]string, string) {
+	answers := make(map[uint]map[uint]string, len(kids))
+	for _, k := range kids {
+		answers[k.ID] = make(map[uint]string, len(qs))
+		for _, q := range qs {
+			val := strings.TrimSpace(r.FormValue(answerKey(k.ID, q.ID)))
+			if q.Required && val == "" {
+				return nil, "Please answer for " + k.Name + ": " + q.Label
+			}
+			if q.Kind == "radio" && val != "" && strings.TrimSpace(q.Options) != "" {
+				ok := false
+				for _, opt := range strings.Split(q.Options, ",") {
+					if strings.TrimSpace(opt) == val {
+						ok = true
+						break
+					}
+				}
+				if !ok {
+					return nil, "Invalid choice for " + k.Name + ": " + q.Label
+				}
+			}
+			answers[k.ID][q.ID] = val
+		}
+	}
+	return answers, ""
+}
+
 func SelectClassConfirmForm(t *template.Template) http.HandlerFunc {
 	view := template.Must(t.Clone())
 	template.Must(view.ParseFiles("templates/pages/parents/class_confirm.tmpl"))
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		childID, _ := strconv.Atoi(r.URL.Query().Get("child_id"))
 		classID, _ := strconv.Atoi(r.URL.Query().Get("class_id"))
-		if childID == 0 || classID == 0 {
+		if r.URL.Query().Get("child_id") == "" || classID == 0 {
 			http.Error(w, "missing ids", http.StatusBadRequest)
 			return
 		}
 
-		var child models.Child
-		if err := db.Conn().First(&child, childID).Error; err != nil {
+		kids, ok := loadSiblings(r)
+		if !ok {
 			http.Error(w, "child not found", http.StatusNotFound)
 			return
 		}
@@ -47,30 +139,17 @@ func SelectClassConfirmForm(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		var qs []models.ClassQuestion
-		_ = db.Conn().Where("class_id = ?", classID).Order("position asc, id asc").Find(&qs).Error
-
-		items := make([]qVM, 0, len(qs))
-		for _, q := range qs {
-			v := qVM{ID: q.ID, Label: q.Label, Kind: q.Kind, Required: q.Required}
-			if q.Kind == "radio" && strings.TrimSpace(q.Options) != "" {
-				parts := strings.Split(q.Options, ",")
-				for i := range parts {
-					parts[i] = strings.TrimSpace(parts[i])
-				}
-				v.Choices = parts
-			}
-			items = append(items, v)
-		}
-
 		if err := view.ExecuteTemplate(w, "parents/class_confirm.tmpl", map[string]any{
-			"Title":   "Confirm Registration",
-			"Child":   child,
-			"Class":   class,
-			"Qs":      items,
-			"ChildID": childID,
-			"ClassID": classID,
-			"Err":     r.URL.Query().Get("err"),
+			"Title":     "Confirm Registration",
+			"Kids":      questionsFor(kids, classQuestions(class)),
+			"Names":     childNames(kids),
+			"Action":    "/register/classes/confirm",
+			"ClassName": class.Name,
+			"When":      class.Date.Format("Mon, 02 Jan 2006 15:04"),
+			"ClassID":   classID,
+			"Skip":      r.URL.Query()["skip"],
+			"Together":  r.URL.Query().Get("together") == "1",
+			"Err":       r.URL.Query().Get("err"),
 		}); err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -85,67 +164,14 @@ func SelectClassConfirmSubmit(t *template.Template) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		_ = r.ParseForm()
-		childID, _ := strconv.Atoi(r.FormValue("child_id"))
 		classID, _ := strconv.Atoi(r.FormValue("class_id"))
-		if childID == 0 || classID == 0 {
+		if r.FormValue("child_id") == "" || classID == 0 {
 			http.Error(w, "missing ids", http.StatusBadRequest)
 			return
 		}
 
-		// Load questions to validate
-		var qs []models.ClassQuestion
-		_ = db.Conn().Where("class_id = ?", classID).Order("position asc, id asc").Find(&qs).Error
-
-		answers := make(map[uint]string, len(qs))
-		for _, q := range qs {
-			key := "q_" + strconv.FormatUint(uint64(q.ID), 10)
-			val := strings.TrimSpace(r.FormValue(key))
-			if q.Required && val == "" {
-				http.Redirect(w, r,
-					"/register/classes/confirm?child_id="+strconv.Itoa(childID)+"&class_id="+strconv.Itoa(classID)+"&err="+url.QueryEscape("Please answer: "+q.Label),
-					http.StatusSeeOther)
-				return
-			}
-			if q.Kind == "radio" && val != "" && strings.TrimSpace(q.Options) != "" {
-				ok := false
-				for _, opt := range strings.Split(q.Options, ",") {
-					if strings.TrimSpace(opt) == val {
-						ok = true
-						break
-					}
-				}
-				if !ok {
-					http.Redirect(w, r,
-						"/register/classes/confirm?child_id="+strconv.Itoa(childID)+"&class_id="+strconv.Itoa(classID)+"&err="+url.QueryEscape("Invalid choice for: "+q.Label),
-						http.StatusSeeOther)
-					return
-				}
-			}
-			answers[q.ID] = val
-		}
-
-		// Safety: conflicts again
-		if err := svc.CheckRegistrationConflicts(uint(childID), uint(classID)); err != nil {
-			switch err {
-			case svc.ErrDuplicateReg:
-				http.Redirect(w, r,
-					"/register/classes?child_id="+strconv.Itoa(childID)+"&error=already_registered",
-					http.StatusSeeOther)
-				return
-			case svc.ErrSameDayReg:
-				http.Redirect(w, r,
-					"/register/classes?child_id="+strconv.Itoa(childID)+"&error=same_day_conflict",
-					http.StatusSeeOther)
-				return
-			default:
-				http.Error(w, "validation error", http.StatusBadRequest)
-				return
-			}
-		}
-
-		// Create registration (same logic as your SelectClassSubmit)
-		var child models.Child
-		if err := db.Conn().First(&child, childID).Error; err != nil {
+		kids, ok := loadSiblings(r)
+		if !ok {
 			http.Error(w, "child not found", http.StatusNotFound)
 			return
 		}
@@ -155,84 +181,134 @@ func SelectClassConfirmSubmit(t *template.Template) http.HandlerFunc {
 			return
 		}
 
-		if class.SignupOpensAt != nil {
-		    nowUTC := time.Now().UTC()
-		    // allow a small grace to cover click at zero / network delay
-		    opensUTC := class.SignupOpensAt.UTC().Add(-2 * time.Second)
-		    if nowUTC.Before(opensUTC) {
-		        http.Redirect(w, r,
-		            "/register/classes?child_id="+strconv.Itoa(childID)+"&error=not_open_yet",
-		            http.StatusSeeOther)
-		        return
-		    }
+		// Load questions to validate
+		answers, msg := answersFromForm(r, kids, classQuestions(class))
+		if msg != "" {
+			u := "/register/classes/confirm?" + siblingQuery(childIDs(kids)) + "&class_id=" + strconv.Itoa(classID)
+			if r.FormValue("together") == "1" {
+				u += "&together=1"
+			}
+			http.Redirect(w, r, u+"&err="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
 		}
 
-		var confirmedCnt int64
-		_ = db.Conn().Model(&models.Registration{}).
-			Where("class_id = ? AND status = ?", class.ID, "confirmed").Count(&confirmedCnt).Error
-		status := "waitlisted"
-		if int(confirmedCnt) < class.Capacity {
-			status = "confirmed"
-		}
+		// Signup time, capacity and conflicts are settled again inside
+		// RegisterSiblings, under the class lock.
+		registerAndShow(w, r, view, kids, class, answers, r.FormValue("together") == "1", skippedFromForm(r, kids, class))
+	}
+}
 
-		code := generateRegCode()
-		if code == "" {
-			http.Error(w, "failed to generate code", http.StatusInternalServerError)
+// GET /register/series/confirm — the questions of all the series' remaining
+// sessions, answered once per child.
+func SelectSeriesConfirmForm(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/parents/class_confirm.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		kids, ok := loadSiblings(r)
+		if !ok {
+			http.Error(w, "child not found", http.StatusNotFound)
+			return
+		}
+		var series models.ClassSeries
+		if err := db.Conn().First(&series, r.URL.Query().Get("series_id")).Error; err != nil {
+			http.Error(w, "series not found", http.StatusNotFound)
 			return
 		}
+		sessions, _ := svc.RemainingSessions(series.ID)
 
-		var reg models.Registration
-		err := db.Conn().Transaction(func(tx *gorm.DB) error {
-			reg = models.Registration{
-				ParentID: child.ParentID,
-				ChildID:  child.ID,
-				ClassID:  class.ID,
-				Status:   status,
-				Code:     code,
-			}
-			if err := tx.Create(&reg).Error; err != nil {
-				return err
-			}
+		if err := view.ExecuteTemplate(w, "parents/class_confirm.tmpl", map[string]any{
+			"Title":     "Confirm Registration",
+			"Kids":      questionsFor(kids, seriesQuestions(series.ID, sessions)),
+			"Names":     childNames(kids),
+			"Action":    "/register/series",
+			"ClassName": series.Name,
+			"When":      strconv.Itoa(len(sessions)) + " remaining sessions",
+			"SeriesID":  series.ID,
+			"Together":  r.URL.Query().Get("together") == "1",
+			"Err":       r.URL.Query().Get("err"),
+		}); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
 
-			// Save answers
-			for qid, ans := range answers {
-				ra := models.RegistrationAnswer{
-					RegistrationID: reg.ID,
-					QuestionID:     qid,
-					Answer:         ans,
-				}
-				if err := tx.Create(&ra).Error; err != nil {
-					return err
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			http.Error(w, "db error", http.StatusInternalServerError)
+// POST /register/series — signs the children up for every remaining session
+// of a series. The class page posts here straight away; when the series has
+// questions the parent is sent to answer them first (answered=1 on return).
+func SelectSeriesSubmit(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/parents/registration_done.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		kids, ok := loadSiblings(r)
+		if !ok {
+			http.Error(w, "child not found", http.StatusNotFound)
+			return
+		}
+		var series models.ClassSeries
+		if err := db.Conn().First(&series, r.FormValue("series_id")).Error; err != nil {
+			http.Error(w, "series not found", http.StatusNotFound)
 			return
 		}
+		together := r.FormValue("together") == "1"
+		confirm := "/register/series/confirm?" + siblingQuery(childIDs(kids)) + "&series_id=" + strconv.FormatUint(uint64(series.ID), 10)
+		if together {
+			confirm += "&together=1"
+		}
 
-		// Recompute & maybe update status
-		_ = svc.RecomputeClass(uint(class.ID))
-		_ = db.Conn().First(&reg, reg.ID).Error
-		status = reg.Status
+		remaining, _ := svc.RemainingSessions(series.ID)
+		qs := seriesQuestions(series.ID, remaining)
+		if len(qs) > 0 && r.FormValue("answered") != "1" {
+			http.Redirect(w, r, confirm, http.StatusSeeOther)
+			return
+		}
+		answers, msg := answersFromForm(r, kids, qs)
+		if msg != "" {
+			http.Redirect(w, r, confirm+"&err="+url.QueryEscape(msg), http.StatusSeeOther)
+			return
+		}
 
-		var rank int64
-		if status == "waitlisted" {
-			_ = db.Conn().Model(&models.Registration{}).
-				Where("class_id = ? AND status = 'waitlisted' AND (created_at < ? OR (created_at = ? AND id <= ?))",
-					class.ID, reg.CreatedAt, reg.CreatedAt, reg.ID).
-				Count(&rank).Error
+		signups := make([]svc.Signup, len(kids))
+		for i, k := range kids {
+			signups[i] = svc.Signup{ChildID: k.ID, Answers: answers[k.ID]}
+		}
+		sessions, err := svc.RegisterSeries(series.ID, signups, together, parentActor(r))
+		switch {
+		case errors.Is(err, svc.ErrNoSessionsLeft):
+			http.Redirect(w, r, "/register/classes?"+siblingQuery(childIDs(kids))+"&error=series_full", http.StatusSeeOther)
+			return
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, "failed to save registration", http.StatusInternalServerError)
+			return
 		}
 
+		names := make(map[uint]string, len(kids))
+		for _, k := range kids {
+			names[k.ID] = k.Name
+		}
+		var rows []doneRow
+		for _, s := range sessions {
+			for _, res := range s.Results {
+				rows = append(rows, doneRow{
+					ChildName: names[res.Registration.ChildID],
+					ClassName: s.Class.Name,
+					Date:      fmtDate(s.Class.Date),
+					Status:    string(res.Status),
+					Code:      res.Code,
+					QRURL:     svc.QRImagePath(res.Code, time.Now().Add(svc.QRImageLinkTTL)),
+					Rank:      res.Rank,
+				})
+			}
+		}
 		_ = view.ExecuteTemplate(w, "parents/registration_done.tmpl", map[string]any{
-			"Title":     "Registration Result",
-			"ChildName": child.Name,
-			"ClassName": class.Name,
-			"Date":      fmtDate(class.Date),
-			"Status":    status,
-			"Code":      code,
-			"Rank":      rank,
+			"Title":    "Registration Result",
+			"Rows":     rows,
+			"Together": together && len(kids) > 1,
 		})
 	}
 }
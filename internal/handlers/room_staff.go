@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// POST /station/rooms/{id}/staff — a volunteer enters (action=in) or leaves
+// (action=out) a class's room. The name defaults to this shift's; a lead may
+// type someone else's. Same fence as check-in: today, own campus.
+func StationRoomStaff(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, chi.URLParam(r, "id")).Error; err != nil {
+		http.Redirect(w, r, "/station?error=class_not_found", http.StatusSeeOther)
+		return
+	}
+	if err := guardCheckin(CurrentUser(r), class); err != nil {
+		writeAudit(r, nil, "room.staff.denied", "class:"+class.Name, err.Error())
+		http.Redirect(w, r, "/station?error=not_allowed", http.StatusSeeOther)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = StaffName(r)
+	}
+	if len(name) > 60 {
+		name = name[:60]
+	}
+
+	var err error
+	action := "room.staff_in"
+	if r.FormValue("action") == "out" {
+		action = "room.staff_out"
+		err = svc.StaffOut(class.ID, name, staffActor(r))
+	} else {
+		err = svc.StaffIn(class.ID, name, staffActor(r))
+	}
+	switch {
+	case errors.Is(err, svc.ErrStaffName):
+		http.Redirect(w, r, "/station?error=staff_name", http.StatusSeeOther)
+		return
+	case err != nil:
+		http.Error(w, "db error", 500)
+		return
+	}
+	writeAudit(r, nil, action, "class:"+class.Name, name)
+	http.Redirect(w, r, "/station", http.StatusSeeOther)
+}
+
+// roomBefore reads class's room just before a check-in, for ratioAlert to
+// compare against. A room that cannot be read counts as not over.
+func roomBefore(class models.Class) svc.Ratio {
+	ratio, _ := svc.RoomRatio(class.ID)
+	return ratio
+}
+
+// ratioAlert looks at class's room right after a check-in. Whenever the room
+// is over after it, whether this child pushed it over or it already was, the
+// alert goes to the audit log and ratioAlert returns true so the station can
+// warn: every child let into a room short of adults is one more to answer for.
+func ratioAlert(r *http.Request, class models.Class, before svc.Ratio) bool {
+	ratio, err := svc.RoomRatio(class.ID)
+	if err != nil || !ratio.Over() {
+		return false
+	}
+	writeAudit(r, nil, "room.ratio_alert", "class:"+class.Name,
+		fmt.Sprintf("%d children, %d adults, max %d per adult; short %d adult(s), was %d",
+			ratio.Kids, ratio.Adults, ratio.KidsPerAdult, ratio.Short(), before.Short()))
+	return true
+}
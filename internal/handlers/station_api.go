@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+// maxSyncBatch bounds one upload; a station that was offline for a whole
+// service has a few hundred check-ins at most.
+const maxSyncBatch = 500
+
+// stationSnapshot is what a station keeps locally to keep working offline.
+type stationSnapshot struct {
+	Campus      string         `json:"campus"`
+	Day         string         `json:"day"` // YYYY-MM-DD at the campus
+	GeneratedAt time.Time      `json:"generated_at"`
+	Classes     []stationClass `json:"classes"`
+}
+
+// GET /station/api/snapshot — today's station as JSON, same fence as the page.
+func StationSnapshot(w http.ResponseWriter, r *http.Request) {
+	vm, err := loadStation(r)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(stationSnapshot{
+		Campus:      vm.Campus,
+		Day:         vm.Day,
+		GeneratedAt: time.Now().UTC(),
+		Classes:     vm.Classes,
+	})
+}
+
+type syncRequest struct {
+	Device   string `json:"device"`
+	Checkins []struct {
+		Key          string    `json:"key"`
+		RegID        uint      `json:"reg_id"`
+		At           time.Time `json:"at"`
+		SecurityCode string    `json:"security_code"`
+	} `json:"checkins"`
+}
+
+// syncResult outcomes beyond models.SyncApplied and models.SyncConflict.
+const (
+	syncDenied   = "denied"    // guardCheckin said no; not remembered
+	syncNotFound = "not_found" // no such registration, even in the trash
+	syncInvalid  = "invalid"   // no idempotency key
+)
+
+type syncResult struct {
+	Key         string     `json:"key"`
+	RegID       uint       `json:"reg_id"`
+	Outcome     string     `json:"outcome"`
+	Reason      string     `json:"reason,omitempty"`
+	Replay      bool       `json:"replay,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	CheckInAt   *time.Time `json:"check_in_at,omitempty"`
+	CheckedInBy string     `json:"checked_in_by,omitempty"`
+	RatioOver   bool       `json:"ratio_over,omitempty"` // this check-in left the room short of adults
+}
+
+// POST /station/api/checkins — upload check-ins recorded offline.
+//
+// Each one is fenced like a live check-in and applied at most once per key.
+// The reply has one result per check-in, in order; conflicts carry the
+// server's view of the registration so the station can show it.
+func StationSyncCheckins(w http.ResponseWriter, r *http.Request) {
+	var req syncRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(req.Checkins) > maxSyncBatch {
+		http.Error(w, "batch too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	u := CurrentUser(r)
+	var accountID uint
+	if u != nil {
+		accountID = u.ID
+	}
+	by := staffActor(r)
+	now := time.Now()
+	results := make([]syncResult, 0, len(req.Checkins))
+	for _, c := range req.Checkins {
+		res := syncResult{Key: c.Key, RegID: c.RegID}
+		if c.Key == "" {
+			res.Outcome = syncInvalid
+			results = append(results, res)
+			continue
+		}
+
+		var reg models.Registration
+		var class models.Class
+		if err := db.Conn().Unscoped().First(&reg, c.RegID).Error; err != nil {
+			res.Outcome = syncNotFound
+			results = append(results, res)
+			continue
+		}
+		if err := db.Conn().Unscoped().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+			res.Outcome = syncNotFound
+			results = append(results, res)
+			continue
+		}
+		// The window is judged at the device's scan time: a tablet that was
+		// offline during the session syncs after it has closed.
+		at := c.At
+		if at.IsZero() || at.After(now) {
+			at = now
+		}
+		if err := guardCheckinAt(u, class, at); err != nil {
+			writeAudit(r, nil, "registration.checkin.denied", regTarget(reg), err.Error()+" (offline sync)")
+			res.Outcome, res.Reason = syncDenied, err.Error()
+			results = append(results, res)
+			continue
+		}
+
+		before := roomBefore(class)
+		out, err := svc.SyncCheckIn(svc.OfflineCheckIn{
+			Key:          c.Key,
+			AccountID:    accountID,
+			RegID:        reg.ID,
+			At:           c.At,
+			Device:       req.Device,
+			SecurityCode: c.SecurityCode,
+		}, by, now)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			res.Outcome = syncNotFound
+			results = append(results, res)
+			continue
+		case err != nil:
+			http.Error(w, "db error", 500)
+			return
+		}
+		res.Outcome, res.Reason, res.Replay = out.Outcome, out.Reason, out.Replay
+		res.Status = out.Registration.Status
+		res.CheckInAt = out.Registration.CheckInAt
+		res.CheckedInBy = out.Registration.CheckedInBy
+		if !out.Replay {
+			detail := "class:" + class.Name + "; offline"
+			if req.Device != "" {
+				detail += " on " + req.Device
+			}
+			if out.Outcome == models.SyncApplied {
+				writeAudit(r, nil, "registration.checkin", regTarget(out.Registration), detail)
+				res.RatioOver = ratioAlert(r, class, before)
+			} else {
+				writeAudit(r, nil, "registration.checkin.conflict", regTarget(out.Registration), detail+": "+out.Reason)
+			}
+		}
+		results = append(results, res)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lojf/nextgen/internal/events"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// livePing keeps idle streams open through proxies that cut silent ones.
+const livePing = 25 * time.Second
+
+// liveFilter decides which changes a stream carries. A check-in account
+// watches its own campus today; admins may pick a campus (?campus_id=) and,
+// for the capacity page, every day (?day=all).
+func liveFilter(u *models.AdminUser, campusID, day string) func(events.Change) bool {
+	var scope *uint
+	if u != nil {
+		scope = u.CampusID
+	}
+	admin := u == nil || u.Role == models.RoleAdmin
+	if admin {
+		if id, err := strconv.Atoi(campusID); err == nil && id > 0 {
+			c := uint(id)
+			scope = &c
+		}
+	}
+	if day != "all" || !admin {
+		day = time.Now().In(campusLoc(scope)).Format("2006-01-02")
+	}
+	return func(c events.Change) bool {
+		if scope != nil && (c.CampusID == nil || *c.CampusID != *scope) {
+			return false
+		}
+		return day == "all" || c.Day == day
+	}
+}
+
+// GET /station/live — a Server-Sent Events stream of check-ins, undos,
+// check-outs, cancellations and promotions, so every tablet at the door and
+// the capacity page stay current without reloading.
+func StationLive(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", 500)
+		return
+	}
+	q := r.URL.Query()
+	ch, stop := events.Subscribe(liveFilter(CurrentUser(r), q.Get("campus_id"), q.Get("day")))
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: do not buffer the stream
+	fmt.Fprint(w, "retry: 3000\n\n")
+	fl.Flush()
+
+	ping := time.NewTicker(livePing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-ch:
+			if !ok {
+				return // fell behind; the browser reconnects and reloads
+			}
+			b, _ := json.Marshal(c)
+			fmt.Fprintf(w, "event: change\ndata: %s\n\n", b)
+			fl.Flush()
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			fl.Flush()
+		}
+	}
+}
@@ -4,14 +4,13 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 
 	"github.com/lojf/nextgen/internal/bot"
 )
 
 func TelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	// Simple secret check: /tg/webhook?secret=...
-	if r.URL.Query().Get("secret") != os.Getenv("TG_WEBHOOK_SECRET") {
+	if r.URL.Query().Get("secret") != conf.Telegram.WebhookSecret {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
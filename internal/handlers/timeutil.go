@@ -24,3 +24,19 @@ func fmtDate(d time.Time) string {
 func fmtISODate(d time.Time) string {
 	return d.In(tzJakarta).Format("2006-01-02")
 }
+
+// Class day with its hours and room when set, e.g.
+// "02 Jan 2006 · 09:00–10:30 · Ruang 3"
+func fmtClassWhen(d time.Time, start, end *time.Time, room string) string {
+	s := fmtDate(d)
+	if start != nil {
+		s += " · " + start.In(tzJakarta).Format("15:04")
+		if end != nil {
+			s += "–" + end.In(tzJakarta).Format("15:04")
+		}
+	}
+	if room != "" {
+		s += " · " + room
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+	svc "github.com/lojf/nextgen/internal/services"
+)
+
+type walkinClass struct {
+	ID      uint
+	Name    string
+	Left    int  // seats left, walk-in seats included
+	OnlyOut bool // capacity is full; only walk-in seats remain
+}
+
+type walkinVM struct {
+	Title     string
+	StaffName string
+	Phone     string
+	Looked    bool // a phone was entered; Parent says whether it matched
+	Parent    *models.Parent
+	Children  []models.Child
+	Classes   []walkinClass
+	Flash     *Flash
+}
+
+// walkinClasses lists today's classes at the volunteer's campus with the
+// seats a walk-in can still take.
+func walkinClasses(u *models.AdminUser) ([]walkinClass, error) {
+	var scope *uint
+	if u != nil {
+		scope = u.CampusID
+	}
+	start, end := todayWindowIn(campusLoc(scope))
+
+	type row struct {
+		ID          uint
+		Name        string
+		Capacity    int
+		WalkInSeats int
+		Confirmed   int
+	}
+	var rows []row
+	q := db.Conn().Table("classes").
+		Select(`classes.id, classes.name, classes.capacity, classes.walk_in_seats,
+		        COUNT(registrations.id) AS confirmed`).
+		Joins(`LEFT JOIN registrations ON registrations.class_id = classes.id
+		       AND registrations.status = ? AND registrations.deleted_at IS NULL`, svc.StatusConfirmed).
+		Where("classes.deleted_at IS NULL").
+		Where("classes.date BETWEEN ? AND ?", start, end).
+		Group("classes.id, classes.name, classes.capacity, classes.walk_in_seats").
+		Order("classes.name ASC")
+	if scope != nil {
+		q = q.Where("classes.campus_id = ?", *scope)
+	}
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]walkinClass, 0, len(rows))
+	for _, rw := range rows {
+		out = append(out, walkinClass{
+			ID:      rw.ID,
+			Name:    rw.Name,
+			Left:    rw.Capacity + rw.WalkInSeats - rw.Confirmed,
+			OnlyOut: rw.Confirmed >= rw.Capacity,
+		})
+	}
+	return out, nil
+}
+
+// GET /station/walkin?phone= — register and check in a family at the door.
+// The phone is looked up first so a known family only picks a child.
+func WalkinForm(t *template.Template) http.HandlerFunc {
+	view := template.Must(t.Clone())
+	template.Must(view.ParseFiles("templates/pages/admin/walkin.tmpl"))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		classes, err := walkinClasses(CurrentUser(r))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		vm := walkinVM{
+			Title:     "Walk-in",
+			StaffName: StaffName(r),
+			Phone:     svc.NormPhone(r.URL.Query().Get("phone")),
+			Classes:   classes,
+			Flash:     MakeFlash(r, "", ""),
+		}
+		if vm.Phone != "" {
+			vm.Looked = true
+			if p, err := svc.FindParentByAny(vm.Phone); err == nil {
+				vm.Parent = p
+				vm.Children = svc.HouseholdChildren(p.HouseholdID)
+			}
+		}
+		if err := view.ExecuteTemplate(w, "admin/walkin.tmpl", vm); err != nil {
+			http.Error(w, err.Error(), 500)
+		}
+	}
+}
+
+// POST /station/walkin
+func WalkinSubmit(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	phone := svc.NormPhone(r.FormValue("phone"))
+	back := func(reason string) {
+		http.Redirect(w, r, "/station/walkin?phone="+url.QueryEscape(phone)+"&error="+reason, http.StatusSeeOther)
+	}
+
+	classID, _ := strconv.Atoi(r.FormValue("class_id"))
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, classID).Error; err != nil {
+		back("class_not_found")
+		return
+	}
+	if err := guardCheckinAt(CurrentUser(r), class, time.Now()); err != nil {
+		writeAudit(r, nil, "registration.walkin.denied", "class:"+class.Name, err.Error())
+		back(checkinDenied(err))
+		return
+	}
+
+	in := svc.WalkIn{
+		Phone:      phone,
+		ParentName: r.FormValue("parent_name"),
+		ClassID:    class.ID,
+		Code:       generateRegCode(),
+	}
+	if id, err := strconv.Atoi(r.FormValue("child_id")); err == nil && id > 0 {
+		in.ChildID = uint(id)
+	} else {
+		in.ChildName = r.FormValue("child_name")
+		in.Gender = normGender(r.FormValue("gender"))
+		if d, err := time.ParseInLocation("2006-01-02", r.FormValue("birthdate"), rosterLoc); err == nil {
+			in.BirthDate = d
+		}
+	}
+	if in.Code == "" {
+		http.Error(w, "failed to generate code", 500)
+		return
+	}
+
+	before := roomBefore(class)
+	res, err := svc.CheckInWalkIn(in, staffActor(r))
+	switch {
+	case errors.Is(err, svc.ErrWalkInMissing):
+		back("walkin_missing")
+		return
+	case errors.Is(err, svc.ErrWalkInFull):
+		back("walkin_full")
+		return
+	case errors.Is(err, svc.ErrWalkInChild):
+		back("walkin_child")
+		return
+	case errors.Is(err, svc.ErrDuplicateReg):
+		back("already_registered")
+		return
+	case errors.Is(err, svc.ErrSameDayReg):
+		back("same_day_conflict")
+		return
+	case errors.Is(err, svc.ErrAgeIneligible):
+		back("walkin_age")
+		return
+	case err != nil:
+		http.Error(w, "db error", 500)
+		return
+	}
+
+	var made []string
+	if res.NewParent {
+		made = append(made, "new parent "+res.Parent.Phone)
+	}
+	if res.NewChild {
+		made = append(made, "new child "+res.Child.Name)
+	}
+	detail := "class:" + class.Name
+	if len(made) > 0 {
+		detail += "; " + strings.Join(made, ", ")
+	}
+	writeAudit(r, nil, "registration.walkin", regTarget(res.Registration), detail)
+	writeAudit(r, nil, "registration.checkin", regTarget(res.Registration), "class:"+class.Name)
+	var browser []uint
+	if !printCheckinLabels(res.Registration) {
+		browser = append(browser, res.Registration.ID)
+	}
+	if ratioAlert(r, class, before) {
+		http.Redirect(w, r, "/station?warn=ratio_over"+printQuery(browser), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/station?ok=walkin_done"+printQuery(browser), http.StatusSeeOther)
+}
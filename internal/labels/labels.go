@@ -0,0 +1,102 @@
+// Package labels renders the check-in name tag and its matching parent stub
+// for the browser (PDF) and for thermal printers on the station LAN (ZPL and
+// ESC/POS). It knows nothing about the database; handlers fill in Label.
+package labels
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Kind tells the child's tag from the stub the parent keeps for pickup.
+type Kind string
+
+const (
+	KindChild  Kind = "child"
+	KindParent Kind = "parent"
+)
+
+// Label is one printed label. A check-in prints two with the same
+// SecurityCode: KindChild goes on the child, KindParent to the parent.
+type Label struct {
+	Kind         Kind
+	ChildName    string
+	ClassName    string
+	DateStr      string
+	SecurityCode string
+	Flags        []string // allergy and medical alerts, printed loud
+}
+
+// Pair is the child tag and parent stub for one check-in.
+func Pair(l Label) []Label {
+	child, parent := l, l
+	child.Kind, parent.Kind = KindChild, KindParent
+	return []Label{child, parent}
+}
+
+// lines is the text of a label, top to bottom, shared by every format.
+func (l Label) lines() (title string, body []string) {
+	if l.Kind == KindParent {
+		body = append(body, "PICKUP STUB - "+l.ChildName)
+	} else {
+		title = l.ChildName
+	}
+	body = append(body, l.ClassName+"  "+l.DateStr)
+	for _, f := range l.Flags {
+		body = append(body, "!! "+f)
+	}
+	return title, body
+}
+
+// Printer is a raw-TCP label printer, the usual port 9100 kind.
+type Printer struct {
+	Addr string // host:port
+	Lang string // "zpl" or "escpos"
+}
+
+// ParsePrinter reads "zpl://10.0.0.5:9100" or "escpos://10.0.0.6:9100".
+// An empty string means no printer.
+func ParsePrinter(s string) (*Printer, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	lang, addr, ok := strings.Cut(s, "://")
+	if !ok || addr == "" || (lang != "zpl" && lang != "escpos") {
+		return nil, fmt.Errorf("label printer %q: want zpl://host:port or escpos://host:port", s)
+	}
+	if !strings.Contains(addr, ":") {
+		addr += ":9100"
+	}
+	return &Printer{Addr: addr, Lang: lang}, nil
+}
+
+// printTimeout bounds a whole send, connecting included: a volunteer is
+// waiting at the door, and a printer that is off should fail fast so the
+// station can print in the browser instead.
+const printTimeout = 2 * time.Second
+
+// Print renders ls in the printer's language and sends it.
+func (p *Printer) Print(ls []Label) error {
+	var b strings.Builder
+	var err error
+	if p.Lang == "zpl" {
+		err = ZPL(&b, ls)
+	} else {
+		err = ESCPOS(&b, ls)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(printTimeout)
+	conn, err := net.DialTimeout("tcp", p.Addr, printTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetWriteDeadline(deadline)
+	_, err = conn.Write([]byte(b.String()))
+	return err
+}
@@ -0,0 +1,78 @@
+package labels
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var sample = Label{
+	ChildName:    "Nadia (Kecil)",
+	ClassName:    "FJB Little Stars",
+	DateStr:      "28 Jun 2026",
+	SecurityCode: "K7PX",
+	Flags:        []string{"ALERGI: kacang"},
+}
+
+func TestPair_SharesTheCode(t *testing.T) {
+	ls := Pair(sample)
+	if len(ls) != 2 || ls[0].Kind != KindChild || ls[1].Kind != KindParent {
+		t.Fatalf("Pair = %+v", ls)
+	}
+	if ls[0].SecurityCode != ls[1].SecurityCode {
+		t.Errorf("codes differ: %q vs %q", ls[0].SecurityCode, ls[1].SecurityCode)
+	}
+}
+
+func TestFormats_CarryCodeClassAndFlags(t *testing.T) {
+	for name, render := range map[string]func(*bytes.Buffer, []Label) error{
+		"pdf":    func(b *bytes.Buffer, ls []Label) error { return PDF(b, ls) },
+		"zpl":    func(b *bytes.Buffer, ls []Label) error { return ZPL(b, ls) },
+		"escpos": func(b *bytes.Buffer, ls []Label) error { return ESCPOS(b, ls) },
+	} {
+		var b bytes.Buffer
+		if err := render(&b, Pair(sample)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		out := b.String()
+		for _, want := range []string{"K7PX", "FJB Little Stars", "ALERGI: kacang", "PICKUP STUB"} {
+			if strings.Count(out, want) < 1 {
+				t.Errorf("%s output lacks %q", name, want)
+			}
+		}
+		if strings.Count(out, "K7PX") != 2 {
+			t.Errorf("%s: code printed %d times, want once per label", name, strings.Count(out, "K7PX"))
+		}
+	}
+}
+
+func TestPDF_IsWellFormed(t *testing.T) {
+	var b bytes.Buffer
+	if err := PDF(&b, Pair(sample)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Errorf("missing PDF header or trailer")
+	}
+	if !strings.Contains(out, "/Count 2") {
+		t.Errorf("want two pages")
+	}
+	// Parentheses in names must be escaped inside PDF strings.
+	if !strings.Contains(out, `Nadia \(Kecil\)`) {
+		t.Errorf("parentheses not escaped")
+	}
+}
+
+func TestParsePrinter(t *testing.T) {
+	if p, err := ParsePrinter(""); p != nil || err != nil {
+		t.Errorf("empty = %v, %v", p, err)
+	}
+	p, err := ParsePrinter("zpl://10.0.0.5")
+	if err != nil || p.Lang != "zpl" || p.Addr != "10.0.0.5:9100" {
+		t.Errorf("zpl = %+v, %v", p, err)
+	}
+	if _, err := ParsePrinter("ipp://printer"); err == nil {
+		t.Error("ipp accepted")
+	}
+}
@@ -0,0 +1,99 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Label stock is 4 x 2 inches, in PDF points.
+const (
+	pageW = 288
+	pageH = 144
+)
+
+// PDF writes one page per label, sized for 4x2" stock, using the built-in
+// Helvetica so the file needs no embedded fonts.
+func PDF(w io.Writer, ls []Label) error {
+	var objs []string
+	add := func(s string) int { objs = append(objs, s); return len(objs) }
+
+	catalog := add("") // filled in once the page tree is known
+	pagesID := add("")
+	font := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	bold := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	var kids []string
+	for _, l := range ls {
+		content := pageContent(l)
+		stream := add(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		page := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, pageW, pageH, font, bold, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	objs[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID)
+	objs[pagesID-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, catalog, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func pageContent(l Label) string {
+	var b strings.Builder
+	text := func(font string, size, x, y int, s string) {
+		fmt.Fprintf(&b, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+	}
+	title, body := l.lines()
+	y := pageH - 34
+	if title != "" {
+		text("F2", 22, 12, y, title)
+		y -= 24
+	}
+	for _, line := range body {
+		size := 10
+		if strings.HasPrefix(line, "!! ") {
+			size = 11
+		}
+		text("F1", size, 12, y, line)
+		y -= size + 3
+	}
+	// The security code sits in the bottom-right corner of both labels,
+	// large enough to compare across a counter.
+	text("F2", 28, pageW-110, 12, l.SecurityCode)
+	return b.String()
+}
+
+// pdfString escapes a string for a PDF literal. Helvetica here is WinAnsi, so
+// anything outside Latin-1 prints as "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		case r > 126:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package labels
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ZPL writes labels for Zebra-style printers at 203 dpi on 4x2" stock.
+func ZPL(w io.Writer, ls []Label) error {
+	var b strings.Builder
+	for _, l := range ls {
+		title, body := l.lines()
+		b.WriteString("^XA^CI28^PW812^LL406\n")
+		y := 30
+		if title != "" {
+			fmt.Fprintf(&b, "^FO30,%d^A0N,70,70^FD%s^FS\n", y, zplText(title))
+			y += 85
+		}
+		for _, line := range body {
+			fmt.Fprintf(&b, "^FO30,%d^A0N,32,32^FD%s^FS\n", y, zplText(line))
+			y += 40
+		}
+		fmt.Fprintf(&b, "^FO560,300^A0N,90,90^FD%s^FS\n", zplText(l.SecurityCode))
+		b.WriteString("^XZ\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zplText drops the characters ZPL treats as commands.
+func zplText(s string) string {
+	return strings.NewReplacer("^", " ", "~", " ").Replace(s)
+}
+
+// ESC/POS control sequences used below.
+const (
+	escInit     = "\x1b@"
+	escBoldOn   = "\x1bE\x01"
+	escBoldOff  = "\x1bE\x00"
+	gsSizeBig   = "\x1d!\x11" // double width and height
+	gsSizeNorm  = "\x1d!\x00"
+	gsCutAfter  = "\x1dV\x42\x03" // feed 3 lines, partial cut
+	escAlignCtr = "\x1ba\x01"
+	escAlignLft = "\x1ba\x00"
+)
+
+// ESCPOS writes labels for receipt-style thermal printers, one cut per label.
+func ESCPOS(w io.Writer, ls []Label) error {
+	var b strings.Builder
+	b.WriteString(escInit)
+	for _, l := range ls {
+		title, body := l.lines()
+		b.WriteString(escAlignLft)
+		if title != "" {
+			b.WriteString(escBoldOn + gsSizeBig + asciiText(title) + "\n" + gsSizeNorm + escBoldOff)
+		}
+		for _, line := range body {
+			if strings.HasPrefix(line, "!! ") {
+				b.WriteString(escBoldOn + asciiText(line) + escBoldOff + "\n")
+				continue
+			}
+			b.WriteString(asciiText(line) + "\n")
+		}
+		b.WriteString(escAlignCtr + escBoldOn + gsSizeBig + asciiText(l.SecurityCode) + "\n" + gsSizeNorm + escBoldOff)
+		b.WriteString(gsCutAfter)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// asciiText keeps printable ASCII; code pages differ between printers, so
+// anything else prints as "?".
+func asciiText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -15,15 +15,16 @@ const (
 //   - RoleCheckin accounts are generic and shared per campus (e.g. "fjb-checkin"),
 //     because volunteers rotate weekly and cannot wait for provisioning.
 //
-// Campus is only meaningful for RoleCheckin: it pins the account to classes whose
-// name starts with that prefix (see CampusOf). Empty campus = all campuses.
+// CampusID is only meaningful for RoleCheckin: it pins the account to classes
+// with the same Class.CampusID. Nil = all campuses.
 type AdminUser struct {
-	ID        uint   `gorm:"primaryKey"`
-	Username  string `gorm:"uniqueIndex;not null"`
-	PassHash  string `gorm:"not null"`
-	Role      string `gorm:"index;not null"`
-	Campus    string `gorm:"index"` // "FJB", "FJU", or "" for all
-	Active    bool   `gorm:"not null;default:true"`
+	ID        uint    `gorm:"primaryKey"`
+	Username  string  `gorm:"uniqueIndex;not null"`
+	PassHash  string  `gorm:"not null"`
+	Role      string  `gorm:"index;not null"`
+	CampusID  *uint   `gorm:"index"` // nil = all campuses
+	Campus    *Campus `gorm:"foreignKey:CampusID"`
+	Active    bool    `gorm:"not null;default:true"`
 	LastLogin *time.Time
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -49,7 +50,8 @@ type AuditLog struct {
 }
 
 // AppSetting is a tiny key/value store for values that must survive restarts but
-// do not deserve their own table (currently just the session signing secret).
+// do not deserve their own table: the session signing secret and the no-show
+// policy.
 type AppSetting struct {
 	Key       string `gorm:"primaryKey"`
 	Value     string
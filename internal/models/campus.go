@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Campus is a physical service location. Classes and check-in accounts point at
+// it by foreign key, so a typo in a class name can no longer move the class to
+// another campus.
+type Campus struct {
+	ID       uint   `gorm:"primaryKey"`
+	Code     string `gorm:"uniqueIndex;not null"` // short code people type: "FJB", "FJU"
+	Name     string // "Feast Jakarta Barat"
+	Address  string
+	TimeZone string `gorm:"not null;default:Asia/Jakarta"` // IANA name
+	// LabelPrinter is the campus's LAN label printer, zpl://host:port or
+	// escpos://host:port; blank means stations print labels in the browser.
+	LabelPrinter string `gorm:"not null;default:''"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TableName pins the plural; GORM's inflector treats "campus" as uncountable.
+func (Campus) TableName() string { return "campuses" }
+
+// Location returns the campus time zone, falling back to Jakarta when the name
+// is empty or unknown to the host's tzdata.
+func (c Campus) Location() *time.Location {
+	if c.TimeZone != "" {
+		if loc, err := time.LoadLocation(c.TimeZone); err == nil {
+			return loc
+		}
+	}
+	if loc, err := time.LoadLocation("Asia/Jakarta"); err == nil {
+		return loc
+	}
+	return time.FixedZone("WIB", 7*3600)
+}
+
+// Label is what the UI shows: "FJB — Feast Jakarta Barat", or just the code.
+func (c Campus) Label() string {
+	if c.Name == "" || strings.EqualFold(c.Name, c.Code) {
+		return c.Code
+	}
+	return c.Code + " — " + c.Name
+}
+
+// CampusCodeFromClassName derives a campus code the way it was done before
+// classes had a campus column: the first whitespace-separated token of the name.
+//
+//	"FJB Little Stars (Feast Jakarta Barat)"  -> "FJB"
+//	"FJB - Stars Club (Feast Jakarta Barat)"  -> "FJB"
+//	"FJU Awesome Kids (Feast Jakarta Utara)"  -> "FJU"
+//
+// It only survives for the one-off backfill. New classes name their campus
+// explicitly, and scoping decisions use Class.CampusID.
+func CampusCodeFromClassName(className string) string {
+	f := strings.Fields(strings.TrimSpace(className))
+	if len(f) == 0 {
+		return ""
+	}
+	return strings.ToUpper(f[0])
+}
+
+// CampusNameFromClassName returns the trailing parenthetical of a class name
+// ("... (Feast Jakarta Barat)" -> "Feast Jakarta Barat"), or "".
+func CampusNameFromClassName(className string) string {
+	s := strings.TrimSpace(className)
+	if !strings.HasSuffix(s, ")") {
+		return ""
+	}
+	i := strings.LastIndex(s, "(")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(s[i+1 : len(s)-1])
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ClassSeries is a class that meets every IntervalWeeks weeks from FirstDate,
+// skipping Blackouts. Its sessions are ordinary Class rows (Class.SeriesID)
+// generated a few weeks ahead, each with its own seats and waitlist; the
+// series holds what they share, questions included (ClassQuestion.SeriesID).
+type ClassSeries struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	Description string  `gorm:"type:text"`
+	CampusID    *uint   `gorm:"index"`
+	Campus      *Campus `gorm:"foreignKey:CampusID"`
+
+	// FirstDate is the campus midnight of the first session; its weekday
+	// is the series'.
+	FirstDate     time.Time
+	IntervalWeeks int `gorm:"not null;default:1"`
+	// StartTime/EndTime are "15:04" at the campus; blank means all day.
+	StartTime string `gorm:"size:5"`
+	EndTime   string `gorm:"size:5"`
+	// Blackouts are the "2006-01-02" dates with no session, comma-separated.
+	Blackouts string `gorm:"type:text"`
+
+	Room         string
+	Capacity     int
+	WalkInSeats  int
+	KidsPerAdult int `gorm:"not null;default:0"`
+	MinAge       *int
+	MaxAge       *int
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (ClassSeries) TableName() string { return "class_series" }
+
+// Location is the series' campus time zone (Jakarta when Campus is not
+// loaded). Its dates and times are read there.
+func (s ClassSeries) Location() *time.Location {
+	if s.Campus != nil {
+		return s.Campus.Location()
+	}
+	return Campus{}.Location()
+}
+
+// Weekday is the day of the week the series meets on, at its campus.
+func (s ClassSeries) Weekday() time.Weekday {
+	return s.FirstDate.In(s.Location()).Weekday()
+}
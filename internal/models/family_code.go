@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FamilyCode is one guardian's check-in code for one day. Scanning it at the
+// station brings up every child of the household registered that day, so a
+// parent with three kids shows one QR instead of three.
+type FamilyCode struct {
+	ID          uint   `gorm:"primaryKey"`
+	Code        string `gorm:"uniqueIndex;not null"` // FAM-XXXXXXXX
+	ParentID    uint   `gorm:"uniqueIndex:idx_family_codes_parent_day;not null"`
+	Day         string `gorm:"uniqueIndex:idx_family_codes_parent_day;not null"` // YYYY-MM-DD, Jakarta
+	HouseholdID uint   `gorm:"index;not null"`
+	CreatedAt   time.Time
+}
@@ -1,28 +1,58 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"time"
 
-type Parent struct {
+	"gorm.io/gorm"
+)
+
+// Parent, Child, Class and Registration are soft-deleted: DeletedAt hides the
+// row from every query until it is restored or purged from /admin/trash.
+
+// Household is a family: the children, their registrations, and every guardian
+// who may sign them up, check them in or get their reminders.
+type Household struct {
 	ID        uint `gorm:"primaryKey"`
+	Name      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
+	Guardians []Parent `gorm:"foreignKey:HouseholdID"`
+}
+
+// Parent is one guardian of a household. Each has their own phone, which is
+// what they log in with, and their own Telegram link.
+type Parent struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	HouseholdID uint           `gorm:"index"`
+
 	Name  string
-	Phone string `gorm:"uniqueIndex;not null"` // unique parent identity
+	Phone string `gorm:"uniqueIndex:idx_parents_phone,where:deleted_at IS NULL;not null"` // unique among live parents
 	Email string
 
 	Children []Child
 }
 
+// Child belongs to a household. ParentID is the guardian it is filed under
+// (whoever added it, moved to another guardian if that one is deleted).
 type Child struct {
-	ID        uint      `gorm:"primaryKey"`
-	ParentID  uint      `gorm:"index"`
+	ID          uint `gorm:"primaryKey"`
+	HouseholdID uint `gorm:"index"`
+	ParentID    uint `gorm:"index"`
 	Name      string
 	BirthDate time.Time
 	// NEW:
 	Gender    string     // "", "Boy", "Girl", "Other" (free text allowed)
+	// Printed on the check-in name tag; empty means nothing to flag.
+	Allergies    string
+	MedicalNotes string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type Class struct {
@@ -30,24 +60,99 @@ type Class struct {
 	Name      string
 	Date      time.Time
 	Capacity  int
+	// WalkInSeats is how many children the station may still take at the
+	// door once Capacity is full. Online sign-ups never get these seats.
+	WalkInSeats int
+	// CampusID is the authoritative campus; nil means "not assigned yet" and
+	// such a class is invisible to campus-scoped check-in accounts.
+	CampusID  *uint      `gorm:"index"`
+	Campus    *Campus    `gorm:"foreignKey:CampusID"`
 	// NEW:
 	Description    string       `gorm:"type:text"`
 	SignupOpensAt  *time.Time   // nil = open now
-
+	// StartsAt/EndsAt are when the session runs; Date stays the calendar
+	// day. Classes made before times were recorded have neither and count
+	// as lasting all day.
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	Room     string // where it meets, e.g. "Ruang 3, lantai 2"
+	// KidsPerAdult is the most checked-in children one volunteer in the
+	// room may look after; 0 means no ratio is enforced.
+	KidsPerAdult int `gorm:"not null;default:0"`
+	// SeriesID is the ClassSeries this session was generated from, if any.
+	SeriesID *uint `gorm:"index"`
+	// MinAge/MaxAge bound the child's age in whole years on the class day,
+	// both inclusive; nil is no limit.
+	MinAge *int
+	MaxAge *int
+	// WaitlistPolicy names how the waitlist is ordered (see
+	// services.WaitlistPolicies); blank is first come, first served.
+	WaitlistPolicy string `gorm:"not null;default:''"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Start is when the class begins: StartsAt, or the start of its day.
+func (c Class) Start() time.Time {
+	if c.StartsAt != nil {
+		return *c.StartsAt
+	}
+	return c.Date
+}
+
+// End is when the class finishes: EndsAt, or the end of its day at its
+// campus (Jakarta when Campus is not loaded).
+func (c Class) End() time.Time {
+	if c.EndsAt != nil {
+		return *c.EndsAt
+	}
+	loc := Campus{}.Location()
+	if c.Campus != nil {
+		loc = c.Campus.Location()
+	}
+	d := c.Date.In(loc)
+	return time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// AgeRange describes the class's ages for parents: "4–6", "4+", "up to 6",
+// or "" when any age may come.
+func (c Class) AgeRange() string {
+	return AgeRange(c.MinAge, c.MaxAge)
+}
+
+// AgeRange describes an age limit pair the way Class.AgeRange does.
+func AgeRange(min, max *int) string {
+	switch {
+	case min != nil && max != nil:
+		return strconv.Itoa(*min) + "–" + strconv.Itoa(*max)
+	case min != nil:
+		return strconv.Itoa(*min) + "+"
+	case max != nil:
+		return "up to " + strconv.Itoa(*max)
+	}
+	return ""
+}
+
+// Overlaps reports whether the two classes run at the same time.
+func (c Class) Overlaps(o Class) bool {
+	return c.Start().Before(o.End()) && o.Start().Before(c.End())
 }
 
-// Status: "confirmed", "waitlisted", "canceled"
+// Status: "confirmed", "waitlisted", "canceled", "no_show". Changes go through
+// the state machine in services (services.Status, services.Transition), never
+// a bare Save.
 type Registration struct {
 	ID        uint `gorm:"primaryKey"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
-	ParentID uint
-	ChildID  uint
-	ClassID  uint
+	HouseholdID uint `gorm:"index"`
+	ParentID    uint
+	ChildID     uint
+	ClassID     uint
 
 	Status    string     // confirmed | waitlisted | canceled
 	Code      string     `gorm:"uniqueIndex"` // e.g., REG-123456
@@ -56,12 +161,26 @@ type Registration struct {
 	// accounts it holds the volunteer's self-declared shift name, otherwise the
 	// admin username. Empty for rows checked in before this column existed.
 	CheckedInBy string
+	// SecurityCode is drawn at check-in and printed on both the child's tag
+	// and the parent's stub; check-out asks for it. Cleared on undo.
+	SecurityCode string
+
+	// Check-out: when the child left, which volunteer released them, and to
+	// whom (a guardian or an authorized pickup person, as shown to staff).
+	CheckOutAt   *time.Time
+	CheckedOutBy string
+	PickedUpBy   string
+
+	// AgeOverrideBy names the admin who registered a child outside the
+	// class's ages; empty for everyone else.
+	AgeOverrideBy string
 }
 
 type ClassQuestion struct {
 	ID         uint   `gorm:"primaryKey"`
 	ClassID    *uint  `gorm:"index"`  // pointer (nil if it belongs to a template)
 	TemplateID *uint  `gorm:"index"`  // pointer (nil if it belongs to a class)
+	SeriesID   *uint  `gorm:"index"`  // set when shared by every session of a series
 
 	Label    string
 	Kind     string // "text" | "radio"
@@ -77,6 +196,8 @@ type ClassTemplate struct {
 	ID          uint      `gorm:"primaryKey"`
 	Name        string    `gorm:"size:200;not null"`
 	Description string    `gorm:"type:text"`
+	MinAge      *int // copied to classes made from the template
+	MaxAge      *int
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	Questions   []ClassTemplateQuestion `gorm:"foreignKey:TemplateID"`
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// PickupPerson is someone other than the household's guardians whom a child
+// may be released to at check-out, or, with DoNotRelease set, must not be.
+// Guardians are always allowed and are not listed here.
+type PickupPerson struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	ChildID  uint   `gorm:"index;not null"`
+	Name     string `gorm:"not null"`
+	Relation string // "grandmother", "driver", ...
+	Phone    string
+
+	// Photo is a small JPEG/PNG the volunteer compares against the face at
+	// the door; PhotoType is its content type, empty when there is none.
+	Photo     []byte `json:"-"`
+	PhotoType string
+
+	// DoNotRelease flags a person the child must never be handed to; the
+	// station shows them as a warning instead of a choice.
+	DoNotRelease bool `gorm:"not null;default:false"`
+	Note         string
+}
+
+// Label is how a pickup person appears on the station and in the history.
+func (p PickupPerson) Label() string {
+	if p.Relation == "" {
+		return p.Name
+	}
+	return p.Name + " (" + p.Relation + ")"
+}
+
+func (PickupPerson) TableName() string { return "pickup_persons" }
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RegistrationEvent kinds. A registration's history is the ordered list of
+// these; Registration.Status only ever shows the last one that stuck.
+const (
+	EventCreated       = "created"
+	EventConfirmed     = "confirmed"
+	EventWaitlisted    = "waitlisted"
+	EventPromoted      = "promoted" // waitlist -> confirmed when a seat frees up
+	EventCanceled      = "canceled"
+	EventCheckedIn     = "checked_in"
+	EventCheckinUndone = "checkin_undone"
+	EventCheckedOut    = "checked_out" // released to a guardian or pickup person
+	EventNoShow        = "no_show"     // confirmed but never checked in by the end of the class day
+	EventDeleted       = "deleted"     // moved to the trash
+	EventRestored      = "restored"
+)
+
+// RegistrationEvent sources: where the change came from.
+const (
+	SourceWeb       = "web"
+	SourceTelegram  = "telegram"
+	SourceRecompute = "recompute" // capacity rebalancing, no person involved
+)
+
+// RegistrationEvent is one transition in a registration's life, kept so the
+// admin can answer "when was this kid promoted" or "who canceled this".
+//
+// Actor is free text on purpose, like AuditLog.StaffedBy: a parent's phone, an
+// admin username, a volunteer's shift name, or "system".
+type RegistrationEvent struct {
+	ID             uint      `gorm:"primaryKey"`
+	RegistrationID uint      `gorm:"index;not null"`
+	CreatedAt      time.Time `gorm:"index"`
+
+	Kind   string `gorm:"not null"`
+	Actor  string
+	Source string
+	Note   string
+}
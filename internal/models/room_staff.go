@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RoomStaff is one volunteer's stint in a class's room, named by the shift
+// name they gave the station (see AuditLog.StaffedBy). OutAt is nil while
+// they are still in the room; the ratio check counts those rows.
+type RoomStaff struct {
+	ID      uint   `gorm:"primaryKey"`
+	ClassID uint   `gorm:"index;not null"`
+	Name    string `gorm:"not null"`
+	InAt    time.Time
+	OutAt   *time.Time `gorm:"index"`
+	AddedBy string     // who recorded it, usually the same shift name
+}
+
+func (RoomStaff) TableName() string { return "room_staff" }
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Station sync outcomes. A replayed key gets the outcome it got the first time.
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+)
+
+// StationSync is one check-in a station recorded while offline and sent later,
+// remembered under the idempotency key the device made up for it so that a
+// batch resent after a dropped reply is applied once. Keys are unique per
+// station account, not globally.
+type StationSync struct {
+	ID             uint      `gorm:"primaryKey"`
+	AccountID      uint      `gorm:"uniqueIndex:idx_station_syncs_account_key;not null;default:0"` // the AdminUser that uploaded it
+	IdemKey        string    `gorm:"uniqueIndex:idx_station_syncs_account_key;not null"`
+	RegistrationID uint      `gorm:"index"`
+	Device         string    // free text from the station, for the audit trail
+	ClientAt       time.Time // when the device says the child came in
+	Outcome        string    // SyncApplied | SyncConflict
+	Reason         string    // why a conflict was one
+	CreatedAt      time.Time
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// ErrAgeIneligible matches every *AgeError.
+var ErrAgeIneligible = errors.New("child's age is outside the class's range")
+
+// AgeError says why a child's age keeps them out of a class.
+type AgeError struct {
+	Child string
+	Age   int // in whole years on the class day
+	Range string
+}
+
+func (e *AgeError) Error() string {
+	return fmt.Sprintf("%s will be %d on the class day; this class is for ages %s", e.Child, e.Age, e.Range)
+}
+
+func (e *AgeError) Is(target error) bool { return target == ErrAgeIneligible }
+
+// AgeOn is how old someone born on birth is on day, in whole years, both
+// read as Jakarta dates.
+func AgeOn(birth, day time.Time) int {
+	loc := models.Campus{}.Location()
+	b, d := birth.In(loc), day.In(loc)
+	age := d.Year() - b.Year()
+	if d.Month() < b.Month() || (d.Month() == b.Month() && d.Day() < b.Day()) {
+		age--
+	}
+	return age
+}
+
+// CheckAge returns an *AgeError when child is too young or too old for
+// class on its date. A child without a birth date on file is let through.
+func CheckAge(child models.Child, class models.Class) error {
+	if child.BirthDate.IsZero() || (class.MinAge == nil && class.MaxAge == nil) {
+		return nil
+	}
+	age := AgeOn(child.BirthDate, class.Date)
+	if (class.MinAge != nil && age < *class.MinAge) || (class.MaxAge != nil && age > *class.MaxAge) {
+		return &AgeError{Child: child.Name, Age: age, Range: class.AgeRange()}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestAgeOn_CountsBirthdaysInJakarta(t *testing.T) {
+	loc := models.Campus{}.Location()
+	birth := time.Date(2020, 3, 15, 0, 0, 0, 0, loc)
+	cases := []struct {
+		day  time.Time
+		want int
+	}{
+		{time.Date(2026, 3, 14, 0, 0, 0, 0, loc), 5},
+		{time.Date(2026, 3, 15, 0, 0, 0, 0, loc), 6},
+		// 14 Mar 18:00 UTC is already the 15th in Jakarta.
+		{time.Date(2026, 3, 14, 18, 0, 0, 0, time.UTC), 6},
+	}
+	for _, c := range cases {
+		if got := AgeOn(birth, c.day); got != c.want {
+			t.Errorf("AgeOn(%s) = %d, want %d", c.day, got, c.want)
+		}
+	}
+}
+
+func TestCheckRegistrationConflicts_RejectsOutsideAges(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	four, six := 4, 6
+	class := models.Class{Name: "FJB Tots", Date: time.Now().AddDate(0, 0, 10), Capacity: 10, MinAge: &four, MaxAge: &six}
+	conn.Create(&class)
+
+	// No birth date on file: let through.
+	if err := CheckRegistrationConflicts(f.kid.ID, class.ID); err != nil {
+		t.Fatalf("no birth date: %v", err)
+	}
+
+	conn.Model(&f.kid).Update("birth_date", time.Now().AddDate(-8, 0, 0))
+	err := CheckRegistrationConflicts(f.kid.ID, class.ID)
+	var ae *AgeError
+	if !errors.Is(err, ErrAgeIneligible) || !errors.As(err, &ae) || ae.Age != 8 || ae.Range != "4–6" {
+		t.Fatalf("8-year-old: err = %v, want an age error for 8 in 4–6", err)
+	}
+	if _, err := Register(f.kid.ID, class.ID, nil, admin); !errors.Is(err, ErrAgeIneligible) {
+		t.Errorf("Register: err = %v, want ErrAgeIneligible", err)
+	}
+
+	conn.Model(&f.kid).Update("birth_date", time.Now().AddDate(-5, 0, 0))
+	if err := CheckRegistrationConflicts(f.kid.ID, class.ID); err != nil {
+		t.Errorf("5-year-old: %v", err)
+	}
+}
+
+// An admin may waive the ages for one registration; the registration
+// remembers who did, and nothing else is waived with it.
+func TestAdminRegister_OverridesAgeOnly(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	four, six := 4, 6
+	class := models.Class{Name: "FJB Tots", Date: time.Now().AddDate(0, 0, 10), Capacity: 10, MinAge: &four, MaxAge: &six}
+	conn.Create(&class)
+	conn.Model(&f.kid).Update("birth_date", time.Now().AddDate(-8, 0, 0))
+
+	if _, err := AdminRegister(f.kid.ID, class.ID, false, admin); !errors.Is(err, ErrAgeIneligible) {
+		t.Fatalf("without override: err = %v, want ErrAgeIneligible", err)
+	}
+	res, err := AdminRegister(f.kid.ID, class.ID, true, admin)
+	if err != nil {
+		t.Fatalf("with override: %v", err)
+	}
+	if res.Status != StatusConfirmed || res.Registration.AgeOverrideBy != admin.Name {
+		t.Errorf("got %s by %q, want confirmed by %q", res.Status, res.Registration.AgeOverrideBy, admin.Name)
+	}
+	if _, err := AdminRegister(f.kid.ID, class.ID, true, admin); !errors.Is(err, ErrDuplicateReg) {
+		t.Errorf("again: err = %v, want ErrDuplicateReg", err)
+	}
+
+	// Inside the ages there is nothing to override, so nothing is recorded.
+	sib := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID, BirthDate: time.Now().AddDate(-5, 0, 0)}
+	conn.Create(&sib)
+	res, err = AdminRegister(sib.ID, class.ID, true, admin)
+	if err != nil || res.Registration.AgeOverrideBy != "" {
+		t.Errorf("in range: err = %v, override by %q, want none", err, res.Registration.AgeOverrideBy)
+	}
+}
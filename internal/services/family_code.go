@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// FamilyCodePrefix tells a family code from a registration code (REG-…) at
+// the scanner.
+const FamilyCodePrefix = "FAM-"
+
+// ErrFamilyCode is returned for a family code that does not exist or belongs
+// to another day.
+var ErrFamilyCode = errors.New("family code not found or not for today")
+
+// IsFamilyCode reports whether a scanned code is a family code.
+func IsFamilyCode(code string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(code)), FamilyCodePrefix)
+}
+
+// FamilyCodeFor returns the parent's family code for day (YYYY-MM-DD in
+// Jakarta), drawing one the first time it is asked for.
+func FamilyCodeFor(parent models.Parent, day string) (string, error) {
+	var fc models.FamilyCode
+	err := db.Conn().Where("parent_id = ? AND day = ?", parent.ID, day).First(&fc).Error
+	if err == nil {
+		return fc.Code, nil
+	}
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	fc = models.FamilyCode{
+		Code:        fmt.Sprintf("%s%08X", FamilyCodePrefix, binary.BigEndian.Uint32(b[:])),
+		ParentID:    parent.ID,
+		HouseholdID: parent.HouseholdID,
+		Day:         day,
+	}
+	if err := db.Conn().Create(&fc).Error; err != nil {
+		return "", err
+	}
+	return fc.Code, nil
+}
+
+// FamilyByCode looks up a family code that is valid on day.
+func FamilyByCode(code, day string) (models.FamilyCode, error) {
+	var fc models.FamilyCode
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if err := db.Conn().Where("code = ? AND day = ?", code, day).First(&fc).Error; err != nil {
+		return fc, ErrFamilyCode
+	}
+	return fc, nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFamilyCodeFor_OnePerGuardianPerDay(t *testing.T) {
+	f := seedTrash(t, 10)
+	other, err := AddGuardian(f.parent.HouseholdID, "Pak Sari", "08122", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := FamilyCodeFor(f.parent, "2026-10-18")
+	if err != nil {
+		t.Fatalf("FamilyCodeFor: %v", err)
+	}
+	if !IsFamilyCode(code) || IsFamilyCode("REG-1234ABCD") {
+		t.Errorf("IsFamilyCode(%q) false or REG code accepted", code)
+	}
+	if again, _ := FamilyCodeFor(f.parent, "2026-10-18"); again != code {
+		t.Errorf("second ask = %q, want the same %q", again, code)
+	}
+	if next, _ := FamilyCodeFor(f.parent, "2026-10-25"); next == code {
+		t.Errorf("next week's code is the same as today's")
+	}
+	theirs, _ := FamilyCodeFor(*other, "2026-10-18")
+	if theirs == code {
+		t.Errorf("the other guardian got the same code")
+	}
+
+	fc, err := FamilyByCode(theirs, "2026-10-18")
+	if err != nil || fc.HouseholdID != f.parent.HouseholdID || fc.ParentID != other.ID {
+		t.Errorf("FamilyByCode = %+v, %v; want the other guardian's household", fc, err)
+	}
+	if _, err := FamilyByCode(code, "2026-10-19"); !errors.Is(err, ErrFamilyCode) {
+		t.Errorf("yesterday's code = %v, want ErrFamilyCode", err)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Actor is who caused a registration change and through which channel; it is
+// copied onto every RegistrationEvent written on their behalf.
+type Actor struct {
+	Name   string // parent phone, admin username, shift name, or "system"
+	Source string // models.SourceWeb, SourceTelegram, SourceRecompute
+}
+
+// System is the actor for changes nobody asked for directly, such as a
+// waitlist promotion after someone else cancels.
+var System = Actor{Name: "system", Source: models.SourceRecompute}
+
+// ParentActor labels a change made by a parent, identified by phone.
+func ParentActor(phone, source string) Actor {
+	name := "parent"
+	if phone != "" {
+		name += " " + phone
+	}
+	return Actor{Name: name, Source: source}
+}
+
+// RecordEvent appends one entry to a registration's history. Call it inside
+// the transaction that makes the change, so the two commit together.
+func RecordEvent(tx *gorm.DB, regID uint, kind string, by Actor, note string) error {
+	return tx.Create(&models.RegistrationEvent{
+		RegistrationID: regID,
+		Kind:           kind,
+		Actor:          by.Name,
+		Source:         by.Source,
+		Note:           note,
+	}).Error
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func eventsOf(t *testing.T, regID uint) []models.RegistrationEvent {
+	t.Helper()
+	var evs []models.RegistrationEvent
+	if err := db.Conn().Where("registration_id = ?", regID).Order("id asc").Find(&evs).Error; err != nil {
+		t.Fatal(err)
+	}
+	return evs
+}
+
+func TestCancelByCode_RecordsCancelAndPromotion(t *testing.T) {
+	f := seedTrash(t, 1)
+	conn := db.Conn()
+	other := models.Child{Name: "Ani", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&other)
+	wait := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: other.ID, ClassID: f.class.ID, Status: "waitlisted", Code: "REG-T2"}
+	conn.Create(&wait)
+
+	parent := ParentActor(f.parent.Phone, models.SourceTelegram)
+	if err := CancelByCode(f.reg.Code, parent); err != nil {
+		t.Fatalf("CancelByCode: %v", err)
+	}
+
+	got := eventsOf(t, f.reg.ID)
+	if len(got) != 1 || got[0].Kind != models.EventCanceled {
+		t.Fatalf("canceled registration history = %+v, want one %q", got, models.EventCanceled)
+	}
+	if got[0].Actor != "parent +628111" || got[0].Source != models.SourceTelegram {
+		t.Errorf("cancel recorded as %q via %q", got[0].Actor, got[0].Source)
+	}
+
+	got = eventsOf(t, wait.ID)
+	if len(got) != 1 || got[0].Kind != models.EventPromoted {
+		t.Fatalf("waitlisted registration history = %+v, want one %q", got, models.EventPromoted)
+	}
+	if got[0].Actor != System.Name || got[0].Source != models.SourceRecompute {
+		t.Errorf("promotion recorded as %q via %q", got[0].Actor, got[0].Source)
+	}
+
+	// Canceling twice changes nothing, so it records nothing either.
+	if err := CancelByCode(f.reg.Code, parent); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(eventsOf(t, f.reg.ID)); n != 1 {
+		t.Errorf("second cancel added events: %d total", n)
+	}
+}
+
+func TestDeleteAndRestore_RecordEvents(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := DeleteChild(f.kid.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(TrashChild, f.kid.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	got := eventsOf(t, f.reg.ID)
+	if len(got) != 2 || got[0].Kind != models.EventDeleted || got[1].Kind != models.EventRestored {
+		t.Fatalf("history = %+v, want deleted then restored", got)
+	}
+	if got[0].Actor != "admin" {
+		t.Errorf("delete recorded as %q, want admin", got[0].Actor)
+	}
+}
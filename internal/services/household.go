@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// A household is the family; parents are its guardians. Anything that belongs
+// to the family (children, registrations, reminders) is looked up by
+// household_id, so whichever guardian's phone comes in, the same family comes
+// out. Parent IDs only say which guardian a row is filed under.
+
+var (
+	ErrGuardianPhoneTaken = errors.New("that phone number already belongs to another family")
+	ErrGuardianExists     = errors.New("that phone number is already a guardian of this family")
+	ErrLastGuardian       = errors.New("a family needs at least one guardian")
+	ErrMissingGuardian    = errors.New("guardian name and phone are required")
+)
+
+// CreateParent saves a new parent. One without a household gets a fresh
+// household of their own, named after them.
+func CreateParent(tx *gorm.DB, p *models.Parent) error {
+	if p.HouseholdID == 0 {
+		h := models.Household{Name: p.Name}
+		if err := tx.Create(&h).Error; err != nil {
+			return err
+		}
+		p.HouseholdID = h.ID
+	}
+	return tx.Create(p).Error
+}
+
+// AddGuardian gives a household another guardian with their own phone.
+func AddGuardian(householdID uint, name, phone, email string) (*models.Parent, error) {
+	phone = NormPhone(phone)
+	name = strings.TrimSpace(name)
+	if phone == "" || name == "" {
+		return nil, ErrMissingGuardian
+	}
+	if existing, err := FindParentByAny(phone); err == nil {
+		if existing.HouseholdID == householdID {
+			return nil, ErrGuardianExists
+		}
+		return nil, ErrGuardianPhoneTaken
+	}
+	p := models.Parent{HouseholdID: householdID, Name: name, Phone: phone, Email: email}
+	if err := CreateParent(db.Conn(), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RemoveGuardian trashes one guardian of a household that has others; the
+// children and registrations filed under them move to a remaining guardian.
+// The last guardian goes through DeleteParent, which takes the family along.
+func RemoveGuardian(householdID, parentID uint, by Actor) error {
+	var p models.Parent
+	if err := db.Conn().Where("id = ? AND household_id = ?", parentID, householdID).First(&p).Error; err != nil {
+		return err
+	}
+	if len(Guardians(householdID)) < 2 {
+		return ErrLastGuardian
+	}
+	return DeleteParent(p.ID, by)
+}
+
+// Guardians lists a household's live guardians, oldest first.
+func Guardians(householdID uint) []models.Parent {
+	var out []models.Parent
+	_ = db.Conn().Where("household_id = ?", householdID).Order("id asc").Find(&out).Error
+	return out
+}
+
+// HouseholdChildren lists a household's live children by name.
+func HouseholdChildren(householdID uint) []models.Child {
+	var out []models.Child
+	_ = db.Conn().Where("household_id = ?", householdID).Order("name asc").Find(&out).Error
+	return out
+}
+
+// otherGuardian returns a live guardian of the household other than parentID,
+// or 0 when parentID is the last one.
+func otherGuardian(tx *gorm.DB, householdID, parentID uint) (uint, error) {
+	if householdID == 0 {
+		return 0, nil
+	}
+	var ids []uint
+	if err := tx.Model(&models.Parent{}).
+		Where("household_id = ? AND id <> ?", householdID, parentID).
+		Order("id asc").Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
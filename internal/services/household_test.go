@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestAddGuardian_SharesTheFamily(t *testing.T) {
+	f := seedTrash(t, 10)
+
+	g, err := AddGuardian(f.parent.HouseholdID, "Pak Joko", "0812 222", "")
+	if err != nil {
+		t.Fatalf("AddGuardian: %v", err)
+	}
+	found, err := FindParentByAny("+62812222")
+	if err != nil || found.ID != g.ID {
+		t.Fatalf("FindParentByAny(second phone) = %v, %v", found, err)
+	}
+	kids := HouseholdChildren(found.HouseholdID)
+	if len(kids) != 1 || kids[0].ID != f.kid.ID {
+		t.Errorf("second guardian sees children %+v, want Budi", kids)
+	}
+
+	if _, err := AddGuardian(f.parent.HouseholdID, "Lagi", g.Phone, ""); !errors.Is(err, ErrGuardianExists) {
+		t.Errorf("re-adding a guardian = %v, want ErrGuardianExists", err)
+	}
+	other := models.Parent{Name: "Other", Phone: "+628333"}
+	if err := CreateParent(db.Conn(), &other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddGuardian(f.parent.HouseholdID, "Other", "08333", ""); !errors.Is(err, ErrGuardianPhoneTaken) {
+		t.Errorf("adding another family's phone = %v, want ErrGuardianPhoneTaken", err)
+	}
+}
+
+func TestRemoveGuardian_HandsTheFamilyOver(t *testing.T) {
+	f := seedTrash(t, 10)
+	g, err := AddGuardian(f.parent.HouseholdID, "Pak Joko", "+628222", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The original guardian leaves; the children and registrations they
+	// filed stay live under the one who remains.
+	if err := RemoveGuardian(f.parent.HouseholdID, f.parent.ID, admin); err != nil {
+		t.Fatalf("RemoveGuardian: %v", err)
+	}
+	var kid models.Child
+	if err := db.Conn().First(&kid, f.kid.ID).Error; err != nil {
+		t.Fatalf("child gone with its first guardian: %v", err)
+	}
+	if kid.ParentID != g.ID {
+		t.Errorf("child parent_id = %d, want %d", kid.ParentID, g.ID)
+	}
+	var reg models.Registration
+	if err := db.Conn().First(&reg, f.reg.ID).Error; err != nil || reg.ParentID != g.ID {
+		t.Errorf("registration after removal = %+v, %v", reg, err)
+	}
+
+	if err := RemoveGuardian(f.parent.HouseholdID, g.ID, admin); !errors.Is(err, ErrLastGuardian) {
+		t.Errorf("removing the last guardian = %v, want ErrLastGuardian", err)
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/events"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// publishChange tells watching stations that reg just went through kind. Call
+// it after the transaction commits, never inside one: it reads through
+// db.Conn().
+func publishChange(reg models.Registration, kind string, by Actor) {
+	var class models.Class
+	if err := db.Conn().Unscoped().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		return
+	}
+	var campus models.Campus
+	if class.Campus != nil {
+		campus = *class.Campus
+	}
+	events.Publish(events.Change{
+		Kind:     kind,
+		RegID:    reg.ID,
+		Code:     reg.Code,
+		ClassID:  class.ID,
+		CampusID: class.CampusID,
+		Day:      class.Date.In(campus.Location()).Format("2006-01-02"),
+		Status:   reg.Status,
+		By:       by.Name,
+		At:       time.Now(),
+	})
+}
+
+// publishRoom tells stations watching classID's campus that its room changed.
+func publishRoom(classID uint, kind string, by Actor) {
+	var class models.Class
+	if err := db.Conn().Preload("Campus").First(&class, classID).Error; err != nil {
+		return
+	}
+	var campus models.Campus
+	if class.Campus != nil {
+		campus = *class.Campus
+	}
+	events.Publish(events.Change{
+		Kind:     kind,
+		ClassID:  class.ID,
+		CampusID: class.CampusID,
+		Day:      class.Date.In(campus.Location()).Format("2006-01-02"),
+		By:       by.Name,
+		At:       time.Now(),
+	})
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// MarkNoShows marks confirmed registrations that were never checked in as
+// no-shows once their class day has ended at its campus, and returns how many
+// it marked. Whoever is still on such a class's waitlist is canceled: the
+// seats the no-shows leave are never given out. Running it again marks
+// nothing new.
+//
+// Only classes inside the no-show policy's look-back window are marked. Older
+// ones would not count against anyone, and most of them predate attendance
+// being taken at all.
+func MarkNoShows(now time.Time) (int, error) {
+	policy, err := GetNoShowPolicy(db.Conn())
+	if err != nil {
+		return 0, err
+	}
+	pending := db.Conn().Model(&models.Registration{}).
+		Select("class_id").
+		Where("(status = ? AND check_in_at IS NULL) OR status = ?", StatusConfirmed, StatusWaitlisted)
+	var classes []models.Class
+	if err := db.Conn().Preload("Campus").
+		Where("date >= ? AND date < ? AND id IN (?)", policy.Since(now), now, pending).
+		Find(&classes).Error; err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, class := range classes {
+		var campus models.Campus
+		if class.Campus != nil {
+			campus = *class.Campus
+		}
+		loc := campus.Location()
+		d := class.Date.In(loc)
+		if now.Before(time.Date(d.Year(), d.Month(), d.Day()+1, 0, 0, 0, 0, loc)) {
+			continue
+		}
+		err := db.Conn().Transaction(func(tx *gorm.DB) error {
+			var regs []models.Registration
+			if err := tx.Where("class_id = ? AND status = ? AND check_in_at IS NULL", class.ID, StatusConfirmed).
+				Find(&regs).Error; err != nil {
+				return err
+			}
+			for i := range regs {
+				if err := applyTx(tx, &regs[i], models.EventNoShow, System, ""); err != nil {
+					return err
+				}
+			}
+			var waiting []models.Registration
+			if err := tx.Where("class_id = ? AND status = ?", class.ID, StatusWaitlisted).Find(&waiting).Error; err != nil {
+				return err
+			}
+			for i := range waiting {
+				if err := applyTx(tx, &waiting[i], models.EventCanceled, System, "class ended"); err != nil {
+					return err
+				}
+			}
+			marked += len(regs)
+			return nil
+		})
+		if err != nil {
+			return marked, fmt.Errorf("class %d: %w", class.ID, err)
+		}
+	}
+	return marked, nil
+}
+
+// NoShowPolicy sends a family's new registrations to the waitlist once they
+// have Limit no-shows in classes of the last Weeks weeks. A zero Limit turns
+// it off.
+type NoShowPolicy struct {
+	Limit int
+	Weeks int
+}
+
+// noShowPolicyKey holds the policy in app_settings as "limit/weeks".
+const noShowPolicyKey = "noshow_policy"
+
+// DefaultNoShowWeeks is the look-back window while no policy is set; the
+// families report still counts no-shows over it.
+const DefaultNoShowWeeks = 8
+
+// ErrNoShowPolicy is returned for a policy that cannot be enforced.
+var ErrNoShowPolicy = errors.New("no-show policy needs a limit of 0 or more and 1 to 52 weeks")
+
+// Enabled reports whether the policy holds anyone back.
+func (p NoShowPolicy) Enabled() bool { return p.Limit > 0 }
+
+// Since is the start of the look-back window ending at now.
+func (p NoShowPolicy) Since(now time.Time) time.Time {
+	return now.AddDate(0, 0, -7*p.Weeks)
+}
+
+func (p NoShowPolicy) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d no-shows in %d weeks", p.Limit, p.Weeks)
+}
+
+// GetNoShowPolicy reads the policy through tx (db.Conn() outside a
+// transaction).
+func GetNoShowPolicy(tx *gorm.DB) (NoShowPolicy, error) {
+	p := NoShowPolicy{Weeks: DefaultNoShowWeeks}
+	var row models.AppSetting
+	err := tx.Where("key = ?", noShowPolicyKey).Limit(1).Find(&row).Error
+	if err != nil || row.Value == "" {
+		return p, err
+	}
+	l, w, _ := strings.Cut(row.Value, "/")
+	limit, err1 := strconv.Atoi(l)
+	weeks, err2 := strconv.Atoi(w)
+	if err1 != nil || err2 != nil {
+		return p, fmt.Errorf("app setting %s: bad value %q", noShowPolicyKey, row.Value)
+	}
+	return NoShowPolicy{Limit: limit, Weeks: weeks}, nil
+}
+
+// SetNoShowPolicy stores the policy; it applies to registrations made from
+// now on.
+func SetNoShowPolicy(p NoShowPolicy) error {
+	if p.Limit < 0 || p.Weeks < 1 || p.Weeks > 52 {
+		return ErrNoShowPolicy
+	}
+	return db.Conn().Save(&models.AppSetting{
+		Key:       noShowPolicyKey,
+		Value:     fmt.Sprintf("%d/%d", p.Limit, p.Weeks),
+		UpdatedAt: time.Now(),
+	}).Error
+}
+
+// NoShowCounts returns, per household, its no-shows in classes dated since
+// since. Households without any are left out.
+func NoShowCounts(tx *gorm.DB, householdIDs []uint, since time.Time) (map[uint]int, error) {
+	type row struct {
+		HouseholdID uint
+		N           int
+	}
+	var rows []row
+	if err := tx.Table("registrations").
+		Select("registrations.household_id, COUNT(*) AS n").
+		Joins("JOIN classes ON classes.id = registrations.class_id").
+		Where("registrations.deleted_at IS NULL AND registrations.status = ?", StatusNoShow).
+		Where("registrations.household_id IN ? AND classes.date >= ?", householdIDs, since).
+		Group("registrations.household_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		out[r.HouseholdID] = r.N
+	}
+	return out, nil
+}
+
+// noShowHold reports whether the policy sends householdID's next
+// registration to the waitlist, with the note to record if so.
+func noShowHold(tx *gorm.DB, householdID uint, now time.Time) (bool, string, error) {
+	p, err := GetNoShowPolicy(tx)
+	if err != nil || !p.Enabled() || householdID == 0 {
+		return false, "", err
+	}
+	counts, err := NoShowCounts(tx, []uint{householdID}, p.Since(now))
+	if err != nil {
+		return false, "", err
+	}
+	if counts[householdID] < p.Limit {
+		return false, "", nil
+	}
+	return true, "no-show policy: " + p.String(), nil
+}
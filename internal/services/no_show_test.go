@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestMarkNoShows_AfterTheClassDay(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+
+	jkt := models.Campus{}.Location()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, jkt)
+	past := models.Class{Name: "Last week", Date: now.AddDate(0, 0, -7), Capacity: 10}
+	today := models.Class{Name: "This morning", Date: now.Add(-2 * time.Hour), Capacity: 10}
+	// Before the policy window: history, not a no-show.
+	old := models.Class{Name: "Last year", Date: now.AddDate(-1, 0, 0), Capacity: 10}
+	conn.Create(&past)
+	conn.Create(&today)
+	conn.Create(&old)
+	sib := models.Child{Name: "Sinta", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&sib)
+	checkedIn := now.AddDate(0, 0, -7)
+	mk := func(child, class uint, code string, in *time.Time) models.Registration {
+		reg := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: child,
+			ClassID: class, Status: "confirmed", Code: code, CheckInAt: in}
+		conn.Create(&reg)
+		return reg
+	}
+	missed := mk(f.kid.ID, past.ID, "REG-NS1", nil)
+	came := mk(sib.ID, past.ID, "REG-NS2", &checkedIn)
+	laterToday := mk(f.kid.ID, today.ID, "REG-NS3", nil)
+	history := mk(f.kid.ID, old.ID, "REG-NS4", nil)
+
+	n, err := MarkNoShows(now)
+	if err != nil || n != 1 {
+		t.Fatalf("MarkNoShows = %d, %v; want 1", n, err)
+	}
+	for reg, want := range map[uint]Status{missed.ID: StatusNoShow, came.ID: StatusConfirmed,
+		laterToday.ID: StatusConfirmed, history.ID: StatusConfirmed, f.reg.ID: StatusConfirmed} {
+		var got models.Registration
+		conn.First(&got, reg)
+		if StatusOf(got) != want {
+			t.Errorf("registration %s: status %q, want %q", got.Code, got.Status, want)
+		}
+	}
+	evs := eventsOf(t, missed.ID)
+	if last := evs[len(evs)-1]; last.Kind != models.EventNoShow || last.Actor != System.Name {
+		t.Errorf("last event = %+v, want no_show by system", last)
+	}
+	if n, _ := MarkNoShows(now); n != 0 {
+		t.Errorf("second run marked %d", n)
+	}
+
+	// The day after, this morning's class has ended too.
+	if n, _ := MarkNoShows(now.AddDate(0, 0, 1)); n != 1 {
+		t.Errorf("next day marked %d, want 1", n)
+	}
+
+	// An admin checking the child in after the fact clears the no-show.
+	reg, err := CheckIn(missed.ID, admin)
+	if err != nil || StatusOf(reg) != StatusConfirmed {
+		t.Errorf("late check-in = %q, %v", reg.Status, err)
+	}
+}
+
+func TestNoShowPolicy_StartsOnTheWaitlist(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	past := models.Class{Name: "Last week", Date: time.Now().AddDate(0, 0, -7), Capacity: 10}
+	conn.Create(&past)
+	conn.Create(&models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: f.kid.ID,
+		ClassID: past.ID, Status: string(StatusNoShow), Code: "REG-NS1"})
+
+	register := func(code string) models.Registration {
+		t.Helper()
+		class := models.Class{Name: "Next week " + code, Date: time.Now().AddDate(0, 0, 7), Capacity: 10}
+		conn.Create(&class)
+		reg := models.Registration{ParentID: f.parent.ID, ChildID: f.kid.ID, ClassID: class.ID, Code: code}
+		if err := conn.Transaction(func(tx *gorm.DB) error { return CreateRegistration(tx, &reg, admin) }); err != nil {
+			t.Fatal(err)
+		}
+		return reg
+	}
+
+	if reg := register("REG-P0"); StatusOf(reg) != StatusConfirmed {
+		t.Errorf("policy off: status %q, want confirmed", reg.Status)
+	}
+
+	if err := SetNoShowPolicy(NoShowPolicy{Limit: 1, Weeks: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := GetNoShowPolicy(conn); p != (NoShowPolicy{Limit: 1, Weeks: 4}) {
+		t.Fatalf("policy = %+v", p)
+	}
+	reg := register("REG-P1")
+	if StatusOf(reg) != StatusWaitlisted {
+		t.Errorf("over the policy: status %q, want waitlisted", reg.Status)
+	}
+	evs := eventsOf(t, reg.ID)
+	if last := evs[len(evs)-1]; last.Note != "no-show policy: 1 no-shows in 4 weeks" {
+		t.Errorf("note = %q", last.Note)
+	}
+
+	// Outside the window the no-show no longer counts.
+	conn.Model(&models.Class{}).Where("id = ?", past.ID).Update("date", time.Now().AddDate(0, 0, -35))
+	if reg := register("REG-P2"); StatusOf(reg) != StatusConfirmed {
+		t.Errorf("old no-show: status %q, want confirmed", reg.Status)
+	}
+
+	if err := SetNoShowPolicy(NoShowPolicy{Limit: 1, Weeks: 0}); err != ErrNoShowPolicy {
+		t.Errorf("zero weeks: err = %v", err)
+	}
+}
+
+// A session that is over gives no seats away: canceling on it promotes no
+// one, and marking its no-shows closes the waitlist instead of filling the
+// seats they left.
+func TestEndedClass_PromotesNoOne(t *testing.T) {
+	f := seedTrash(t, 1)
+	conn := db.Conn()
+	over := models.Class{Name: "Last week", Date: time.Now().AddDate(0, 0, -7), Capacity: 1}
+	conn.Create(&over)
+	sib := models.Child{Name: "Sinta", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&sib)
+	mk := func(child uint, status, code string) models.Registration {
+		reg := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: child,
+			ClassID: over.ID, Status: status, Code: code}
+		conn.Create(&reg)
+		return reg
+	}
+	seated := mk(f.kid.ID, "confirmed", "REG-E1")
+	waiting := mk(sib.ID, "waitlisted", "REG-E2")
+
+	if err := CancelByCode(seated.Code, admin); err != nil {
+		t.Fatal(err)
+	}
+	var got models.Registration
+	conn.First(&got, waiting.ID)
+	if StatusOf(got) != StatusWaitlisted {
+		t.Fatalf("waitlisted after cancel on an ended class = %q, want still waitlisted", got.Status)
+	}
+
+	if _, err := MarkNoShows(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	conn.First(&got, waiting.ID)
+	if StatusOf(got) != StatusCanceled {
+		t.Errorf("waitlisted after MarkNoShows = %q, want canceled", got.Status)
+	}
+	for _, e := range eventsOf(t, waiting.ID) {
+		if e.Kind == models.EventPromoted {
+			t.Errorf("ended class promoted %s", got.Code)
+		}
+	}
+}
@@ -88,6 +88,7 @@ func altPhones(p string) []string {
 }
 
 // FindParentByAny tries multiple normalized variants and a digits-only SQL compare.
+// Any guardian's phone finds that guardian; their HouseholdID is the family.
 func FindParentByAny(phone string) (*models.Parent, error) {
 	var parent models.Parent
 
@@ -101,9 +102,7 @@ func FindParentByAny(phone string) (*models.Parent, error) {
 	// fallback: digits-only compare in SQL (ignore +, spaces, -, ())
 	inDigits := digitsOnly(phone)
 	if inDigits != "" {
-		q := `
-			REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(phone,'+',''),' ',''),'-',''),'(',''),')','')
-		`
+		q := db.Dialect().PhoneDigits("phone")
 		if err := db.Conn().Where(q+" = ?", inDigits).First(&parent).Error; err == nil {
 			return &parent, nil
 		}
@@ -111,3 +110,27 @@ func FindParentByAny(phone string) (*models.Parent, error) {
 
 	return nil, errors.New("parent not found")
 }
+
+// MinPhoneFragment is the fewest digits a partial phone search runs on;
+// shorter ones would match half the families of the day.
+const MinPhoneFragment = 4
+
+// PhoneFragments turns a partial phone typed at the station into the digit
+// strings to look for inside stored numbers, using the same digits-only view
+// as FindParentByAny. A local "0812…" start also looks for "62812…", which
+// is how numbers are stored. Anything that is not phone-like gives nil.
+func PhoneFragments(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" || !reAllowed.MatchString(s) {
+		return nil
+	}
+	d := digitsOnly(s)
+	if len(d) < MinPhoneFragment {
+		return nil
+	}
+	out := []string{d}
+	if strings.HasPrefix(d, "0") && !strings.HasPrefix(d, "00") {
+		out = append(out, "62"+d[1:])
+	}
+	return out
+}
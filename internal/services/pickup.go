@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+var (
+	ErrDoNotRelease  = errors.New("this person is flagged do-not-release for the child")
+	ErrUnknownPickup = errors.New("not an authorized pickup for this child")
+	ErrMissingPickup = errors.New("pickup name is required")
+)
+
+// MaxPickupPhoto caps an uploaded pickup photo; a phone snapshot scaled down
+// by the browser fits easily.
+const MaxPickupPhoto = 1 << 20
+
+// Pickup is one choice on the check-out screen: a guardian of the child's
+// household, or one of the child's authorized pickup persons. Key is what the
+// station posts back.
+type Pickup struct {
+	Key      string // "guardian:ID" or "person:ID"
+	Name     string
+	Relation string
+	Phone    string
+	PersonID uint // set for pickup persons, 0 for guardians
+	HasPhoto bool
+}
+
+// Label is the name that goes into Registration.PickedUpBy.
+func (p Pickup) Label() string {
+	if p.Relation == "" {
+		return p.Name
+	}
+	return p.Name + " (" + p.Relation + ")"
+}
+
+// PickupPersons lists a child's pickup list, do-not-release entries included,
+// without photo bytes.
+func PickupPersons(childID uint) []models.PickupPerson {
+	return pickupPersons(db.Conn(), childID)
+}
+
+func pickupPersons(tx *gorm.DB, childID uint) []models.PickupPerson {
+	var out []models.PickupPerson
+	_ = tx.Select("id, created_at, updated_at, child_id, name, relation, phone, photo_type, do_not_release, note").
+		Where("child_id = ?", childID).Order("do_not_release desc, name asc").Find(&out).Error
+	return out
+}
+
+// PickupsFor splits who may collect a child from who must not: the household's
+// guardians and authorized persons on one side, do-not-release flags on the
+// other.
+func PickupsFor(child models.Child) (allowed []Pickup, blocked []models.PickupPerson) {
+	return pickupsFor(db.Conn(), child)
+}
+
+func pickupsFor(tx *gorm.DB, child models.Child) (allowed []Pickup, blocked []models.PickupPerson) {
+	var guardians []models.Parent
+	_ = tx.Where("household_id = ?", child.HouseholdID).Order("id asc").Find(&guardians).Error
+	for _, g := range guardians {
+		allowed = append(allowed, Pickup{
+			Key:      fmt.Sprintf("guardian:%d", g.ID),
+			Name:     g.Name,
+			Relation: "guardian",
+			Phone:    g.Phone,
+		})
+	}
+	for _, p := range pickupPersons(tx, child.ID) {
+		if p.DoNotRelease {
+			blocked = append(blocked, p)
+			continue
+		}
+		allowed = append(allowed, Pickup{
+			Key:      fmt.Sprintf("person:%d", p.ID),
+			Name:     p.Name,
+			Relation: p.Relation,
+			Phone:    p.Phone,
+			PersonID: p.ID,
+			HasPhoto: p.PhotoType != "",
+		})
+	}
+	return allowed, blocked
+}
+
+// AddPickupPerson saves a pickup person for p.ChildID.
+func AddPickupPerson(p *models.PickupPerson) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Relation = strings.TrimSpace(p.Relation)
+	if p.Name == "" {
+		return ErrMissingPickup
+	}
+	if p.Phone != "" {
+		p.Phone = NormPhone(p.Phone)
+	}
+	if len(p.Photo) == 0 {
+		p.Photo, p.PhotoType = nil, ""
+	}
+	return db.Conn().Create(p).Error
+}
+
+// RemovePickupPerson deletes one entry of a child's pickup list. It is a
+// plain delete: the names already written into PickedUpBy keep the record.
+func RemovePickupPerson(childID, id uint) error {
+	res := db.Conn().Where("id = ? AND child_id = ?", id, childID).Delete(&models.PickupPerson{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CheckOut releases a checked-in child to the pickup chosen by key, which must
+// be one PickupsFor offers, on showing the security code from the parent's
+// stub. A do-not-release person is refused with ErrDoNotRelease, a wrong code
+// with ErrSecurityCode. As with CheckIn, callers fence permissions first.
+func CheckOut(regID uint, key, code string, by Actor) (models.Registration, error) {
+	var reg models.Registration
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&reg, regID).Error; err != nil {
+			return err
+		}
+		var child models.Child
+		if err := tx.First(&child, reg.ChildID).Error; err != nil {
+			return err
+		}
+		allowed, blocked := pickupsFor(tx, child)
+		for _, b := range blocked {
+			if key == fmt.Sprintf("person:%d", b.ID) {
+				return ErrDoNotRelease
+			}
+		}
+		var picked *Pickup
+		for i := range allowed {
+			if allowed[i].Key == key {
+				picked = &allowed[i]
+			}
+		}
+		if picked == nil {
+			return ErrUnknownPickup
+		}
+		if reg.CheckInAt != nil && !SecurityCodeMatches(reg.SecurityCode, code) {
+			return ErrSecurityCode
+		}
+		reg.PickedUpBy = picked.Label()
+		return applyTx(tx, &reg, models.EventCheckedOut, by, "picked up by "+picked.Label())
+	})
+	if err == nil {
+		publishChange(reg, models.EventCheckedOut, by)
+	}
+	return reg, err
+}
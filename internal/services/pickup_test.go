@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestCheckOut(t *testing.T) {
+	f := seedTrash(t, 10)
+	grandma := models.PickupPerson{ChildID: f.kid.ID, Name: "Oma Lien", Relation: "grandmother"}
+	uncle := models.PickupPerson{ChildID: f.kid.ID, Name: "Om Didi", DoNotRelease: true}
+	for _, p := range []*models.PickupPerson{&grandma, &uncle} {
+		if err := AddPickupPerson(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	guardian := fmt.Sprintf("guardian:%d", f.parent.ID)
+
+	if _, err := CheckOut(f.reg.ID, guardian, "", admin); !errors.Is(err, ErrNotCheckedIn) {
+		t.Fatalf("check out before check-in = %v, want ErrNotCheckedIn", err)
+	}
+	in, err := CheckIn(f.reg.ID, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := in.SecurityCode
+	if len(code) != SecurityCodeLen {
+		t.Fatalf("security code = %q", code)
+	}
+	if _, err := CheckOut(f.reg.ID, guardian, "ZZZZ", admin); !errors.Is(err, ErrSecurityCode) {
+		t.Errorf("wrong security code = %v, want ErrSecurityCode", err)
+	}
+	if _, err := CheckOut(f.reg.ID, fmt.Sprintf("person:%d", uncle.ID), code, admin); !errors.Is(err, ErrDoNotRelease) {
+		t.Errorf("release to flagged person = %v, want ErrDoNotRelease", err)
+	}
+	if _, err := CheckOut(f.reg.ID, "person:9999", code, admin); !errors.Is(err, ErrUnknownPickup) {
+		t.Errorf("release to stranger = %v, want ErrUnknownPickup", err)
+	}
+
+	// Lower case and stray spaces are how codes get typed at the door.
+	reg, err := CheckOut(f.reg.ID, fmt.Sprintf("person:%d", grandma.ID), " "+strings.ToLower(code), admin)
+	if err != nil {
+		t.Fatalf("CheckOut: %v", err)
+	}
+	if reg.CheckOutAt == nil || reg.CheckedOutBy != "admin" || reg.PickedUpBy != "Oma Lien (grandmother)" {
+		t.Errorf("after check-out: at=%v by=%q picked=%q", reg.CheckOutAt, reg.CheckedOutBy, reg.PickedUpBy)
+	}
+	if _, err := CheckOut(f.reg.ID, guardian, code, admin); !errors.Is(err, ErrAlreadyCheckedOut) {
+		t.Errorf("second check-out = %v, want ErrAlreadyCheckedOut", err)
+	}
+	evs := eventsOf(t, f.reg.ID)
+	if last := evs[len(evs)-1]; last.Kind != models.EventCheckedOut {
+		t.Errorf("last event = %q, want %q", last.Kind, models.EventCheckedOut)
+	}
+}
+
+func TestPickupsFor_SplitsBlocked(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := AddPickupPerson(&models.PickupPerson{ChildID: f.kid.ID, Name: "Om Didi", DoNotRelease: true}); err != nil {
+		t.Fatal(err)
+	}
+	allowed, blocked := PickupsFor(f.kid)
+	if len(allowed) != 1 || allowed[0].Key != fmt.Sprintf("guardian:%d", f.parent.ID) {
+		t.Errorf("allowed = %+v, want only the guardian", allowed)
+	}
+	if len(blocked) != 1 || blocked[0].Name != "Om Didi" {
+		t.Errorf("blocked = %+v", blocked)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// QRKey returns the HMAC key QR payloads and image links are signed with.
+// The web layer points it at the session secret at startup, so rotating
+// SESSION_SECRET retires every QR along with the sessions.
+var QRKey func() []byte
+
+// QRImageLinkTTL is how long a signed QR image link keeps working. Telegram
+// fetches the photo when the message is sent, and the registration page is
+// read right away; after that the parent's own session serves the image.
+const QRImageLinkTTL = 24 * time.Hour
+
+// ErrQRToken is returned for a QR payload that was not signed by us or no
+// longer matches the registration it names.
+var ErrQRToken = errors.New("qr code is not valid")
+
+// QRClaim is what a signed QR vouches for: the registration (or family code)
+// ID, its code, and the day it is for (YYYY-MM-DD at the class's campus).
+type QRClaim struct {
+	ID   uint
+	Code string
+	Day  string
+}
+
+func qrMAC(parts ...string) string {
+	mac := hmac.New(sha256.New, QRKey())
+	mac.Write([]byte(strings.Join(parts, "|")))
+	// Half the digest keeps the QR small enough to scan off a dim phone.
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// SignQR builds "<id>.<code>.<day>.<mac>", the token a QR carries to /checkin.
+func SignQR(c QRClaim) string {
+	body := fmt.Sprintf("%d.%s.%s", c.ID, c.Code, c.Day)
+	return body + "." + qrMAC("checkin", body)
+}
+
+// ParseQR checks the signature and returns the claim. It does not look at
+// the database; ResolveQR does.
+func ParseQR(tok string) (QRClaim, error) {
+	parts := strings.Split(strings.TrimSpace(tok), ".")
+	if len(parts) != 4 {
+		return QRClaim{}, ErrQRToken
+	}
+	body := parts[0] + "." + parts[1] + "." + parts[2]
+	if subtle.ConstantTimeCompare([]byte(qrMAC("checkin", body)), []byte(parts[3])) != 1 {
+		return QRClaim{}, ErrQRToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || id == 0 {
+		return QRClaim{}, ErrQRToken
+	}
+	return QRClaim{ID: uint(id), Code: parts[1], Day: parts[2]}, nil
+}
+
+// RegistrationQR returns the signed payload for reg's QR, bound to its class
+// date.
+func RegistrationQR(reg models.Registration) (string, error) {
+	day, err := regDay(reg)
+	if err != nil {
+		return "", err
+	}
+	return SignQR(QRClaim{ID: reg.ID, Code: reg.Code, Day: day}), nil
+}
+
+// FamilyQR returns the signed payload for a family code.
+func FamilyQR(fc models.FamilyCode) string {
+	return SignQR(QRClaim{ID: fc.ID, Code: fc.Code, Day: fc.Day})
+}
+
+// ResolveQR verifies tok and that the registration or family code it names
+// still has that code and day. A class moved to another date invalidates the
+// QR printed for the old one.
+func ResolveQR(tok string) (QRClaim, error) {
+	c, err := ParseQR(tok)
+	if err != nil {
+		return c, err
+	}
+	if IsFamilyCode(c.Code) {
+		var fc models.FamilyCode
+		if err := db.Conn().First(&fc, c.ID).Error; err != nil || fc.Code != c.Code || fc.Day != c.Day {
+			return QRClaim{}, ErrQRToken
+		}
+		return c, nil
+	}
+	var reg models.Registration
+	if err := db.Conn().First(&reg, c.ID).Error; err != nil || reg.Code != c.Code {
+		return QRClaim{}, ErrQRToken
+	}
+	if day, err := regDay(reg); err != nil || day != c.Day {
+		return QRClaim{}, ErrQRToken
+	}
+	return c, nil
+}
+
+// regDay is the registration's class date at its campus.
+func regDay(reg models.Registration) (string, error) {
+	var class models.Class
+	if err := db.Conn().Unscoped().Preload("Campus").First(&class, reg.ClassID).Error; err != nil {
+		return "", err
+	}
+	var campus models.Campus
+	if class.Campus != nil {
+		campus = *class.Campus
+	}
+	return class.Date.In(campus.Location()).Format("2006-01-02"), nil
+}
+
+// QRImagePath is a link to code's QR image that works without a parent
+// session until exp. Bot messages and the registration page use it.
+func QRImagePath(code string, exp time.Time) string {
+	e := strconv.FormatInt(exp.Unix(), 10)
+	return "/qr/" + url.PathEscape(code) + ".png?exp=" + e + "&sig=" + qrMAC("image", code, e)
+}
+
+// QRImageLinkValid reports whether exp/sig from a QR image link were signed
+// for code and have not run out.
+func QRImageLinkValid(code, exp, sig string, now time.Time) bool {
+	if exp == "" || sig == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(qrMAC("image", code, exp)), []byte(sig)) != 1 {
+		return false
+	}
+	n, err := strconv.ParseInt(exp, 10, 64)
+	return err == nil && now.Before(time.Unix(n, 0))
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func init() {
+	QRKey = func() []byte { return []byte("test-qr-key") }
+}
+
+func TestResolveQR_BindsRegistrationCodeAndDay(t *testing.T) {
+	f := seedTrash(t, 10)
+
+	tok, err := RegistrationQR(f.reg)
+	if err != nil {
+		t.Fatalf("RegistrationQR: %v", err)
+	}
+	c, err := ResolveQR(tok)
+	if err != nil || c.ID != f.reg.ID || c.Code != "REG-T1" {
+		t.Fatalf("ResolveQR = %+v, %v", c, err)
+	}
+
+	// Swapping in another code keeps the old signature: rejected.
+	forged := strings.Replace(tok, "REG-T1", "REG-T2", 1)
+	if _, err := ResolveQR(forged); !errors.Is(err, ErrQRToken) {
+		t.Errorf("forged code: err = %v, want ErrQRToken", err)
+	}
+
+	// Moving the class to another day retires the QR printed for the old one.
+	db.Conn().Model(&models.Class{}).Where("id = ?", f.class.ID).
+		Update("date", f.class.Date.AddDate(0, 0, 7))
+	if _, err := ResolveQR(tok); !errors.Is(err, ErrQRToken) {
+		t.Errorf("moved class: err = %v, want ErrQRToken", err)
+	}
+}
+
+func TestResolveQR_FamilyCode(t *testing.T) {
+	f := seedTrash(t, 10)
+	code, err := FamilyCodeFor(f.parent, "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err := FamilyByCode(code, "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ResolveQR(FamilyQR(fc))
+	if err != nil || c.Code != code {
+		t.Fatalf("ResolveQR(family) = %+v, %v", c, err)
+	}
+}
+
+func TestParseQR_RejectsGarbage(t *testing.T) {
+	for _, bad := range []string{"", "REG-T1", "1.REG-T1.2026-10-18", "1.REG-T1.2026-10-18.AAAA", "x.REG-T1.2026-10-18." + qrMAC("checkin", "x.REG-T1.2026-10-18")} {
+		if _, err := ParseQR(bad); err == nil {
+			t.Errorf("ParseQR(%q) accepted", bad)
+		}
+	}
+}
+
+func TestQRImageLink(t *testing.T) {
+	now := time.Now()
+	link := QRImagePath("REG-T1", now.Add(time.Hour))
+	if !strings.HasPrefix(link, "/qr/REG-T1.png?") {
+		t.Fatalf("link = %q", link)
+	}
+	q := link[strings.IndexByte(link, '?')+1:]
+	var exp, sig string
+	for _, kv := range strings.Split(q, "&") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "exp":
+			exp = v
+		case "sig":
+			sig = v
+		}
+	}
+	if !QRImageLinkValid("REG-T1", exp, sig, now) {
+		t.Error("fresh link rejected")
+	}
+	if QRImageLinkValid("REG-T2", exp, sig, now) {
+		t.Error("link accepted for another code")
+	}
+	if QRImageLinkValid("REG-T1", exp, sig, now.Add(2*time.Hour)) {
+		t.Error("expired link accepted")
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// ErrNotOpenYet is returned by Register before the class's signup opens.
+var ErrNotOpenYet = errors.New("signup for this class is not open yet")
+
+// SignupOpenGrace lets a tap sent right as signup opens through, so a phone
+// whose clock runs a second ahead is not turned away.
+const SignupOpenGrace = 2 * time.Second
+
+// RegisterResult is what a parent is told after signing a child up.
+type RegisterResult struct {
+	Registration models.Registration
+	Status       Status // StatusConfirmed or StatusWaitlisted
+	Code         string
+	Rank         int // place on the waitlist, from 1; 0 when confirmed
+}
+
+// Register signs childID up for classID with the answers to its questions
+// (question ID → answer, already validated), deciding confirmed or
+// waitlisted. The class row is locked for the whole transaction, so two
+// parents racing for the last seat are served one after the other and the
+// class never goes over capacity. The website and the bot both register
+// through here.
+func Register(childID, classID uint, answers map[uint]string, by Actor) (RegisterResult, error) {
+	res, err := RegisterSiblings(classID, []Signup{{ChildID: childID, Answers: answers}}, false, by)
+	if err != nil {
+		return RegisterResult{}, err
+	}
+	return res[0], nil
+}
+
+// ErrNotSiblings is returned when a sibling signup mixes households.
+var ErrNotSiblings = errors.New("children are not from the same family")
+
+// Signup is one child's part of a RegisterSiblings call.
+type Signup struct {
+	ChildID uint
+	Answers map[uint]string // question ID → answer, already validated
+
+	ageOverride bool // let in past the class's ages; see AdminRegister
+}
+
+// RegisterSiblings signs several children of one household up for classID
+// in a single transaction, in the order given: either all of them are
+// registered or, on any error, none. With together set the siblings are
+// never split — when the class cannot seat every one of them, they all go
+// on the waitlist side by side instead of the first few taking the last
+// seats. Results come back in the order of kids.
+func RegisterSiblings(classID uint, kids []Signup, together bool, by Actor) ([]RegisterResult, error) {
+	return registerSiblings(classID, kids, together, false, by)
+}
+
+// AdminRegister is Register for staff adding a child from the roster. With
+// overrideAge the class's age limits are waived for this one registration,
+// which remembers who waived them; everything else is checked as usual.
+func AdminRegister(childID, classID uint, overrideAge bool, by Actor) (RegisterResult, error) {
+	res, err := registerSiblings(classID, []Signup{{ChildID: childID}}, false, overrideAge, by)
+	if err != nil {
+		return RegisterResult{}, err
+	}
+	return res[0], nil
+}
+
+func registerSiblings(classID uint, kids []Signup, together, overrideAge bool, by Actor) ([]RegisterResult, error) {
+	var out []RegisterResult
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		class, err := lockClass(tx, classID)
+		if err != nil {
+			return err
+		}
+		if !signupOpen(class) {
+			return ErrNotOpenYet
+		}
+		children, err := loadSiblings(tx, kids)
+		if err != nil {
+			return err
+		}
+		for i, k := range kids {
+			err := checkRegistrationConflicts(tx, k.ChildID, classID)
+			if overrideAge && errors.Is(err, ErrAgeIneligible) {
+				kids[i].ageOverride, err = true, nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		out, err = registerTx(tx, class, children, kids, together, by)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// lockClass loads classID for update. SQLite has one connection, so its
+// transactions are serial already; on Postgres FOR UPDATE queues everyone
+// behind the class row.
+func lockClass(tx *gorm.DB, classID uint) (models.Class, error) {
+	var class models.Class
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&class, classID).Error
+	return class, err
+}
+
+func signupOpen(class models.Class) bool {
+	return class.SignupOpensAt == nil || !time.Now().Add(SignupOpenGrace).Before(*class.SignupOpensAt)
+}
+
+// loadSiblings loads kids' children, in order, and checks they share a
+// household.
+func loadSiblings(tx *gorm.DB, kids []Signup) ([]models.Child, error) {
+	children := make([]models.Child, len(kids))
+	for i, k := range kids {
+		if err := tx.First(&children[i], k.ChildID).Error; err != nil {
+			return nil, err
+		}
+		if children[i].HouseholdID != children[0].HouseholdID {
+			return nil, ErrNotSiblings
+		}
+	}
+	return children, nil
+}
+
+// registerTx creates the registrations of kids (children holds their rows)
+// on class, which the caller has locked and cleared of conflicts.
+func registerTx(tx *gorm.DB, class models.Class, children []models.Child, kids []Signup, together bool, by Actor) ([]RegisterResult, error) {
+	waitAll := false
+	if together && len(kids) > 1 {
+		var confirmed int64
+		if err := tx.Model(&models.Registration{}).
+			Where("class_id = ? AND status = ?", class.ID, StatusConfirmed).
+			Count(&confirmed).Error; err != nil {
+			return nil, err
+		}
+		waitAll = class.Capacity-int(confirmed) < len(kids)
+	}
+
+	out := make([]RegisterResult, 0, len(kids))
+	for i, k := range kids {
+		code, err := NewRegCode()
+		if err != nil {
+			return nil, err
+		}
+		reg := models.Registration{ParentID: children[i].ParentID, ChildID: k.ChildID, ClassID: class.ID, Code: code}
+		if k.ageOverride {
+			reg.AgeOverrideBy = by.Name
+		}
+		if waitAll {
+			reg.HouseholdID = children[i].HouseholdID
+			err = insertRegistration(tx, &reg, class, models.EventWaitlisted, by, "kept together with siblings")
+		} else {
+			err = CreateRegistration(tx, &reg, by)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for qid, ans := range k.Answers {
+			if err := tx.Create(&models.RegistrationAnswer{RegistrationID: reg.ID, QuestionID: qid, Answer: ans}).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		res := RegisterResult{Registration: reg, Status: StatusOf(reg), Code: reg.Code}
+		if res.Status == StatusWaitlisted {
+			rank, err := WaitlistRank(tx, reg)
+			if err != nil {
+				return nil, err
+			}
+			res.Rank = rank
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// NewRegCode draws a REG-XXXXXXXX code. 32 random bits make a collision
+// statistically impossible at this scale.
+func NewRegCode() (string, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("REG-%08X", binary.BigEndian.Uint32(b[:])), nil
+}
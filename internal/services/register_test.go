@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// TestRegister_ConcurrentSignupsNeverOverfill opens signup to a few hundred
+// parents at once: exactly capacity of them are confirmed, the rest are
+// waitlisted with distinct ranks.
+func TestRegister_ConcurrentSignupsNeverOverfill(t *testing.T) {
+	const capacity, parents = 5, 300
+	conn := dbtest.Init(t)
+	class := models.Class{Name: "FJB Stars Club", Date: time.Now().AddDate(0, 0, 7), Capacity: capacity}
+	conn.Create(&class)
+
+	kids := make([]models.Child, parents)
+	for i := range kids {
+		p := models.Parent{Name: fmt.Sprintf("Parent %d", i), Phone: fmt.Sprintf("+62812%06d", i)}
+		if err := CreateParent(conn, &p); err != nil {
+			t.Fatal(err)
+		}
+		kids[i] = models.Child{Name: fmt.Sprintf("Kid %d", i), ParentID: p.ID, HouseholdID: p.HouseholdID}
+		conn.Create(&kids[i])
+	}
+
+	results := make([]RegisterResult, parents)
+	errs := make([]error, parents)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range kids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = Register(kids[i].ID, class.ID, nil, ParentActor(fmt.Sprintf("+62812%06d", i), models.SourceWeb))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	confirmed := 0
+	ranks := map[int]bool{}
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatalf("Register %d: %v", i, errs[i])
+		}
+		switch res.Status {
+		case StatusConfirmed:
+			confirmed++
+		case StatusWaitlisted:
+			if res.Rank < 1 || res.Rank > parents-capacity || ranks[res.Rank] {
+				t.Errorf("waitlist rank %d out of range or repeated", res.Rank)
+			}
+			ranks[res.Rank] = true
+		default:
+			t.Errorf("Register %d: status %q", i, res.Status)
+		}
+	}
+	if confirmed != capacity {
+		t.Errorf("confirmed %d of %d seats", confirmed, capacity)
+	}
+	var stored int64
+	conn.Model(&models.Registration{}).Where("class_id = ? AND status = ?", class.ID, StatusConfirmed).Count(&stored)
+	if stored != capacity {
+		t.Errorf("%d confirmed rows in the database for %d seats", stored, capacity)
+	}
+}
+
+func TestRegister_RejectsDuplicatesAndClosedSignup(t *testing.T) {
+	f := seedTrash(t, 10)
+	if _, err := Register(f.kid.ID, f.class.ID, nil, admin); !errors.Is(err, ErrDuplicateReg) {
+		t.Fatalf("second signup: got %v, want ErrDuplicateReg", err)
+	}
+
+	opens := time.Now().Add(time.Hour)
+	later := models.Class{Name: "FJB Stars Club", Date: time.Now().AddDate(0, 0, 10), Capacity: 10, SignupOpensAt: &opens}
+	db.Conn().Create(&later)
+	if _, err := Register(f.kid.ID, later.ID, nil, admin); !errors.Is(err, ErrNotOpenYet) {
+		t.Fatalf("before signup opens: got %v, want ErrNotOpenYet", err)
+	}
+
+	q := models.ClassQuestion{ClassID: &f.class.ID, Label: "Allergies", Kind: "text"}
+	db.Conn().Create(&q)
+	other := models.Class{Name: "FJB Awesome Kids", Date: time.Now().AddDate(0, 0, 20), Capacity: 1}
+	db.Conn().Create(&other)
+	res, err := Register(f.kid.ID, other.ID, map[uint]string{q.ID: "peanuts"}, admin)
+	if err != nil || res.Status != StatusConfirmed || res.Rank != 0 || res.Code == "" {
+		t.Fatalf("Register = %+v, %v", res, err)
+	}
+	var ans models.RegistrationAnswer
+	if err := db.Conn().Where("registration_id = ?", res.Registration.ID).First(&ans).Error; err != nil || ans.Answer != "peanuts" {
+		t.Errorf("answer = %+v, %v", ans, err)
+	}
+}
+
+// Two seats left and three siblings: kept together they all wait; split, the
+// first two take the seats.
+func TestRegisterSiblings_KeepTogether(t *testing.T) {
+	for _, together := range []bool{true, false} {
+		f := seedTrash(t, 3) // Budi holds one of the three seats
+		class := f.class
+		var kids []Signup
+		for _, name := range []string{"Sari", "Tono", "Wati"} {
+			k := models.Child{Name: name, ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+			db.Conn().Create(&k)
+			kids = append(kids, Signup{ChildID: k.ID})
+		}
+		res, err := RegisterSiblings(class.ID, kids, together, admin)
+		if err != nil || len(res) != 3 {
+			t.Fatalf("together=%v: %v, %v", together, res, err)
+		}
+		want := []Status{StatusWaitlisted, StatusWaitlisted, StatusWaitlisted}
+		if !together {
+			want = []Status{StatusConfirmed, StatusConfirmed, StatusWaitlisted}
+		}
+		for i, r := range res {
+			if r.Status != want[i] {
+				t.Errorf("together=%v: child %d is %s, want %s", together, i, r.Status, want[i])
+			}
+		}
+		if together && (res[0].Rank != 1 || res[2].Rank != 3) {
+			t.Errorf("ranks %d..%d, want 1..3", res[0].Rank, res[2].Rank)
+		}
+	}
+}
+
+func TestRegisterSiblings_AllOrNothing(t *testing.T) {
+	f := seedTrash(t, 10)
+	sari := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	db.Conn().Create(&sari)
+
+	// Budi is already on the class, so Sari is not signed up either.
+	_, err := RegisterSiblings(f.class.ID, []Signup{{ChildID: sari.ID}, {ChildID: f.kid.ID}}, false, admin)
+	if !errors.Is(err, ErrDuplicateReg) {
+		t.Fatalf("got %v, want ErrDuplicateReg", err)
+	}
+	if n := count(t, &models.Registration{}); n != 1 {
+		t.Errorf("%d registrations after a failed sibling signup, want 1", n)
+	}
+
+	other := models.Parent{Name: "Ibu Ani", Phone: "+628222"}
+	CreateParent(db.Conn(), &other)
+	stranger := models.Child{Name: "Andi", ParentID: other.ID, HouseholdID: other.HouseholdID}
+	db.Conn().Create(&stranger)
+	if _, err := RegisterSiblings(f.class.ID, []Signup{{ChildID: sari.ID}, {ChildID: stranger.ID}}, false, admin); !errors.Is(err, ErrNotSiblings) {
+		t.Fatalf("mixed households: got %v, want ErrNotSiblings", err)
+	}
+}
+
+func TestEligibleChildren_SkipsHeldAndOverlapping(t *testing.T) {
+	f := seedTrash(t, 10)
+	sari := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	db.Conn().Create(&sari)
+	sameDay := models.Class{Name: "FJB Stars Club", Date: f.class.Date, Capacity: 10}
+	db.Conn().Create(&sameDay)
+	nextWeek := models.Class{Name: "FJB Awesome Kids", Date: f.class.Date.AddDate(0, 0, 7), Capacity: 10}
+	db.Conn().Create(&nextWeek)
+
+	got, err := EligibleChildren([]uint{f.kid.ID, sari.ID}, []uint{f.class.ID, sameDay.ID, nextWeek.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got[f.class.ID]) != fmt.Sprint([]uint{sari.ID}) ||
+		fmt.Sprint(got[sameDay.ID]) != fmt.Sprint([]uint{sari.ID}) ||
+		fmt.Sprint(got[nextWeek.ID]) != fmt.Sprint([]uint{f.kid.ID, sari.ID}) {
+		t.Errorf("EligibleChildren = %v", got)
+	}
+}
@@ -13,7 +13,9 @@ import (
 
 var (
 	ErrDuplicateReg = errors.New("already registered for this class")
-	ErrSameDayReg   = errors.New("already registered for another class on that day")
+	// ErrSameDayReg: the child already holds a seat in a class that runs at
+	// the same time. Classes without times count as all day.
+	ErrSameDayReg = errors.New("already registered for another class at that time")
 )
 
 // RecomputeClass enforces capacity and, if anyone is promoted from waitlist → confirmed,
@@ -33,19 +35,20 @@ func RecomputeClass(classID uint) error {
 }
 
 // CancelByCode marks a registration canceled, rebalances, and triggers promotion events.
-func CancelByCode(code string) error {
+// by is recorded in the registration's history. Canceling twice is a no-op.
+func CancelByCode(code string, by Actor) error {
 	var promoted []models.Registration
+	var reg models.Registration
+	canceled := false
 	err := db.Conn().Transaction(func(tx *gorm.DB) error {
-		var reg models.Registration
 		if err := tx.Where("code = ?", code).First(&reg).Error; err != nil {
 			return err
 		}
-		if reg.Status != "canceled" {
-			reg.Status = "canceled"
-			reg.CheckInAt = nil
-			if err := tx.Save(&reg).Error; err != nil {
+		if StatusOf(reg) != StatusCanceled {
+			if err := applyTx(tx, &reg, models.EventCanceled, by, ""); err != nil {
 				return err
 			}
+			canceled = true
 		}
 		var err error
 		promoted, err = recomputeClassTxCollect(tx, reg.ClassID)
@@ -54,6 +57,9 @@ func CancelByCode(code string) error {
 	if err != nil {
 		return err
 	}
+	if canceled {
+		publishChange(reg, models.EventCanceled, by)
+	}
 	notifyPromotions(promoted)
 	return nil
 }
@@ -67,39 +73,45 @@ func RecomputeClassTx(tx *gorm.DB, classID uint) error {
 
 func recomputeClassTxCollect(tx *gorm.DB, classID uint) ([]models.Registration, error) {
     var class models.Class
-    if err := tx.First(&class, classID).Error; err != nil {
+    if err := tx.Preload("Campus").First(&class, classID).Error; err != nil {
         return nil, err
     }
+    // A session that is over has no seats to give: a cancel or a no-show
+    // there must not promote anyone into it.
+    if class.End().Before(time.Now()) {
+        return nil, nil
+    }
 
     // 1. Load confirmed (FIFO preserved)
     var confirmed []models.Registration
     if err := tx.
-        Where("class_id = ? AND status = 'confirmed'", classID).
+        Where("class_id = ? AND status = ?", classID, StatusConfirmed).
         Order("created_at asc, id asc").
         Find(&confirmed).Error; err != nil {
         return nil, err
     }
 
-    // 2. Load waitlist FIFO
-    var waitlist []models.Registration
-    if err := tx.
-        Where("class_id = ? AND status = 'waitlisted'", classID).
-        Order("created_at asc, id asc").
-        Find(&waitlist).Error; err != nil {
+    // 2. Load waitlist in the class's policy order (FIFO by default)
+    waitlist, err := WaitlistOrder(tx, class)
+    if err != nil {
         return nil, err
     }
 
     promoted := []models.Registration{}
 
-    // 3. Promote oldest waitlisted until capacity filled
+    // 3. Promote from the front of the waitlist until capacity filled
     slots := class.Capacity - len(confirmed)
     if slots > 0 {
         for i := 0; i < slots && i < len(waitlist); i++ {
-            waitlist[i].Status = "confirmed"
-            if err := tx.Save(&waitlist[i]).Error; err != nil {
+            reg := waitlist[i].Registration
+            note := ""
+            if waitlist[i].Reason != "" {
+                note = "waitlist: " + waitlist[i].Reason
+            }
+            if err := applyTx(tx, &reg, models.EventPromoted, System, note); err != nil {
                 return nil, err
             }
-            promoted = append(promoted, waitlist[i])
+            promoted = append(promoted, reg)
         }
     }
 
@@ -109,19 +121,28 @@ func recomputeClassTxCollect(tx *gorm.DB, classID uint) ([]models.Registration,
 
 // internal: fire events for promotions
 func notifyPromotions(promoted []models.Registration) {
-	if len(promoted) == 0 || events.OnPromotion == nil {
-		return
-	}
 	for _, r := range promoted {
-		events.OnPromotion(r)
+		publishChange(r, models.EventPromoted, System)
+		if events.OnPromotion != nil {
+			events.OnPromotion(r)
+		}
 	}
 }
 
+// CheckRegistrationConflicts reports ErrDuplicateReg when the child already
+// holds a seat or waitlist place in the class, ErrSameDayReg when it does
+// in another class at the same time, and an *AgeError (ErrAgeIneligible)
+// when the child is outside the class's ages. The age check comes last, so
+// an admin overriding it knows nothing else is wrong.
 func CheckRegistrationConflicts(childID, classID uint) error {
+	return checkRegistrationConflicts(db.Conn(), childID, classID)
+}
+
+func checkRegistrationConflicts(tx *gorm.DB, childID, classID uint) error {
 	// 1) same class?
 	var dup int64
-	if err := db.Conn().Model(&models.Registration{}).
-		Where("child_id = ? AND class_id = ? AND status IN ?", childID, classID, []string{"confirmed", "waitlisted"}).
+	if err := tx.Model(&models.Registration{}).
+		Where("child_id = ? AND class_id = ? AND status IN ?", childID, classID, []Status{StatusConfirmed, StatusWaitlisted}).
 		Count(&dup).Error; err != nil {
 		return err
 	}
@@ -129,30 +150,92 @@ func CheckRegistrationConflicts(childID, classID uint) error {
 		return ErrDuplicateReg
 	}
 
-	// Load class date
 	var class models.Class
-	if err := db.Conn().First(&class, classID).Error; err != nil {
+	if err := tx.Preload("Campus").First(&class, classID).Error; err != nil {
 		return err
 	}
 
-	// 2) same day (Asia/Jakarta)
-	loc, _ := time.LoadLocation("Asia/Jakarta")
-	day := class.Date.In(loc)
-	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
-	end := start.Add(24 * time.Hour)
+	// 2) overlapping times. Candidates are the child's classes from the day
+	// before to the day after; Overlaps decides.
+	var others []models.Class
+	if err := tx.Preload("Campus").
+		Where("id <> ? AND date > ? AND date < ?", class.ID, class.Date.AddDate(0, 0, -2), class.End().AddDate(0, 0, 1)).
+		Where("id IN (?)", tx.Model(&models.Registration{}).Select("class_id").
+			Where("child_id = ? AND status IN ?", childID, []Status{StatusConfirmed, StatusWaitlisted})).
+		Find(&others).Error; err != nil {
+		return err
+	}
+	for _, o := range others {
+		if class.Overlaps(o) {
+			return ErrSameDayReg
+		}
+	}
 
-	// compare against other classes that day
-	var dayCnt int64
-	if err := db.Conn().Model(&models.Registration{}).
-		Joins("JOIN classes ON classes.id = registrations.class_id").
-		Where("registrations.child_id = ? AND registrations.status IN ?", childID, []string{"confirmed", "waitlisted"}).
-		Where("classes.date >= ? AND classes.date < ?", start.UTC(), end.UTC()).
-		Count(&dayCnt).Error; err != nil {
+	// 3) old enough, young enough?
+	var child models.Child
+	if err := tx.First(&child, childID).Error; err != nil {
 		return err
 	}
-	if dayCnt > 0 {
-		return ErrSameDayReg
+	return CheckAge(child, class)
+}
+
+// EligibleChildren says, for each class in classIDs, which of childIDs are
+// free for it: not on it already and not busy at that time. It is
+// CheckRegistrationConflicts for a whole class picker at once, less the age
+// check, which the picker shows on its own (CheckAge).
+func EligibleChildren(childIDs, classIDs []uint) (map[uint][]uint, error) {
+	out := make(map[uint][]uint, len(classIDs))
+	if len(childIDs) == 0 || len(classIDs) == 0 {
+		return out, nil
+	}
+	var classes []models.Class
+	if err := db.Conn().Preload("Campus").Where("id IN ?", classIDs).Find(&classes).Error; err != nil {
+		return nil, err
+	}
+	type held struct {
+		ChildID uint
+		ClassID uint
+	}
+	var regs []held
+	if err := db.Conn().Model(&models.Registration{}).
+		Select("child_id, class_id").
+		Where("child_id IN ? AND status IN ?", childIDs, []Status{StatusConfirmed, StatusWaitlisted}).
+		Scan(&regs).Error; err != nil {
+		return nil, err
+	}
+	heldIDs := map[uint]bool{}
+	for _, h := range regs {
+		heldIDs[h.ClassID] = true
+	}
+	ids := make([]uint, 0, len(heldIDs))
+	for id := range heldIDs {
+		ids = append(ids, id)
+	}
+	var heldClasses []models.Class
+	if err := db.Conn().Preload("Campus").Where("id IN ?", ids).Find(&heldClasses).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Class, len(heldClasses))
+	for _, c := range heldClasses {
+		byID[c.ID] = c
 	}
 
-	return nil
+	for _, class := range classes {
+	kids:
+		for _, kid := range childIDs {
+			for _, h := range regs {
+				if h.ChildID != kid {
+					continue
+				}
+				if h.ClassID == class.ID {
+					continue kids
+				}
+				if o, ok := byID[h.ClassID]; ok && class.Overlaps(o) {
+					continue kids
+				}
+			}
+			out[class.ID] = append(out[class.ID], kid)
+		}
+	}
+	return out, nil
 }
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Budi is in the 9:00–10:30 session; the afternoon session and the next day
+// are free, anything overlapping the morning is not. A class without times
+// fills the whole day, as every class did before times were recorded.
+func TestCheckRegistrationConflicts_OnlyOverlappingTimes(t *testing.T) {
+	f := seedTrash(t, 10)
+	loc := models.Campus{}.Location()
+	d := f.class.Date.In(loc)
+	day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+	at := func(days, h, m int) *time.Time {
+		t := day.AddDate(0, 0, days).Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+		return &t
+	}
+	db.Conn().Model(&f.class).Updates(map[string]any{"date": day, "starts_at": at(0, 9, 0), "ends_at": at(0, 10, 30)})
+
+	cases := []struct {
+		name     string
+		class    models.Class
+		conflict bool
+	}{
+		{"afternoon", models.Class{Date: day, StartsAt: at(0, 16, 0), EndsAt: at(0, 17, 30)}, false},
+		{"right after", models.Class{Date: day, StartsAt: at(0, 10, 30), EndsAt: at(0, 12, 0)}, false},
+		{"overlapping", models.Class{Date: day, StartsAt: at(0, 10, 0), EndsAt: at(0, 11, 0)}, true},
+		{"start only", models.Class{Date: day, StartsAt: at(0, 8, 0)}, true},
+		{"all day", models.Class{Date: day}, true},
+		{"next day", models.Class{Date: day.AddDate(0, 0, 1), StartsAt: at(1, 9, 0), EndsAt: at(1, 10, 30)}, false},
+	}
+	for _, c := range cases {
+		c.class.Name = "FJB Stars Club " + c.name
+		c.class.Capacity = 10
+		db.Conn().Create(&c.class)
+		err := CheckRegistrationConflicts(f.kid.ID, c.class.ID)
+		if c.conflict && err != ErrSameDayReg {
+			t.Errorf("%s: got %v, want ErrSameDayReg", c.name, err)
+		}
+		if !c.conflict && err != nil {
+			t.Errorf("%s: got %v, want no conflict", c.name, err)
+		}
+	}
+}
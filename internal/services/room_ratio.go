@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Live change kinds for volunteers entering and leaving a room. They ride the
+// same stream as registration events so stations redraw their ratios.
+const (
+	LiveStaffIn  = "staff_in"
+	LiveStaffOut = "staff_out"
+)
+
+// ErrStaffName is returned when nobody is named for a room.
+var ErrStaffName = errors.New("volunteer name is required")
+
+// Ratio is a room's live headcount against its child-to-adult ratio.
+type Ratio struct {
+	Kids         int      // checked in and not picked up yet
+	Adults       int      // volunteers recorded in the room
+	KidsPerAdult int      // the room's limit; 0 means none
+	Staff        []string // the adults' names, in the order they came in
+}
+
+// Enforced reports whether the class has a ratio at all.
+func (r Ratio) Enforced() bool { return r.KidsPerAdult > 0 }
+
+// Over reports whether the room has more children than its adults may
+// look after.
+func (r Ratio) Over() bool {
+	return r.Enforced() && r.Kids > r.Adults*r.KidsPerAdult
+}
+
+// AdultsNeeded is how many adults the children in the room call for.
+func (r Ratio) AdultsNeeded() int {
+	if !r.Enforced() {
+		return 0
+	}
+	return (r.Kids + r.KidsPerAdult - 1) / r.KidsPerAdult
+}
+
+// Short is how many more adults the room needs right now.
+func (r Ratio) Short() int {
+	if n := r.AdultsNeeded() - r.Adults; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// RoomRatios returns the live ratio of the room each class in classIDs meets
+// in, read through tx (db.Conn() outside a transaction). Classes at the same
+// campus in the same room at overlapping times share it: their children and
+// volunteers are counted together against the strictest of their ratios. A
+// class without a room is a room of its own.
+func RoomRatios(tx *gorm.DB, classIDs []uint) (map[uint]Ratio, error) {
+	out := make(map[uint]Ratio, len(classIDs))
+	if len(classIDs) == 0 {
+		return out, nil
+	}
+	var classes []models.Class
+	if err := tx.Preload("Campus").Where("id IN ?", classIDs).Find(&classes).Error; err != nil {
+		return nil, err
+	}
+
+	// mates[c] is every class sharing c's room, c included.
+	mates := make(map[uint]map[uint]bool, len(classes))
+	byID := make(map[uint]models.Class, len(classes))
+	var from, until time.Time
+	for _, c := range classes {
+		mates[c.ID] = map[uint]bool{c.ID: true}
+		byID[c.ID] = c
+		if c.Room == "" {
+			continue
+		}
+		if from.IsZero() || c.Date.Before(from) {
+			from = c.Date
+		}
+		if c.Date.After(until) {
+			until = c.Date
+		}
+	}
+	if !from.IsZero() {
+		var others []models.Class
+		if err := tx.Preload("Campus").
+			Where("room <> '' AND date BETWEEN ? AND ? AND id NOT IN ?", from.AddDate(0, 0, -1), until.AddDate(0, 0, 1), classIDs).
+			Find(&others).Error; err != nil {
+			return nil, err
+		}
+		pool := append(append([]models.Class{}, classes...), others...)
+		for _, c := range classes {
+			for _, o := range pool {
+				if o.ID != c.ID && sameRoom(c, o) && c.Overlaps(o) {
+					mates[c.ID][o.ID] = true
+					byID[o.ID] = o
+				}
+			}
+		}
+	}
+	ids := make([]uint, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	type kidRow struct {
+		ClassID uint
+		N       int
+	}
+	var kidRows []kidRow
+	if err := tx.Table("registrations").
+		Select("class_id, COUNT(*) AS n").
+		Where("deleted_at IS NULL AND class_id IN ?", ids).
+		Where("status = ? AND check_in_at IS NOT NULL AND check_out_at IS NULL", StatusConfirmed).
+		Group("class_id").
+		Scan(&kidRows).Error; err != nil {
+		return nil, err
+	}
+	kids := make(map[uint]int, len(kidRows))
+	for _, k := range kidRows {
+		kids[k.ClassID] = k.N
+	}
+
+	var staff []models.RoomStaff
+	if err := tx.Where("class_id IN ? AND out_at IS NULL", ids).
+		Order("in_at ASC, id ASC").Find(&staff).Error; err != nil {
+		return nil, err
+	}
+
+	for _, c := range classes {
+		var r Ratio
+		for id := range mates[c.ID] {
+			r.Kids += kids[id]
+			if k := byID[id].KidsPerAdult; k > 0 && (r.KidsPerAdult == 0 || k < r.KidsPerAdult) {
+				r.KidsPerAdult = k
+			}
+		}
+		for _, s := range staff {
+			if mates[c.ID][s.ClassID] {
+				r.Adults++
+				r.Staff = append(r.Staff, s.Name)
+			}
+		}
+		out[c.ID] = r
+	}
+	return out, nil
+}
+
+// sameRoom reports whether a and b name the same room at the same campus.
+func sameRoom(a, b models.Class) bool {
+	if (a.CampusID == nil) != (b.CampusID == nil) || (a.CampusID != nil && *a.CampusID != *b.CampusID) {
+		return false
+	}
+	ra, rb := strings.TrimSpace(a.Room), strings.TrimSpace(b.Room)
+	return ra != "" && strings.EqualFold(ra, rb)
+}
+
+// RoomRatio is RoomRatios for one class.
+func RoomRatio(classID uint) (Ratio, error) {
+	m, err := RoomRatios(db.Conn(), []uint{classID})
+	return m[classID], err
+}
+
+// StaffIn records name as on duty in classID's room. A volunteer is in one
+// room at a time, so joining a room leaves any other; joining the room they
+// are already in changes nothing.
+func StaffIn(classID uint, name string, by Actor) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrStaffName
+	}
+	now := time.Now()
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var here int64
+		if err := tx.Model(&models.RoomStaff{}).
+			Where("class_id = ? AND name = ? AND out_at IS NULL", classID, name).
+			Count(&here).Error; err != nil || here > 0 {
+			return err
+		}
+		if err := tx.Model(&models.RoomStaff{}).
+			Where("name = ? AND out_at IS NULL", name).
+			Update("out_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RoomStaff{ClassID: classID, Name: name, InAt: now, AddedBy: by.Name}).Error
+	})
+	if err == nil {
+		publishRoom(classID, LiveStaffIn, by)
+	}
+	return err
+}
+
+// StaffOut records name leaving classID's room.
+func StaffOut(classID uint, name string, by Actor) error {
+	err := db.Conn().Model(&models.RoomStaff{}).
+		Where("class_id = ? AND name = ? AND out_at IS NULL", classID, strings.TrimSpace(name)).
+		Update("out_at", time.Now()).Error
+	if err == nil {
+		publishRoom(classID, LiveStaffOut, by)
+	}
+	return err
+}
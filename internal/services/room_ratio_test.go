@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestRoomRatio_CountsKidsInRoomAgainstStaff(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	conn.Model(&f.class).Update("kids_per_adult", 1)
+	now := time.Now()
+	conn.Model(&f.reg).Update("check_in_at", now)
+	// A second child, already picked up, is no longer in the room.
+	kid2 := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&kid2)
+	conn.Create(&models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: kid2.ID,
+		ClassID: f.class.ID, Status: "confirmed", Code: "REG-T2", CheckInAt: &now, CheckOutAt: &now})
+
+	r, err := RoomRatio(f.class.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Kids != 1 || r.Adults != 0 || !r.Over() || r.Short() != 1 {
+		t.Fatalf("nobody on duty: %+v over=%v short=%d", r, r.Over(), r.Short())
+	}
+
+	if err := StaffIn(f.class.ID, " Kak Rina ", admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := StaffIn(f.class.ID, "Kak Rina", admin); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = RoomRatio(f.class.ID)
+	if r.Adults != 1 || r.Over() || len(r.Staff) != 1 || r.Staff[0] != "Kak Rina" {
+		t.Fatalf("after staff in: %+v", r)
+	}
+
+	// Moving to another room leaves this one.
+	other := models.Class{Name: "FJB Stars Club", Date: f.class.Date, Capacity: 10}
+	conn.Create(&other)
+	if err := StaffIn(other.ID, "Kak Rina", admin); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = RoomRatio(f.class.ID); r.Adults != 0 || !r.Over() {
+		t.Fatalf("after moving rooms: %+v", r)
+	}
+	if err := StaffOut(other.ID, "Kak Rina", admin); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = RoomRatio(other.ID); r.Adults != 0 {
+		t.Fatalf("after staff out: %+v", r)
+	}
+
+	if err := StaffIn(f.class.ID, "  ", admin); err != ErrStaffName {
+		t.Fatalf("blank name: got %v, want ErrStaffName", err)
+	}
+}
+
+func TestRoomRatio_NoRatioIsNeverOver(t *testing.T) {
+	r := Ratio{Kids: 30}
+	if r.Enforced() || r.Over() || r.Short() != 0 {
+		t.Fatalf("unenforced ratio: %+v", r)
+	}
+	r = Ratio{Kids: 17, Adults: 2, KidsPerAdult: 8}
+	if !r.Over() || r.AdultsNeeded() != 3 || r.Short() != 1 {
+		t.Fatalf("17 kids, 2 adults at 1:8: over=%v needed=%d", r.Over(), r.AdultsNeeded())
+	}
+}
+
+// Two classes meeting in the same room at the same time are one room: their
+// children and adults count together, and a class down the hall does not.
+func TestRoomRatios_SharedRoomCountsTogether(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	start := time.Now().Add(-30 * time.Minute)
+	end := start.Add(2 * time.Hour)
+	room := func(name, where string, perAdult int) models.Class {
+		c := models.Class{Name: name, Date: start, StartsAt: &start, EndsAt: &end, Room: where, Capacity: 10, KidsPerAdult: perAdult}
+		conn.Create(&c)
+		return c
+	}
+	tots := room("Tots", "Ruang 3", 2)
+	stars := room("Stars", " ruang 3", 0)
+	hall := room("Club", "Ruang 4", 2)
+	now := time.Now()
+	for i, c := range []models.Class{tots, stars, stars, hall} {
+		kid := models.Child{Name: "Anak", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+		conn.Create(&kid)
+		conn.Create(&models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: kid.ID,
+			ClassID: c.ID, Status: "confirmed", Code: "REG-R" + string(rune('A'+i)), CheckInAt: &now})
+	}
+	if err := StaffIn(tots.ID, "Kak Rina", admin); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := RoomRatios(conn, []uint{tots.ID, stars.ID, hall.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{tots.ID, stars.ID} {
+		if r := got[id]; r.Kids != 3 || r.Adults != 1 || r.KidsPerAdult != 2 || !r.Over() {
+			t.Errorf("shared room via class %d = %+v, want 3 kids, 1 adult, 2 per adult, over", id, r)
+		}
+	}
+	if r := got[hall.ID]; r.Kids != 1 || r.Adults != 0 {
+		t.Errorf("other room = %+v, want 1 kid, no adults", r)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// securityAlphabet leaves out letters and digits that read alike on a
+// thermal print (0/O, 1/I/L, 2/Z, 5/S, 8/B).
+const securityAlphabet = "ACDEFGHJKMNPQRTUVWXY34679"
+
+// SecurityCodeLen is short enough to read aloud at the door.
+const SecurityCodeLen = 4
+
+// ErrSecurityCode is returned when the code presented at pickup does not
+// match the one printed at check-in.
+var ErrSecurityCode = errors.New("security code does not match")
+
+// NewSecurityCode draws a random code for a check-in.
+func NewSecurityCode() (string, error) {
+	b := make([]byte, SecurityCodeLen)
+	max := big.NewInt(int64(len(securityAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = securityAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// SecurityCodeMatches compares a presented code with the stored one, ignoring
+// case and spaces. Registrations checked in before codes existed have none
+// and match anything.
+func SecurityCodeMatches(stored, presented string) bool {
+	if stored == "" {
+		return true
+	}
+	presented = strings.ToUpper(strings.ReplaceAll(presented, " ", ""))
+	return presented == stored
+}
+
+// NormSecurityCode upper-cases a code drawn elsewhere, such as on a station
+// that was offline, and returns "" unless it is one NewSecurityCode could
+// have drawn.
+func NormSecurityCode(s string) string {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(s) != SecurityCodeLen {
+		return ""
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(securityAlphabet, r) {
+			return ""
+		}
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// ErrNoSessionsLeft is returned by RegisterSeries when no session of the
+// series is left to sign up for.
+var ErrNoSessionsLeft = errors.New("no sessions left in this series")
+
+// SeriesDates lists the days s meets on from from up to, not including,
+// until: every IntervalWeeks weeks from FirstDate, minus the blackouts.
+// Days are midnights at the series' campus, so load s.Campus.
+func SeriesDates(s models.ClassSeries, from, until time.Time) []time.Time {
+	loc := s.Location()
+	step := s.IntervalWeeks
+	if step < 1 {
+		step = 1
+	}
+	blackout := map[string]bool{}
+	for _, d := range strings.Split(s.Blackouts, ",") {
+		blackout[strings.TrimSpace(d)] = true
+	}
+	f := s.FirstDate.In(loc)
+	day := time.Date(f.Year(), f.Month(), f.Day(), 0, 0, 0, 0, loc)
+	var out []time.Time
+	for ; day.Before(until); day = day.AddDate(0, 0, 7*step) {
+		if day.Before(from) || blackout[day.Format("2006-01-02")] {
+			continue
+		}
+		out = append(out, day)
+	}
+	return out
+}
+
+// GenerateSessions makes sure s has a class for every session in the next
+// weeks weeks. Days that already have one — even one sent to the trash — are
+// left alone, so running it again only fills in what is missing. It returns
+// the classes it made.
+func GenerateSessions(seriesID uint, weeks int) ([]models.Class, error) {
+	var made []models.Class
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var s models.ClassSeries
+		if err := tx.Preload("Campus").First(&s, seriesID).Error; err != nil {
+			return err
+		}
+		loc := s.Location()
+		now := time.Now().In(loc)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+		var have []models.Class
+		if err := tx.Unscoped().Select("date").Where("series_id = ?", s.ID).Find(&have).Error; err != nil {
+			return err
+		}
+		taken := map[string]bool{}
+		for _, c := range have {
+			taken[c.Date.In(loc).Format("2006-01-02")] = true
+		}
+
+		for _, day := range SeriesDates(s, today, today.AddDate(0, 0, 7*weeks)) {
+			if taken[day.Format("2006-01-02")] {
+				continue
+			}
+			c := models.Class{
+				Name:         s.Name,
+				Description:  s.Description,
+				CampusID:     s.CampusID,
+				Date:         day,
+				StartsAt:     seriesTime(day, s.StartTime),
+				EndsAt:       seriesTime(day, s.EndTime),
+				Room:         s.Room,
+				Capacity:     s.Capacity,
+				WalkInSeats:  s.WalkInSeats,
+				KidsPerAdult: s.KidsPerAdult,
+				MinAge:       s.MinAge,
+				MaxAge:       s.MaxAge,
+				SeriesID:     &s.ID,
+			}
+			if err := tx.Create(&c).Error; err != nil {
+				return err
+			}
+			made = append(made, c)
+		}
+		return nil
+	})
+	return made, err
+}
+
+// seriesTime puts the "15:04" hm on day, on the clock of day's location
+// (the series' campus); nil when hm is blank.
+func seriesTime(day time.Time, hm string) *time.Time {
+	t, err := time.Parse("15:04", strings.TrimSpace(hm))
+	if err != nil {
+		return nil
+	}
+	at := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+	return &at
+}
+
+// RemainingSessions returns the sessions of seriesID that have not started
+// yet, soonest first.
+func RemainingSessions(seriesID uint) ([]models.Class, error) {
+	var out []models.Class
+	err := db.Conn().
+		Where("series_id = ? AND COALESCE(starts_at, date) > ?", seriesID, time.Now()).
+		Order("COALESCE(starts_at, date) ASC").
+		Find(&out).Error
+	return out, err
+}
+
+// SessionResult is how a RegisterSeries signup went for one session.
+type SessionResult struct {
+	Class   models.Class
+	Results []RegisterResult // one per child signed up, in the order given
+}
+
+// RegisterSeries signs kids up for every remaining session of seriesID in
+// one step. Each session is still its own class: seats and waitlist are
+// decided per session, exactly as if the parent had registered for each
+// one, and together keeps siblings side by side in each. A child already
+// on a session, busy at that time or outside its ages is just left out of
+// it, as is a session whose signup has not opened. Either every session is
+// registered or, on an error, none.
+func RegisterSeries(seriesID uint, kids []Signup, together bool, by Actor) ([]SessionResult, error) {
+	sessions, err := RemainingSessions(seriesID)
+	if err != nil {
+		return nil, err
+	}
+	var out []SessionResult
+	err = db.Conn().Transaction(func(tx *gorm.DB) error {
+		children, err := loadSiblings(tx, kids)
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			class, err := lockClass(tx, s.ID)
+			if err != nil {
+				return err
+			}
+			if !signupOpen(class) {
+				continue
+			}
+			// Answers are asked for every session at once; each keeps
+			// those to its own questions and the series'.
+			var asked []uint
+			if err := tx.Model(&models.ClassQuestion{}).
+				Where("class_id = ? OR series_id = ?", class.ID, seriesID).
+				Pluck("id", &asked).Error; err != nil {
+				return err
+			}
+			var free []Signup
+			var freeKids []models.Child
+			for i, k := range kids {
+				switch err := checkRegistrationConflicts(tx, k.ChildID, class.ID); {
+				case err == nil:
+					k.Answers = answersFor(k.Answers, asked)
+					free = append(free, k)
+					freeKids = append(freeKids, children[i])
+				case errors.Is(err, ErrDuplicateReg), errors.Is(err, ErrSameDayReg), errors.Is(err, ErrAgeIneligible):
+				default:
+					return err
+				}
+			}
+			if len(free) == 0 {
+				continue
+			}
+			res, err := registerTx(tx, class, freeKids, free, together, by)
+			if err != nil {
+				return err
+			}
+			out = append(out, SessionResult{Class: class, Results: res})
+		}
+		if len(out) == 0 {
+			return ErrNoSessionsLeft
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// answersFor keeps the answers to the questions in ids.
+func answersFor(answers map[uint]string, ids []uint) map[uint]string {
+	out := make(map[uint]string, len(ids))
+	for _, id := range ids {
+		if a, ok := answers[id]; ok {
+			out[id] = a
+		}
+	}
+	return out
+}
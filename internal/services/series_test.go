@@ -0,0 +1,139 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func jakartaDay(t time.Time) time.Time {
+	loc := models.Campus{}.Location()
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func TestSeriesDates_StepsWeeksAndSkipsBlackouts(t *testing.T) {
+	first := jakartaDay(time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC)) // a Sunday
+	s := models.ClassSeries{FirstDate: first, IntervalWeeks: 2, Blackouts: "2026-02-01"}
+	var days []string
+	for _, d := range SeriesDates(s, first.AddDate(0, 0, 1), first.AddDate(0, 0, 70)) {
+		days = append(days, d.Format("Mon 2006-01-02"))
+	}
+	want := "Sun 2026-01-18,Sun 2026-02-15,Sun 2026-03-01"
+	if got := strings.Join(days, ","); got != want {
+		t.Errorf("dates = %s, want %s", got, want)
+	}
+}
+
+// Each session of a series is its own class: one child's seat in one week
+// says nothing about the next, and a week the child already holds is skipped.
+func TestRegisterSeries_DecidesEachSessionOnItsOwn(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	s := models.ClassSeries{Name: "FJB Weekly", FirstDate: jakartaDay(time.Now().AddDate(0, 0, 1)),
+		IntervalWeeks: 1, StartTime: "09:00", EndTime: "10:30", Capacity: 1}
+	conn.Create(&s)
+	q := models.ClassQuestion{SeriesID: &s.ID, Label: "Allergies", Kind: "text"}
+	conn.Create(&q)
+
+	made, err := GenerateSessions(s.ID, 3)
+	if err != nil || len(made) != 3 {
+		t.Fatalf("GenerateSessions = %d classes, %v", len(made), err)
+	}
+	if again, _ := GenerateSessions(s.ID, 3); len(again) != 0 {
+		t.Fatalf("second GenerateSessions made %d more", len(again))
+	}
+	if made[0].StartsAt == nil || made[0].StartsAt.Sub(made[0].Date) != 9*time.Hour {
+		t.Errorf("session starts at %v on %v", made[0].StartsAt, made[0].Date)
+	}
+
+	// Week 2 is already full; Budi already holds week 3.
+	sari := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&sari)
+	if _, err := Register(sari.ID, made[1].ID, nil, admin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Register(f.kid.ID, made[2].ID, nil, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := RegisterSeries(s.ID, []Signup{{ChildID: f.kid.ID, Answers: map[uint]string{q.ID: "none"}}}, false, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Class.ID != made[0].ID || res[1].Class.ID != made[1].ID {
+		t.Fatalf("signed up for %+v, want weeks 1 and 2", res)
+	}
+	if res[0].Results[0].Status != StatusConfirmed || res[1].Results[0].Status != StatusWaitlisted {
+		t.Errorf("statuses %s, %s; want confirmed, waitlisted", res[0].Results[0].Status, res[1].Results[0].Status)
+	}
+	var answers int64
+	conn.Model(&models.RegistrationAnswer{}).Where("question_id = ?", q.ID).Count(&answers)
+	if answers != 2 {
+		t.Errorf("%d answers saved, want one per session", answers)
+	}
+
+	if _, err := RegisterSeries(s.ID, []Signup{{ChildID: f.kid.ID}}, false, admin); err != ErrNoSessionsLeft {
+		t.Errorf("registering again: got %v, want ErrNoSessionsLeft", err)
+	}
+}
+
+// A series at a campus outside Jakarta meets on that campus's days and
+// clock: its sessions start at its midnight and its 09:00.
+func TestGenerateSessions_UsesCampusTimeZone(t *testing.T) {
+	seedTrash(t, 10)
+	conn := db.Conn()
+	campus := models.Campus{Code: "FJP", TimeZone: "Asia/Jayapura"}
+	conn.Create(&campus)
+	loc := campus.Location()
+	now := time.Now().In(loc)
+	first := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	s := models.ClassSeries{Name: "FJP Weekly", CampusID: &campus.ID, FirstDate: first,
+		IntervalWeeks: 1, StartTime: "09:00", Capacity: 5}
+	conn.Create(&s)
+
+	made, err := GenerateSessions(s.ID, 1)
+	if err != nil || len(made) != 1 {
+		t.Fatalf("GenerateSessions = %d classes, %v", len(made), err)
+	}
+	if !made[0].Date.Equal(first) {
+		t.Errorf("session day = %v, want %v", made[0].Date, first)
+	}
+	if at := made[0].StartsAt.In(loc); at.Hour() != 9 || at.Minute() != 0 {
+		t.Errorf("session starts at %v, want 09:00 in %s", at, loc)
+	}
+}
+
+// A question one session asks on its own is answered with the series', and
+// its answer is kept on that session's registration only.
+func TestRegisterSeries_KeepsSessionAnswersToTheirSession(t *testing.T) {
+	f := seedTrash(t, 10)
+	conn := db.Conn()
+	s := models.ClassSeries{Name: "FJB Weekly", FirstDate: jakartaDay(time.Now().AddDate(0, 0, 1)),
+		IntervalWeeks: 1, StartTime: "09:00", Capacity: 5}
+	conn.Create(&s)
+	made, err := GenerateSessions(s.ID, 2)
+	if err != nil || len(made) != 2 {
+		t.Fatalf("GenerateSessions = %d classes, %v", len(made), err)
+	}
+	shared := models.ClassQuestion{SeriesID: &s.ID, Label: "Allergies", Kind: "text"}
+	conn.Create(&shared)
+	trip := models.ClassQuestion{ClassID: &made[1].ID, Label: "Field trip permission", Kind: "text"}
+	conn.Create(&trip)
+
+	res, err := RegisterSeries(s.ID, []Signup{{ChildID: f.kid.ID,
+		Answers: map[uint]string{shared.ID: "none", trip.ID: "yes"}}}, false, admin)
+	if err != nil || len(res) != 2 {
+		t.Fatalf("RegisterSeries = %d sessions, %v", len(res), err)
+	}
+	for i, want := range []int64{1, 2} {
+		var n int64
+		conn.Model(&models.RegistrationAnswer{}).Where("registration_id = ?", res[i].Results[0].Registration.ID).Count(&n)
+		if n != want {
+			t.Errorf("week %d: %d answers saved, want %d", i+1, n, want)
+		}
+	}
+}
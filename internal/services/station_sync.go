@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+var ErrSyncKey = errors.New("offline check-in needs an idempotency key")
+
+// Conflict reasons a station is told about. The registration that comes back
+// with them says who checked the child in, or what became of the seat.
+const (
+	ConflictCheckedIn = "already_checked_in"
+	ConflictCanceled  = "canceled"
+	ConflictWaitlist  = "waitlisted"
+	ConflictDeleted   = "deleted"
+	// ConflictKeyReused: the account already used the key for another
+	// registration. Nothing is done; the device should make a fresh key.
+	ConflictKeyReused = "key_reused"
+)
+
+// OfflineCheckIn is a check-in a station recorded without a connection.
+type OfflineCheckIn struct {
+	Key       string // made up by the device, unique per check-in
+	AccountID uint   // the station account uploading it; keys are its own
+	RegID     uint
+	At        time.Time // device clock; zero or in the future means now
+	Device    string
+	// SecurityCode is the one the station printed offline, if it could.
+	// Without one the check-out does not ask for a code.
+	SecurityCode string
+}
+
+// SyncResult is what became of one offline check-in.
+type SyncResult struct {
+	Outcome      string // models.SyncApplied | models.SyncConflict
+	Reason       string // a Conflict* constant
+	Replay       bool   // the key was seen before; nothing was done now
+	Registration models.Registration
+}
+
+// SyncCheckIn applies one offline check-in, at most once per account and key.
+// A key the account already used for another registration is refused as a
+// ConflictKeyReused rather than replayed. A child that
+// another device checked in first, or whose registration was canceled or
+// trashed in the meantime, is a conflict rather than an error: the station
+// needs to show it, not retry it. Callers do the same fencing as for CheckIn.
+func SyncCheckIn(c OfflineCheckIn, by Actor, now time.Time) (SyncResult, error) {
+	var res SyncResult
+	if c.Key == "" {
+		return res, ErrSyncKey
+	}
+	at := c.At
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var seen models.StationSync
+		err := tx.Where("account_id = ? AND idem_key = ?", c.AccountID, c.Key).First(&seen).Error
+		switch {
+		case err == nil && seen.RegistrationID != c.RegID:
+			res.Outcome, res.Reason = models.SyncConflict, ConflictKeyReused
+			return tx.Unscoped().First(&res.Registration, c.RegID).Error
+		case err == nil:
+			res.Outcome, res.Reason, res.Replay = seen.Outcome, seen.Reason, true
+			return tx.Unscoped().First(&res.Registration, seen.RegistrationID).Error
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		reg := &res.Registration
+		if err := tx.Unscoped().First(reg, c.RegID).Error; err != nil {
+			return err
+		}
+		res.Outcome = models.SyncConflict
+		switch {
+		case reg.DeletedAt.Valid:
+			res.Reason = ConflictDeleted
+		case reg.CheckInAt != nil:
+			res.Reason = ConflictCheckedIn
+		case StatusOf(*reg) == StatusCanceled:
+			res.Reason = ConflictCanceled
+		case StatusOf(*reg) == StatusWaitlisted:
+			res.Reason = ConflictWaitlist
+		default:
+			reg.SecurityCode = NormSecurityCode(c.SecurityCode)
+			note := "offline"
+			if c.Device != "" {
+				note += " on " + c.Device
+			}
+			switch err := applyAtTx(tx, reg, models.EventCheckedIn, by, note, at); {
+			case errors.Is(err, ErrIllegalTransition):
+				res.Reason = ConflictDeleted // the class went to the trash
+			case err != nil:
+				return err
+			default:
+				res.Outcome = models.SyncApplied
+			}
+		}
+		return tx.Create(&models.StationSync{
+			AccountID:      c.AccountID,
+			IdemKey:        c.Key,
+			RegistrationID: reg.ID,
+			Device:         c.Device,
+			ClientAt:       at,
+			Outcome:        res.Outcome,
+			Reason:         res.Reason,
+		}).Error
+	})
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if res.Outcome == models.SyncApplied && !res.Replay {
+		publishChange(res.Registration, models.EventCheckedIn, by)
+	}
+	return res, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestSyncCheckIn_AppliesOnceAtDeviceTime(t *testing.T) {
+	f := seedTrash(t, 10)
+	now := time.Now()
+	at := now.Add(-20 * time.Minute)
+	c := OfflineCheckIn{Key: "ipad-1:1", RegID: f.reg.ID, At: at, Device: "ipad-1", SecurityCode: "ac3f"}
+
+	res, err := SyncCheckIn(c, admin, now)
+	if err != nil {
+		t.Fatalf("SyncCheckIn: %v", err)
+	}
+	if res.Outcome != models.SyncApplied || res.Replay {
+		t.Fatalf("first sync = %+v, want applied", res)
+	}
+	reg := res.Registration
+	if reg.CheckInAt == nil || !reg.CheckInAt.Equal(at) {
+		t.Errorf("CheckInAt = %v, want device time %v", reg.CheckInAt, at)
+	}
+	if reg.SecurityCode != "AC3F" {
+		t.Errorf("SecurityCode = %q, want the printed AC3F", reg.SecurityCode)
+	}
+
+	again, err := SyncCheckIn(c, admin, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Outcome != models.SyncApplied || !again.Replay {
+		t.Errorf("resent sync = %+v, want a replay of applied", again)
+	}
+	var n int
+	for _, e := range eventsOf(t, f.reg.ID) {
+		if e.Kind == models.EventCheckedIn {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("checked_in events = %d, want 1", n)
+	}
+}
+
+func TestSyncCheckIn_Conflicts(t *testing.T) {
+	f := seedTrash(t, 10)
+	now := time.Now()
+
+	// Another device got there first.
+	if _, err := CheckIn(f.reg.ID, Actor{Name: "Rina", Source: models.SourceWeb}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := SyncCheckIn(OfflineCheckIn{Key: "k1", RegID: f.reg.ID}, admin, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outcome != models.SyncConflict || res.Reason != ConflictCheckedIn || res.Registration.CheckedInBy != "Rina" {
+		t.Errorf("sync after live check-in = %+v, want conflict already_checked_in by Rina", res)
+	}
+
+	// Canceled while the station was offline.
+	sib := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: f.kid.ID, ClassID: f.class.ID, Status: "canceled", Code: "REG-T2"}
+	db.Conn().Create(&sib)
+	res, err = SyncCheckIn(OfflineCheckIn{Key: "k2", RegID: sib.ID}, admin, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outcome != models.SyncConflict || res.Reason != ConflictCanceled {
+		t.Errorf("sync of canceled = %+v, want conflict canceled", res)
+	}
+
+	if _, err := SyncCheckIn(OfflineCheckIn{RegID: sib.ID}, admin, now); !errors.Is(err, ErrSyncKey) {
+		t.Errorf("sync without key = %v, want ErrSyncKey", err)
+	}
+}
+
+func TestSyncCheckIn_FutureDeviceClockIsNow(t *testing.T) {
+	f := seedTrash(t, 10)
+	now := time.Now()
+	res, err := SyncCheckIn(OfflineCheckIn{Key: "k", RegID: f.reg.ID, At: now.Add(time.Hour)}, admin, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Registration.CheckInAt == nil || !res.Registration.CheckInAt.Equal(now) {
+		t.Errorf("CheckInAt = %v, want server time %v", res.Registration.CheckInAt, now)
+	}
+	if res.Registration.SecurityCode != "" {
+		t.Errorf("SecurityCode = %q, want none when the station sent none", res.Registration.SecurityCode)
+	}
+}
+
+// Keys belong to the station account that sent them: another account may
+// use the same key, but the same account reusing it for another child is
+// refused instead of replaying the first child's registration.
+func TestSyncCheckIn_KeysArePerAccount(t *testing.T) {
+	f := seedTrash(t, 10)
+	now := time.Now()
+	sib := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	db.Conn().Create(&sib)
+	other := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: sib.ID, ClassID: f.class.ID, Status: "confirmed", Code: "REG-T2"}
+	db.Conn().Create(&other)
+
+	if res, err := SyncCheckIn(OfflineCheckIn{Key: "k", AccountID: 1, RegID: f.reg.ID}, admin, now); err != nil || res.Outcome != models.SyncApplied {
+		t.Fatalf("first = %+v, %v", res, err)
+	}
+	res, err := SyncCheckIn(OfflineCheckIn{Key: "k", AccountID: 1, RegID: other.ID}, admin, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outcome != models.SyncConflict || res.Reason != ConflictKeyReused || res.Replay || res.Registration.ID != other.ID {
+		t.Errorf("reused key = %+v, want key_reused conflict on reg %d", res, other.ID)
+	}
+	res, err = SyncCheckIn(OfflineCheckIn{Key: "k", AccountID: 2, RegID: other.ID}, admin, now)
+	if err != nil || res.Outcome != models.SyncApplied || res.Replay {
+		t.Errorf("same key on another account = %+v, %v, want applied", res, err)
+	}
+}
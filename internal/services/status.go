@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Status is a registration's place in its life cycle. The column stays a
+// plain string in models.Registration; this is the type code reasons with.
+type Status string
+
+const (
+	StatusNew        Status = "" // not saved yet
+	StatusConfirmed  Status = "confirmed"
+	StatusWaitlisted Status = "waitlisted"
+	StatusCanceled   Status = "canceled"
+	StatusNoShow     Status = "no_show"
+)
+
+// Active reports whether the registration holds or waits for a seat.
+func (s Status) Active() bool {
+	return s == StatusConfirmed || s == StatusWaitlisted
+}
+
+// StatusOf is the typed status of a registration.
+func StatusOf(reg models.Registration) Status { return Status(reg.Status) }
+
+var (
+	// ErrIllegalTransition is what every rejected move wraps; the
+	// *TransitionError says which move and why.
+	ErrIllegalTransition = errors.New("illegal registration transition")
+	ErrAlreadyCheckedIn  = errors.New("already checked in")
+	ErrNotCheckedIn      = errors.New("not checked in")
+	ErrAlreadyCheckedOut = errors.New("already checked out")
+)
+
+// TransitionError explains a rejected move.
+type TransitionError struct {
+	From   Status
+	Event  string // a models.Event* kind
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	from := string(e.From)
+	if from == "" {
+		from = "new"
+	}
+	return fmt.Sprintf("cannot %s a %s registration: %s", e.Event, from, e.Reason)
+}
+
+func (e *TransitionError) Is(target error) bool { return target == ErrIllegalTransition }
+
+// transitions lists, per event, the statuses it may start from and the one it
+// ends in. Anything not listed is illegal. Check-in and its undo do not move
+// the status; they only require it. So does check-out. An admin checking in
+// a no-show after the fact puts it back to confirmed.
+var transitions = map[string]struct {
+	from []Status
+	to   Status
+}{
+	models.EventConfirmed:     {[]Status{StatusNew}, StatusConfirmed},
+	models.EventWaitlisted:    {[]Status{StatusNew, StatusConfirmed}, StatusWaitlisted},
+	models.EventPromoted:      {[]Status{StatusWaitlisted}, StatusConfirmed},
+	models.EventCanceled:      {[]Status{StatusConfirmed, StatusWaitlisted}, StatusCanceled},
+	models.EventNoShow:        {[]Status{StatusConfirmed}, StatusNoShow},
+	models.EventCheckedIn:     {[]Status{StatusConfirmed, StatusNoShow}, StatusConfirmed},
+	models.EventCheckinUndone: {[]Status{StatusConfirmed}, StatusConfirmed},
+	models.EventCheckedOut:    {[]Status{StatusConfirmed}, StatusConfirmed},
+}
+
+// Transition applies one event to reg in memory, or says why it may not.
+// class is the registration's class, loaded with Unscoped so a trashed one is
+// seen as such. Nothing is saved; see applyTx.
+func Transition(reg *models.Registration, class models.Class, event string, by Actor, now time.Time) error {
+	from := StatusOf(*reg)
+	fail := func(reason string) error {
+		return &TransitionError{From: from, Event: event, Reason: reason}
+	}
+
+	t, ok := transitions[event]
+	if !ok {
+		return fail("not a status change")
+	}
+	if reg.DeletedAt.Valid {
+		return fail("the registration is in the trash")
+	}
+	if class.DeletedAt.Valid {
+		return fail("the class is in the trash")
+	}
+	legal := false
+	for _, s := range t.from {
+		legal = legal || s == from
+	}
+	if !legal {
+		return fail("not allowed from this status")
+	}
+
+	switch event {
+	case models.EventCheckedIn:
+		if reg.CheckInAt != nil {
+			return ErrAlreadyCheckedIn
+		}
+		reg.CheckInAt = &now
+		reg.CheckedInBy = by.Name
+	case models.EventCheckinUndone:
+		if reg.CheckInAt == nil {
+			return ErrNotCheckedIn
+		}
+		if reg.CheckOutAt != nil {
+			return fail("the child has already been picked up")
+		}
+		reg.CheckInAt = nil
+		reg.CheckedInBy = ""
+		reg.SecurityCode = ""
+	case models.EventCheckedOut:
+		if reg.CheckInAt == nil {
+			return ErrNotCheckedIn
+		}
+		if reg.CheckOutAt != nil {
+			return ErrAlreadyCheckedOut
+		}
+		reg.CheckOutAt = &now
+		reg.CheckedOutBy = by.Name
+	case models.EventCanceled:
+		reg.CheckInAt = nil
+	case models.EventNoShow:
+		if reg.CheckInAt != nil {
+			return fail("the child was checked in")
+		}
+	}
+	reg.Status = string(t.to)
+	return nil
+}
+
+// applyTx runs Transition on a saved registration, stores the result and
+// records the event, all in tx.
+func applyTx(tx *gorm.DB, reg *models.Registration, event string, by Actor, note string) error {
+	return applyAtTx(tx, reg, event, by, note, time.Now())
+}
+
+// applyAtTx is applyTx for an event that happened at now rather than just now,
+// such as a check-in a station recorded offline.
+func applyAtTx(tx *gorm.DB, reg *models.Registration, event string, by Actor, note string, now time.Time) error {
+	var class models.Class
+	if err := tx.Unscoped().First(&class, reg.ClassID).Error; err != nil {
+		return err
+	}
+	if err := Transition(reg, class, event, by, now); err != nil {
+		return err
+	}
+	if err := tx.Save(reg).Error; err != nil {
+		return err
+	}
+	return RecordEvent(tx, reg.ID, event, by, note)
+}
+
+// CheckIn marks a confirmed registration present and draws the security code
+// for its name tag. Callers do their own permission fencing first; this only
+// enforces the life cycle.
+func CheckIn(regID uint, by Actor) (models.Registration, error) {
+	var reg models.Registration
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&reg, regID).Error; err != nil {
+			return err
+		}
+		code, err := NewSecurityCode()
+		if err != nil {
+			return err
+		}
+		reg.SecurityCode = code
+		return applyTx(tx, &reg, models.EventCheckedIn, by, "")
+	})
+	if err == nil {
+		publishChange(reg, models.EventCheckedIn, by)
+	}
+	return reg, err
+}
+
+// UndoCheckIn takes back a check-in, security code included, as long as the
+// child has not been picked up. The same fencing caveat as CheckIn applies.
+func UndoCheckIn(regID uint, by Actor) (models.Registration, error) {
+	var reg models.Registration
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&reg, regID).Error; err != nil {
+			return err
+		}
+		return applyTx(tx, &reg, models.EventCheckinUndone, by, "")
+	})
+	if err == nil {
+		publishChange(reg, models.EventCheckinUndone, by)
+	}
+	return reg, err
+}
+
+// CreateRegistration saves a new registration for reg's child and class,
+// confirmed while the class has room and waitlisted after, and writes its
+// opening history. The household comes from the child. A family over the
+// no-show policy starts on the waitlist. Run it inside the caller's
+// transaction.
+func CreateRegistration(tx *gorm.DB, reg *models.Registration, by Actor) error {
+	var child models.Child
+	if err := tx.First(&child, reg.ChildID).Error; err != nil {
+		return err
+	}
+	reg.HouseholdID = child.HouseholdID
+	var class models.Class
+	if err := tx.Unscoped().First(&class, reg.ClassID).Error; err != nil {
+		return err
+	}
+	var confirmed int64
+	if err := tx.Model(&models.Registration{}).
+		Where("class_id = ? AND status = ?", class.ID, StatusConfirmed).
+		Count(&confirmed).Error; err != nil {
+		return err
+	}
+	event := models.EventWaitlisted
+	note := ""
+	if int(confirmed) < class.Capacity {
+		event = models.EventConfirmed
+		// Families over the no-show policy start on the waitlist even
+		// when there is room.
+		held, why, err := noShowHold(tx, reg.HouseholdID, time.Now())
+		if err != nil {
+			return err
+		}
+		if held {
+			event, note = models.EventWaitlisted, why
+		}
+	}
+	return insertRegistration(tx, reg, class, event, by, note)
+}
+
+// insertRegistration saves a new registration in the status event leads to,
+// with its created and opening events. note goes on the opening event.
+func insertRegistration(tx *gorm.DB, reg *models.Registration, class models.Class, event string, by Actor, note string) error {
+	reg.Status = string(StatusNew)
+	if err := Transition(reg, class, event, by, time.Now()); err != nil {
+		return err
+	}
+	if err := tx.Create(reg).Error; err != nil {
+		return err
+	}
+	if err := RecordEvent(tx, reg.ID, models.EventCreated, by, reg.Code); err != nil {
+		return err
+	}
+	return RecordEvent(tx, reg.ID, event, by, note)
+}
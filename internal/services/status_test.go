@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/events"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestTransition(t *testing.T) {
+	now := time.Date(2026, 6, 28, 9, 0, 0, 0, time.UTC)
+	earlier := now.Add(-time.Hour)
+	trashed := gorm.DeletedAt{Time: earlier, Valid: true}
+	by := Actor{Name: "Rina", Source: models.SourceWeb}
+
+	cases := []struct {
+		name         string
+		from         Status
+		checkedIn    bool
+		regTrashed   bool
+		classTrashed bool
+		event        string
+		want         Status
+		wantErr      error
+	}{
+		{name: "new confirmed", from: StatusNew, event: models.EventConfirmed, want: StatusConfirmed},
+		{name: "new waitlisted", from: StatusNew, event: models.EventWaitlisted, want: StatusWaitlisted},
+		{name: "confirm into trashed class", from: StatusNew, classTrashed: true, event: models.EventConfirmed, wantErr: ErrIllegalTransition},
+		{name: "confirm twice", from: StatusConfirmed, event: models.EventConfirmed, wantErr: ErrIllegalTransition},
+		{name: "confirm canceled", from: StatusCanceled, event: models.EventConfirmed, wantErr: ErrIllegalTransition},
+		{name: "demote confirmed", from: StatusConfirmed, event: models.EventWaitlisted, want: StatusWaitlisted},
+		{name: "waitlist canceled", from: StatusCanceled, event: models.EventWaitlisted, wantErr: ErrIllegalTransition},
+
+		{name: "promote", from: StatusWaitlisted, event: models.EventPromoted, want: StatusConfirmed},
+		{name: "promote confirmed", from: StatusConfirmed, event: models.EventPromoted, wantErr: ErrIllegalTransition},
+		{name: "promote canceled", from: StatusCanceled, event: models.EventPromoted, wantErr: ErrIllegalTransition},
+		{name: "promote into trashed class", from: StatusWaitlisted, classTrashed: true, event: models.EventPromoted, wantErr: ErrIllegalTransition},
+
+		{name: "cancel confirmed", from: StatusConfirmed, event: models.EventCanceled, want: StatusCanceled},
+		{name: "cancel waitlisted", from: StatusWaitlisted, event: models.EventCanceled, want: StatusCanceled},
+		{name: "cancel checked in clears it", from: StatusConfirmed, checkedIn: true, event: models.EventCanceled, want: StatusCanceled},
+		{name: "cancel canceled", from: StatusCanceled, event: models.EventCanceled, wantErr: ErrIllegalTransition},
+		{name: "cancel trashed", from: StatusConfirmed, regTrashed: true, event: models.EventCanceled, wantErr: ErrIllegalTransition},
+
+		{name: "check in", from: StatusConfirmed, event: models.EventCheckedIn, want: StatusConfirmed},
+		{name: "check in twice", from: StatusConfirmed, checkedIn: true, event: models.EventCheckedIn, wantErr: ErrAlreadyCheckedIn},
+		{name: "check in waitlisted", from: StatusWaitlisted, event: models.EventCheckedIn, wantErr: ErrIllegalTransition},
+		{name: "check in canceled", from: StatusCanceled, event: models.EventCheckedIn, wantErr: ErrIllegalTransition},
+		{name: "check in trashed", from: StatusConfirmed, regTrashed: true, event: models.EventCheckedIn, wantErr: ErrIllegalTransition},
+		{name: "check in trashed class", from: StatusConfirmed, classTrashed: true, event: models.EventCheckedIn, wantErr: ErrIllegalTransition},
+
+		{name: "undo check in", from: StatusConfirmed, checkedIn: true, event: models.EventCheckinUndone, want: StatusConfirmed},
+		{name: "undo without check in", from: StatusConfirmed, event: models.EventCheckinUndone, wantErr: ErrNotCheckedIn},
+		{name: "undo canceled", from: StatusCanceled, event: models.EventCheckinUndone, wantErr: ErrIllegalTransition},
+
+		{name: "check out", from: StatusConfirmed, checkedIn: true, event: models.EventCheckedOut, want: StatusConfirmed},
+		{name: "check out without check in", from: StatusConfirmed, event: models.EventCheckedOut, wantErr: ErrNotCheckedIn},
+		{name: "check out waitlisted", from: StatusWaitlisted, event: models.EventCheckedOut, wantErr: ErrIllegalTransition},
+
+		{name: "not a status event", from: StatusConfirmed, event: models.EventDeleted, wantErr: ErrIllegalTransition},
+		{name: "unknown event", from: StatusConfirmed, event: "teleported", wantErr: ErrIllegalTransition},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := models.Registration{Status: string(tc.from)}
+			if tc.checkedIn {
+				reg.CheckInAt, reg.CheckedInBy = &earlier, "Budi"
+			}
+			if tc.regTrashed {
+				reg.DeletedAt = trashed
+			}
+			var class models.Class
+			if tc.classTrashed {
+				class.DeletedAt = trashed
+			}
+			before := reg
+
+			err := Transition(&reg, class, tc.event, by, now)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+				if reg.Status != before.Status || reg.CheckInAt != before.CheckInAt {
+					t.Errorf("rejected move still changed the registration: %+v", reg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if StatusOf(reg) != tc.want {
+				t.Errorf("status = %q, want %q", reg.Status, tc.want)
+			}
+
+			switch tc.event {
+			case models.EventCheckedIn:
+				if reg.CheckInAt == nil || !reg.CheckInAt.Equal(now) || reg.CheckedInBy != by.Name {
+					t.Errorf("check-in not stamped: %v by %q", reg.CheckInAt, reg.CheckedInBy)
+				}
+			case models.EventCheckinUndone, models.EventCanceled:
+				if reg.CheckInAt != nil || (tc.event == models.EventCheckinUndone && reg.CheckedInBy != "") {
+					t.Errorf("check-in left behind: %v by %q", reg.CheckInAt, reg.CheckedInBy)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckIn_GoesThroughStateMachine(t *testing.T) {
+	f := seedTrash(t, 10)
+	by := Actor{Name: "Rina", Source: models.SourceWeb}
+
+	reg, err := CheckIn(f.reg.ID, by)
+	if err != nil {
+		t.Fatalf("CheckIn: %v", err)
+	}
+	if reg.CheckInAt == nil || reg.CheckedInBy != "Rina" {
+		t.Errorf("check-in not saved: %+v", reg)
+	}
+	if _, err := CheckIn(f.reg.ID, by); !errors.Is(err, ErrAlreadyCheckedIn) {
+		t.Errorf("second CheckIn = %v, want ErrAlreadyCheckedIn", err)
+	}
+
+	if err := CancelByCode(f.reg.Code, by); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckIn(f.reg.ID, by); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("CheckIn canceled = %v, want ErrIllegalTransition", err)
+	}
+	var got models.Registration
+	db.Conn().First(&got, f.reg.ID)
+	if got.CheckInAt != nil {
+		t.Errorf("canceled registration kept its check-in")
+	}
+}
+
+func TestUndoCheckIn(t *testing.T) {
+	f := seedTrash(t, 10)
+	by := Actor{Name: "Rina", Source: models.SourceWeb}
+
+	if _, err := UndoCheckIn(f.reg.ID, by); !errors.Is(err, ErrNotCheckedIn) {
+		t.Fatalf("undo before check-in = %v, want ErrNotCheckedIn", err)
+	}
+	if _, err := CheckIn(f.reg.ID, by); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := UndoCheckIn(f.reg.ID, by)
+	if err != nil {
+		t.Fatalf("UndoCheckIn: %v", err)
+	}
+	if reg.CheckInAt != nil || reg.CheckedInBy != "" || reg.SecurityCode != "" {
+		t.Errorf("undo left check-in behind: %+v", reg)
+	}
+	evs := eventsOf(t, f.reg.ID)
+	if last := evs[len(evs)-1]; last.Kind != models.EventCheckinUndone || last.Actor != "Rina" {
+		t.Errorf("last event = %+v, want checkin_undone by Rina", last)
+	}
+
+	// Once picked up, the check-in stands.
+	in, _ := CheckIn(f.reg.ID, by)
+	if _, err := CheckOut(f.reg.ID, fmt.Sprintf("guardian:%d", f.parent.ID), in.SecurityCode, by); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UndoCheckIn(f.reg.ID, by); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("undo after pickup = %v, want ErrIllegalTransition", err)
+	}
+}
+
+func TestCreateRegistration(t *testing.T) {
+	f := seedTrash(t, 1)
+	conn := db.Conn()
+	by := ParentActor(f.parent.Phone, models.SourceWeb)
+	other := models.Child{Name: "Ani", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&other)
+
+	// The seeded registration holds the only seat.
+	reg := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: other.ID, ClassID: f.class.ID, Code: "REG-T2"}
+	if err := CreateRegistration(conn, &reg, by); err != nil {
+		t.Fatalf("CreateRegistration: %v", err)
+	}
+	if StatusOf(reg) != StatusWaitlisted {
+		t.Errorf("status = %q, want waitlisted", reg.Status)
+	}
+	evs := eventsOf(t, reg.ID)
+	if len(evs) != 2 || evs[0].Kind != models.EventCreated || evs[1].Kind != models.EventWaitlisted {
+		t.Errorf("history = %+v, want created then waitlisted", evs)
+	}
+
+	if err := DeleteClass(f.class.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	late := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: other.ID, ClassID: f.class.ID, Code: "REG-T3"}
+	if err := CreateRegistration(conn, &late, by); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("register into trashed class = %v, want ErrIllegalTransition", err)
+	}
+}
+
+func TestStationChangesArePublished(t *testing.T) {
+	f := seedTrash(t, 1)
+	wait := models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: f.kid.ID, ClassID: f.class.ID, Status: "waitlisted", Code: "REG-T2"}
+	db.Conn().Create(&wait)
+
+	ch, stop := events.Subscribe(func(c events.Change) bool { return c.ClassID == f.class.ID })
+	defer stop()
+
+	if _, err := CheckIn(f.reg.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := CancelByCode(f.reg.Code, admin); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind string
+		reg  uint
+	}{
+		{models.EventCheckedIn, f.reg.ID},
+		{models.EventCanceled, f.reg.ID},
+		{models.EventPromoted, wait.ID},
+	}
+	for _, w := range want {
+		select {
+		case c := <-ch:
+			if c.Kind != w.kind || c.RegID != w.reg {
+				t.Errorf("change = %s reg %d, want %s reg %d", c.Kind, c.RegID, w.kind, w.reg)
+			}
+		default:
+			t.Fatalf("no change published, want %s reg %d", w.kind, w.reg)
+		}
+	}
+}
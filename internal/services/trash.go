@@ -0,0 +1,404 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Deletes from the admin UI are soft: the row gets a deleted_at and drops out
+// of every query. Deleting a parent, child or class takes the rows hanging off
+// it along, stamped with the same deleted_at, so restoring it brings back that
+// batch and nothing that was deleted on its own earlier.
+//
+// Invariant kept by all of this: a live registration always has a live parent,
+// child and class, so read queries only need to check registrations.deleted_at.
+
+type TrashKind string
+
+const (
+	TrashParent       TrashKind = "parent"
+	TrashChild        TrashKind = "child"
+	TrashClass        TrashKind = "class"
+	TrashRegistration TrashKind = "registration"
+)
+
+var (
+	ErrOwnerDeleted = errors.New("its parent, child or class is still in the trash")
+	ErrPhoneInUse   = errors.New("phone number now belongs to another parent")
+	ErrUnknownKind  = errors.New("unknown trash kind")
+)
+
+// trashStamp is the deleted_at for one batch. Microseconds are all Postgres
+// keeps, and restore matches the batch by equality.
+func trashStamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// DeleteParent trashes a parent with their children and registrations. When
+// the household has other guardians, only the parent goes: what was filed
+// under them moves to another guardian and stays live.
+func DeleteParent(id uint, by Actor) error {
+	var classIDs []uint
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var p models.Parent
+		if err := tx.First(&p, id).Error; err != nil {
+			return err
+		}
+		heir, err := otherGuardian(tx, p.HouseholdID, p.ID)
+		if err != nil {
+			return err
+		}
+		if heir != 0 {
+			for _, m := range []any{&models.Registration{}, &models.Child{}} {
+				if err := tx.Model(m).Where("parent_id = ?", id).UpdateColumn("parent_id", heir).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&p).UpdateColumn("deleted_at", trashStamp()).Error
+		}
+
+		if err := tx.Model(&models.Registration{}).Where("parent_id = ?", id).
+			Distinct().Pluck("class_id", &classIDs).Error; err != nil {
+			return err
+		}
+		if err := recordDeleted(tx, "parent_id = ?", id, by); err != nil {
+			return err
+		}
+		now := trashStamp()
+		for _, m := range []any{&models.Registration{}, &models.Child{}} {
+			if err := tx.Model(m).Where("parent_id = ?", id).UpdateColumn("deleted_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&p).UpdateColumn("deleted_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	recomputeAll(classIDs)
+	return nil
+}
+
+// DeleteChild trashes a child with their registrations.
+func DeleteChild(id uint, by Actor) error {
+	var classIDs []uint
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var c models.Child
+		if err := tx.First(&c, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Registration{}).Where("child_id = ?", id).
+			Distinct().Pluck("class_id", &classIDs).Error; err != nil {
+			return err
+		}
+		if err := recordDeleted(tx, "child_id = ?", id, by); err != nil {
+			return err
+		}
+		now := trashStamp()
+		if err := tx.Model(&models.Registration{}).Where("child_id = ?", id).
+			UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&c).UpdateColumn("deleted_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	recomputeAll(classIDs)
+	return nil
+}
+
+// DeleteClass trashes a class and whatever (canceled) registrations it has
+// left; callers refuse classes that still have a roster.
+func DeleteClass(id uint, by Actor) error {
+	return db.Conn().Transaction(func(tx *gorm.DB) error {
+		var c models.Class
+		if err := tx.First(&c, id).Error; err != nil {
+			return err
+		}
+		if err := recordDeleted(tx, "class_id = ?", id, by); err != nil {
+			return err
+		}
+		now := trashStamp()
+		if err := tx.Model(&models.Registration{}).Where("class_id = ?", id).
+			UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&c).UpdateColumn("deleted_at", now).Error
+	})
+}
+
+// DeleteRegistration trashes one registration and rebalances its class.
+func DeleteRegistration(id uint, by Actor) error {
+	var reg models.Registration
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&reg, id).Error; err != nil {
+			return err
+		}
+		if err := RecordEvent(tx, reg.ID, models.EventDeleted, by, ""); err != nil {
+			return err
+		}
+		return tx.Model(&reg).UpdateColumn("deleted_at", trashStamp()).Error
+	})
+	if err != nil {
+		return err
+	}
+	return RecomputeClass(reg.ClassID)
+}
+
+// recordDeleted writes a "deleted" event for every live registration matching
+// where, before the batch goes to the trash.
+func recordDeleted(tx *gorm.DB, where string, id uint, by Actor) error {
+	var regIDs []uint
+	if err := tx.Model(&models.Registration{}).Where(where, id).Pluck("id", &regIDs).Error; err != nil {
+		return err
+	}
+	for _, regID := range regIDs {
+		if err := RecordEvent(tx, regID, models.EventDeleted, by, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore brings a trashed row back, with the batch that was deleted with it.
+// Restored registrations go through the capacity check again: a confirmed
+// seat that has since been taken comes back waitlisted, and RecomputeClass
+// runs for every class touched.
+func Restore(kind TrashKind, id uint, by Actor) error {
+	var promoted []models.Registration
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		var regs []models.Registration
+		trashed := tx.Unscoped().Where("deleted_at IS NOT NULL")
+
+		switch kind {
+		case TrashParent:
+			var p models.Parent
+			if err := trashed.First(&p, id).Error; err != nil {
+				return err
+			}
+			var clash int64
+			tx.Model(&models.Parent{}).Where("phone = ?", p.Phone).Count(&clash)
+			if clash > 0 {
+				return ErrPhoneInUse
+			}
+			stamp := p.DeletedAt.Time
+			if err := tx.Unscoped().Model(&models.Child{}).
+				Where("parent_id = ? AND deleted_at = ?", id, stamp).
+				UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("parent_id = ? AND deleted_at = ?", id, stamp).
+				Find(&regs).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&p).UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+
+		case TrashChild:
+			var c models.Child
+			if err := trashed.First(&c, id).Error; err != nil {
+				return err
+			}
+			if !live(tx, &models.Parent{}, c.ParentID) {
+				return ErrOwnerDeleted
+			}
+			if err := tx.Unscoped().Where("child_id = ? AND deleted_at = ?", id, c.DeletedAt.Time).
+				Find(&regs).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&c).UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+
+		case TrashClass:
+			var c models.Class
+			if err := trashed.First(&c, id).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("class_id = ? AND deleted_at = ?", id, c.DeletedAt.Time).
+				Find(&regs).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&c).UpdateColumn("deleted_at", nil).Error; err != nil {
+				return err
+			}
+
+		case TrashRegistration:
+			var r models.Registration
+			if err := trashed.First(&r, id).Error; err != nil {
+				return err
+			}
+			regs = []models.Registration{r}
+
+		default:
+			return ErrUnknownKind
+		}
+
+		var err error
+		promoted, err = restoreRegistrationsTx(tx, regs, kind == TrashRegistration, by)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	notifyPromotions(promoted)
+	return nil
+}
+
+// restoreRegistrationsTx un-deletes regs whose parent, child and class are
+// live again. When strict, one that cannot come back is an error; otherwise it
+// stays in the trash to be restored on its own later.
+func restoreRegistrationsTx(tx *gorm.DB, regs []models.Registration, strict bool, by Actor) ([]models.Registration, error) {
+	classes := map[uint]bool{}
+	for _, r := range regs {
+		if !live(tx, &models.Parent{}, r.ParentID) || !live(tx, &models.Child{}, r.ChildID) || !live(tx, &models.Class{}, r.ClassID) {
+			if strict {
+				return nil, ErrOwnerDeleted
+			}
+			continue
+		}
+
+		if StatusOf(r).Active() {
+			var dup int64
+			if err := tx.Model(&models.Registration{}).
+				Where("child_id = ? AND class_id = ? AND status IN ?", r.ChildID, r.ClassID, []Status{StatusConfirmed, StatusWaitlisted}).
+				Count(&dup).Error; err != nil {
+				return nil, err
+			}
+			if dup > 0 {
+				return nil, ErrDuplicateReg
+			}
+		}
+		full := false
+		if StatusOf(r) == StatusConfirmed {
+			var class models.Class
+			if err := tx.First(&class, r.ClassID).Error; err != nil {
+				return nil, err
+			}
+			var taken int64
+			if err := tx.Model(&models.Registration{}).
+				Where("class_id = ? AND status = ?", r.ClassID, StatusConfirmed).
+				Count(&taken).Error; err != nil {
+				return nil, err
+			}
+			full = int(taken) >= class.Capacity
+		}
+
+		if err := tx.Unscoped().Model(&models.Registration{}).Where("id = ?", r.ID).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return nil, err
+		}
+		r.DeletedAt = gorm.DeletedAt{}
+		if err := RecordEvent(tx, r.ID, models.EventRestored, by, ""); err != nil {
+			return nil, err
+		}
+		if full {
+			if err := applyTx(tx, &r, models.EventWaitlisted, by, "seat was taken while it was in the trash"); err != nil {
+				return nil, err
+			}
+		}
+		classes[r.ClassID] = true
+	}
+
+	var promoted []models.Registration
+	for classID := range classes {
+		p, err := recomputeClassTxCollect(tx, classID)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, p...)
+	}
+	return promoted, nil
+}
+
+// Purge removes a trashed row for good, with everything that hangs off it.
+// Live rows are never purged.
+func Purge(kind TrashKind, id uint) error {
+	return db.Conn().Transaction(func(tx *gorm.DB) error {
+		trashed := tx.Unscoped().Where("deleted_at IS NOT NULL")
+		var owner any
+		var regsOf string
+		switch kind {
+		case TrashParent:
+			owner, regsOf = &models.Parent{}, "parent_id"
+		case TrashChild:
+			owner, regsOf = &models.Child{}, "child_id"
+		case TrashClass:
+			owner, regsOf = &models.Class{}, "class_id"
+		case TrashRegistration:
+			owner, regsOf = &models.Registration{}, "id"
+		default:
+			return ErrUnknownKind
+		}
+		if err := trashed.First(owner, id).Error; err != nil {
+			return err
+		}
+
+		regIDs := tx.Unscoped().Model(&models.Registration{}).Select("id").Where(regsOf+" = ?", id)
+		for _, m := range []any{&models.RegistrationAnswer{}, &models.RegistrationEvent{}, &models.StationSync{}} {
+			if err := tx.Where("registration_id IN (?)", regIDs).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where(regsOf+" = ?", id).Delete(&models.Registration{}).Error; err != nil {
+			return err
+		}
+		switch kind {
+		case TrashParent:
+			kids := tx.Unscoped().Model(&models.Child{}).Select("id").Where("parent_id = ?", id)
+			if err := tx.Where("child_id IN (?)", kids).Delete(&models.PickupPerson{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("parent_id = ?", id).Delete(&models.FamilyCode{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("parent_id = ?", id).Delete(&models.Child{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(owner).Error; err != nil {
+				return err
+			}
+			// The household goes with its last guardian.
+			hid := owner.(*models.Parent).HouseholdID
+			var left int64
+			if err := tx.Unscoped().Model(&models.Parent{}).Where("household_id = ?", hid).Count(&left).Error; err != nil {
+				return err
+			}
+			if left > 0 || hid == 0 {
+				return nil
+			}
+			return tx.Delete(&models.Household{}, hid).Error
+		case TrashChild:
+			if err := tx.Where("child_id = ?", id).Delete(&models.PickupPerson{}).Error; err != nil {
+				return err
+			}
+		case TrashClass:
+			if err := tx.Where("class_id = ?", id).Delete(&models.ClassQuestion{}).Error; err != nil {
+				return err
+			}
+		case TrashRegistration:
+			return nil // the owner was the registration itself
+		}
+		return tx.Unscoped().Delete(owner).Error
+	})
+}
+
+// live reports whether the row with this id exists and is not trashed.
+func live(tx *gorm.DB, model any, id uint) bool {
+	var n int64
+	tx.Model(model).Where("id = ?", id).Count(&n)
+	return n > 0
+}
+
+func recomputeAll(classIDs []uint) {
+	for _, id := range classIDs {
+		_ = RecomputeClass(id)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/db/dbtest"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+var admin = Actor{Name: "admin", Source: models.SourceWeb}
+
+type trashFixture struct {
+	parent models.Parent
+	kid    models.Child
+	class  models.Class
+	reg    models.Registration
+}
+
+func seedTrash(t *testing.T, capacity int) trashFixture {
+	t.Helper()
+	conn := dbtest.Init(t)
+	var f trashFixture
+	f.parent = models.Parent{Name: "Ibu Sari", Phone: "+628111"}
+	if err := CreateParent(conn, &f.parent); err != nil {
+		t.Fatal(err)
+	}
+	f.kid = models.Child{Name: "Budi", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&f.kid)
+	f.class = models.Class{Name: "FJB Little Stars", Date: time.Now().AddDate(0, 0, 3), Capacity: capacity}
+	conn.Create(&f.class)
+	f.reg = models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: f.kid.ID, ClassID: f.class.ID, Status: "confirmed", Code: "REG-T1"}
+	conn.Create(&f.reg)
+	return f
+}
+
+func count(t *testing.T, model any) int64 {
+	t.Helper()
+	var n int64
+	db.Conn().Model(model).Count(&n)
+	return n
+}
+
+func TestDeleteParent_TrashesFamilyAndRestoreBringsItBack(t *testing.T) {
+	f := seedTrash(t, 10)
+
+	if err := DeleteParent(f.parent.ID, admin); err != nil {
+		t.Fatalf("DeleteParent: %v", err)
+	}
+	for _, m := range []any{&models.Parent{}, &models.Child{}, &models.Registration{}} {
+		if n := count(t, m); n != 0 {
+			t.Errorf("%T still visible after delete: %d", m, n)
+		}
+	}
+	if p, err := FindParentByAny("08111"); err == nil {
+		t.Errorf("FindParentByAny found trashed parent %d", p.ID)
+	}
+
+	if err := Restore(TrashParent, f.parent.ID, admin); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	for _, m := range []any{&models.Parent{}, &models.Child{}, &models.Registration{}} {
+		if n := count(t, m); n != 1 {
+			t.Errorf("%T after restore = %d, want 1", m, n)
+		}
+	}
+}
+
+func TestRestore_KeepsEarlierSeparateDeleteInTrash(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := DeleteRegistration(f.reg.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := DeleteParent(f.parent.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(TrashParent, f.parent.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, &models.Registration{}); n != 0 {
+		t.Errorf("registration deleted on its own came back with the parent")
+	}
+	if err := Restore(TrashRegistration, f.reg.ID, admin); err != nil {
+		t.Fatalf("restore registration: %v", err)
+	}
+}
+
+func TestRestoreRegistration_WaitlistsWhenSeatWasTaken(t *testing.T) {
+	f := seedTrash(t, 1)
+	if err := DeleteRegistration(f.reg.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	conn := db.Conn()
+	other := models.Child{Name: "Ani", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&other)
+	conn.Create(&models.Registration{HouseholdID: f.parent.HouseholdID, ParentID: f.parent.ID, ChildID: other.ID, ClassID: f.class.ID, Status: "confirmed", Code: "REG-T2"})
+
+	if err := Restore(TrashRegistration, f.reg.ID, admin); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	var back models.Registration
+	conn.First(&back, f.reg.ID)
+	if back.Status != "waitlisted" {
+		t.Errorf("restored into a full class as %q, want waitlisted", back.Status)
+	}
+}
+
+func TestRestoreChild_RefusesWhileParentTrashed(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := DeleteParent(f.parent.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(TrashChild, f.kid.ID, admin); !errors.Is(err, ErrOwnerDeleted) {
+		t.Errorf("Restore child = %v, want ErrOwnerDeleted", err)
+	}
+}
+
+func TestRestoreParent_RefusesWhenPhoneReused(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := DeleteParent(f.parent.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	// The same number registers again while the old record is in the trash.
+	if err := CreateParent(db.Conn(), &models.Parent{Name: "Baru", Phone: f.parent.Phone}); err != nil {
+		t.Fatalf("re-register with trashed phone: %v", err)
+	}
+	if err := Restore(TrashParent, f.parent.ID, admin); !errors.Is(err, ErrPhoneInUse) {
+		t.Errorf("Restore = %v, want ErrPhoneInUse", err)
+	}
+}
+
+func TestPurge_OnlyTrashedRowsAndEverythingBelow(t *testing.T) {
+	f := seedTrash(t, 10)
+	if err := Purge(TrashParent, f.parent.ID); err == nil {
+		t.Fatal("purged a live parent")
+	}
+	if err := DeleteParent(f.parent.ID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := Purge(TrashParent, f.parent.ID); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	conn := db.Conn().Unscoped()
+	for _, m := range []any{&models.Parent{}, &models.Child{}, &models.Registration{}, &models.RegistrationEvent{}} {
+		var n int64
+		conn.Model(m).Count(&n)
+		if n != 0 {
+			t.Errorf("%T rows left after purge: %d", m, n)
+		}
+	}
+}
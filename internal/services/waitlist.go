@@ -0,0 +1,219 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// A WaitlistPolicy decides the order of a class's waitlist; the front of it
+// is promoted first when a seat frees up. Classes pick one by key in
+// Class.WaitlistPolicy.
+type WaitlistPolicy interface {
+	// Label names the policy for admins.
+	Label() string
+	// Explain says in one line how the policy orders the waitlist.
+	Explain() string
+	// Order returns waitlist, which comes in first come, first served
+	// order, in the order the policy wants it promoted.
+	Order(tx *gorm.DB, class models.Class, waitlist []models.Registration) ([]WaitlistEntry, error)
+}
+
+// WaitlistEntry is one waitlisted registration in its effective place.
+type WaitlistEntry struct {
+	Registration models.Registration
+	Reason       string // why it sits apart from its FIFO place; blank if it doesn't
+}
+
+// Keys of the built-in policies.
+const (
+	WaitlistFIFO   = ""
+	WaitlistFamily = "family"
+)
+
+var waitlistPolicies = map[string]WaitlistPolicy{
+	WaitlistFIFO:   fifoPolicy{},
+	WaitlistFamily: familyPolicy{},
+}
+
+// RegisterWaitlistPolicy makes p available to classes as key. Call it at
+// startup, before any class is recomputed.
+func RegisterWaitlistPolicy(key string, p WaitlistPolicy) {
+	waitlistPolicies[key] = p
+}
+
+// IsWaitlistPolicy reports whether key names a policy.
+func IsWaitlistPolicy(key string) bool {
+	_, ok := waitlistPolicies[key]
+	return ok
+}
+
+// WaitlistPolicyFor returns the policy stored under key; an unknown key
+// falls back to first come, first served.
+func WaitlistPolicyFor(key string) WaitlistPolicy {
+	if p, ok := waitlistPolicies[key]; ok {
+		return p
+	}
+	return fifoPolicy{}
+}
+
+// WaitlistPolicyOption is a policy as the class form lists it.
+type WaitlistPolicyOption struct {
+	Key     string
+	Label   string
+	Explain string
+}
+
+// DescribeWaitlistPolicy is the policy a class with key uses.
+func DescribeWaitlistPolicy(key string) WaitlistPolicyOption {
+	p := WaitlistPolicyFor(key)
+	return WaitlistPolicyOption{Key: key, Label: p.Label(), Explain: p.Explain()}
+}
+
+// WaitlistPolicies lists every policy, first come, first served first.
+func WaitlistPolicies() []WaitlistPolicyOption {
+	out := make([]WaitlistPolicyOption, 0, len(waitlistPolicies))
+	for k := range waitlistPolicies {
+		out = append(out, DescribeWaitlistPolicy(k))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// WaitlistOrder is class's waitlist in the order its policy promotes it.
+func WaitlistOrder(tx *gorm.DB, class models.Class) ([]WaitlistEntry, error) {
+	var waitlist []models.Registration
+	if err := tx.
+		Where("class_id = ? AND status = ?", class.ID, StatusWaitlisted).
+		Order("created_at asc, id asc").
+		Find(&waitlist).Error; err != nil {
+		return nil, err
+	}
+	return WaitlistPolicyFor(class.WaitlistPolicy).Order(tx, class, waitlist)
+}
+
+// WaitlistRank is reg's place on its class's waitlist, from 1; 0 when it is
+// not on it.
+func WaitlistRank(tx *gorm.DB, reg models.Registration) (int, error) {
+	var class models.Class
+	if err := tx.First(&class, reg.ClassID).Error; err != nil {
+		return 0, err
+	}
+	order, err := WaitlistOrder(tx, class)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range order {
+		if e.Registration.ID == reg.ID {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+type fifoPolicy struct{}
+
+func (fifoPolicy) Label() string   { return "First come, first served" }
+func (fifoPolicy) Explain() string { return "Whoever joined the waitlist first gets the next seat." }
+
+func (fifoPolicy) Order(_ *gorm.DB, _ models.Class, waitlist []models.Registration) ([]WaitlistEntry, error) {
+	out := make([]WaitlistEntry, len(waitlist))
+	for i, r := range waitlist {
+		out[i] = WaitlistEntry{Registration: r}
+	}
+	return out, nil
+}
+
+// familyPolicy moves siblings of children already seated in the class and
+// families who have never checked in to the front, and families with
+// no-shows in the no-show policy's window to the back. Within each group it
+// is first come, first served.
+type familyPolicy struct{}
+
+func (familyPolicy) Label() string { return "Family priority" }
+func (familyPolicy) Explain() string {
+	return "Siblings of seated children and first-time families go first, families with recent no-shows last; otherwise first come, first served."
+}
+
+func (familyPolicy) Order(tx *gorm.DB, class models.Class, waitlist []models.Registration) ([]WaitlistEntry, error) {
+	if len(waitlist) == 0 {
+		return nil, nil
+	}
+	households := make([]uint, 0, len(waitlist))
+	for _, r := range waitlist {
+		households = append(households, r.HouseholdID)
+	}
+
+	var seated []uint
+	if err := tx.Model(&models.Registration{}).
+		Where("class_id = ? AND status = ? AND household_id IN ?", class.ID, StatusConfirmed, households).
+		Distinct().Pluck("household_id", &seated).Error; err != nil {
+		return nil, err
+	}
+	var returning []uint
+	if err := tx.Model(&models.Registration{}).
+		Where("household_id IN ? AND check_in_at IS NOT NULL", households).
+		Distinct().Pluck("household_id", &returning).Error; err != nil {
+		return nil, err
+	}
+	policy, err := GetNoShowPolicy(tx)
+	if err != nil {
+		return nil, err
+	}
+	noShows, err := NoShowCounts(tx, households, policy.Since(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	hasSeat := make(map[uint]bool, len(seated))
+	for _, h := range seated {
+		hasSeat[h] = true
+	}
+	hasCome := make(map[uint]bool, len(returning))
+	for _, h := range returning {
+		hasCome[h] = true
+	}
+
+	out := make([]WaitlistEntry, len(waitlist))
+	group := make([]int, len(waitlist)) // 0 front, 1 middle, 2 back
+	for i, r := range waitlist {
+		out[i].Registration = r
+		group[i] = 1
+		var why []string
+		if r.HouseholdID != 0 && hasSeat[r.HouseholdID] {
+			why = append(why, "sibling already seated")
+		}
+		if r.HouseholdID != 0 && !hasCome[r.HouseholdID] {
+			why = append(why, "first-time family")
+		}
+		if len(why) > 0 {
+			group[i] = 0
+		}
+		if n := noShows[r.HouseholdID]; n > 0 && r.HouseholdID != 0 {
+			group[i] = 2
+			why = []string{fmt.Sprintf("%d no-show%s in the last %d weeks", n, plural(n), policy.Weeks)}
+		}
+		out[i].Reason = strings.Join(why, ", ")
+	}
+	idx := make([]int, len(out))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return group[idx[a]] < group[idx[b]] })
+	sorted := make([]WaitlistEntry, len(out))
+	for i, j := range idx {
+		sorted[i] = out[j]
+	}
+	return sorted, nil
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+// Under family priority a seated child's sibling and a first-time family
+// jump a returning family, who in turn stays ahead of one with a recent
+// no-show; the freed seat goes to the front.
+func TestFamilyPolicy_OrdersAndPromotesByPriority(t *testing.T) {
+	f := seedTrash(t, 1) // Budi holds the only seat (REG-T1)
+	conn := db.Conn()
+	conn.Model(&f.class).Update("waitlist_policy", WaitlistFamily)
+	f.class.WaitlistPolicy = WaitlistFamily
+	past := models.Class{Name: "Last month", Date: time.Now().AddDate(0, 0, -20), Capacity: 10}
+	conn.Create(&past)
+
+	family := func(name, phone string) (models.Parent, models.Child) {
+		p := models.Parent{Name: name, Phone: phone}
+		if err := CreateParent(conn, &p); err != nil {
+			t.Fatal(err)
+		}
+		k := models.Child{Name: "Anak " + name, ParentID: p.ID, HouseholdID: p.HouseholdID}
+		conn.Create(&k)
+		return p, k
+	}
+	n := 0
+	waitlist := func(p models.Parent, k models.Child) models.Registration {
+		n++
+		r := models.Registration{HouseholdID: p.HouseholdID, ParentID: p.ID, ChildID: k.ID, ClassID: f.class.ID,
+			Status: "waitlisted", Code: fmt.Sprintf("REG-W%d", n), CreatedAt: time.Now().Add(time.Duration(n) * time.Minute)}
+		conn.Create(&r)
+		return r
+	}
+	checkedIn := time.Now().AddDate(0, 0, -20)
+
+	returning, rk := family("Returning", "+628222")
+	conn.Create(&models.Registration{HouseholdID: returning.HouseholdID, ParentID: returning.ID, ChildID: rk.ID, ClassID: past.ID,
+		Status: "confirmed", Code: "REG-P1", CheckInAt: &checkedIn})
+	flaky, fk := family("Flaky", "+628333")
+	conn.Create(&models.Registration{HouseholdID: flaky.HouseholdID, ParentID: flaky.ID, ChildID: fk.ID, ClassID: past.ID,
+		Status: "no_show", Code: "REG-P2"})
+	newcomer, nk := family("Baru", "+628444")
+	sib := models.Child{Name: "Sari", ParentID: f.parent.ID, HouseholdID: f.parent.HouseholdID}
+	conn.Create(&sib)
+	conn.Model(&f.reg).Update("check_in_at", checkedIn) // Budi's family has been before
+
+	wFlaky := waitlist(flaky, fk)
+	wReturning := waitlist(returning, rk)
+	wSib := waitlist(f.parent, sib)
+	wNew := waitlist(newcomer, nk)
+
+	order, err := WaitlistOrder(conn, f.class)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id     uint
+		reason string
+	}{
+		{wSib.ID, "sibling already seated"},
+		{wNew.ID, "first-time family"},
+		{wReturning.ID, ""},
+		{wFlaky.ID, "1 no-show in the last 8 weeks"},
+	}
+	if len(order) != len(want) {
+		t.Fatalf("waitlist has %d entries, want %d", len(order), len(want))
+	}
+	for i, w := range want {
+		if order[i].Registration.ID != w.id || order[i].Reason != w.reason {
+			t.Errorf("#%d = reg %d (%q), want reg %d (%q)", i+1, order[i].Registration.ID, order[i].Reason, w.id, w.reason)
+		}
+	}
+	if rank, _ := WaitlistRank(conn, wFlaky); rank != 4 {
+		t.Errorf("WaitlistRank(flaky) = %d, want 4", rank)
+	}
+
+	if err := CancelByCode(f.reg.Code, admin); err != nil {
+		t.Fatal(err)
+	}
+	var got models.Registration
+	conn.First(&got, wSib.ID)
+	if got.Status != "confirmed" {
+		t.Errorf("sibling status = %s after the seat freed, want confirmed", got.Status)
+	}
+}
+
+func TestWaitlistPolicyFor_UnknownFallsBackToFIFO(t *testing.T) {
+	if got := WaitlistPolicyFor("no-such-policy").Label(); got != WaitlistPolicyFor(WaitlistFIFO).Label() {
+		t.Errorf("unknown policy = %q, want FIFO", got)
+	}
+	if opts := WaitlistPolicies(); len(opts) < 2 || opts[0].Key != WaitlistFIFO {
+		t.Errorf("WaitlistPolicies() = %+v, want FIFO first", opts)
+	}
+}
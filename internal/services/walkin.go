@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+var (
+	ErrWalkInFull    = errors.New("class is full, walk-in seats included")
+	ErrWalkInMissing = errors.New("walk-in needs a phone, a parent name and a child")
+	ErrWalkInChild   = errors.New("child does not belong to this family")
+)
+
+// WalkIn is a family that turns up at the station without having registered.
+// ChildID picks a child of the household found by Phone; otherwise ChildName
+// names one. A household child of that name is taken as that child, so a
+// sibling typed in twice is not added twice; only a new name adds a child,
+// with BirthDate and Gender. ParentName is only used when nobody has that
+// phone yet.
+type WalkIn struct {
+	Phone      string
+	ParentName string
+	ChildID    uint
+	ChildName  string
+	BirthDate  time.Time
+	Gender     string
+	ClassID    uint
+	Code       string // registration code, drawn by the caller
+}
+
+// WalkInResult is what a walk-in ended up as, and what it had to create.
+type WalkInResult struct {
+	Registration models.Registration
+	Parent       models.Parent
+	Child        models.Child
+	NewParent    bool
+	NewChild     bool
+}
+
+// CheckInWalkIn registers a walk-in for a class and checks the child in, all
+// in one transaction: the parent and child are looked up or created, the seat
+// comes from capacity or else the class's walk-in seats, and walk-ins are
+// never waitlisted. Callers fence the class to the volunteer's day and campus.
+func CheckInWalkIn(w WalkIn, by Actor) (WalkInResult, error) {
+	var res WalkInResult
+	phone := NormPhone(w.Phone)
+	w.ParentName = strings.TrimSpace(w.ParentName)
+	w.ChildName = strings.TrimSpace(w.ChildName)
+	if phone == "" || (w.ChildID == 0 && w.ChildName == "") {
+		return res, ErrWalkInMissing
+	}
+
+	// Lookups that go through db.Conn() happen before the transaction.
+	found, _ := FindParentByAny(phone)
+	if found == nil && w.ParentName == "" {
+		return res, ErrWalkInMissing
+	}
+	if w.ChildID != 0 && found == nil {
+		return res, ErrWalkInChild
+	}
+
+	err := db.Conn().Transaction(func(tx *gorm.DB) error {
+		// Same lock as Register: the conflict check and the seat count
+		// below hold until commit.
+		class, err := lockClass(tx, w.ClassID)
+		if err != nil {
+			return err
+		}
+
+		if found != nil {
+			res.Parent = *found
+		} else {
+			res.Parent = models.Parent{Name: w.ParentName, Phone: phone}
+			if err := CreateParent(tx, &res.Parent); err != nil {
+				return err
+			}
+			res.NewParent = true
+		}
+
+		if w.ChildID != 0 {
+			if err := tx.First(&res.Child, w.ChildID).Error; err != nil {
+				return err
+			}
+			if res.Child.HouseholdID != res.Parent.HouseholdID {
+				return ErrWalkInChild
+			}
+		} else if !res.NewParent {
+			err := tx.Where("household_id = ? AND LOWER(name) = LOWER(?)", res.Parent.HouseholdID, w.ChildName).
+				Order("id").First(&res.Child).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		if res.Child.ID != 0 {
+			// An existing child: same class, same time, age.
+			if err := checkRegistrationConflicts(tx, res.Child.ID, class.ID); err != nil {
+				return err
+			}
+		} else {
+			res.Child = models.Child{
+				HouseholdID: res.Parent.HouseholdID,
+				ParentID:    res.Parent.ID,
+				Name:        w.ChildName,
+				BirthDate:   w.BirthDate,
+				Gender:      w.Gender,
+			}
+			// A child typed in at the door gets the age check here.
+			if err := CheckAge(res.Child, class); err != nil {
+				return err
+			}
+			if err := tx.Create(&res.Child).Error; err != nil {
+				return err
+			}
+			res.NewChild = true
+		}
+
+		var confirmed int64
+		if err := tx.Model(&models.Registration{}).
+			Where("class_id = ? AND status = ?", class.ID, StatusConfirmed).
+			Count(&confirmed).Error; err != nil {
+			return err
+		}
+		if int(confirmed) >= class.Capacity+class.WalkInSeats {
+			return ErrWalkInFull
+		}
+
+		reg := &res.Registration
+		reg.HouseholdID = res.Child.HouseholdID
+		reg.ParentID = res.Parent.ID
+		reg.ChildID = res.Child.ID
+		reg.ClassID = class.ID
+		reg.Code = w.Code
+		if err := insertRegistration(tx, reg, class, models.EventConfirmed, by, "walk-in"); err != nil {
+			return err
+		}
+		code, err := NewSecurityCode()
+		if err != nil {
+			return err
+		}
+		reg.SecurityCode = code
+		return applyTx(tx, reg, models.EventCheckedIn, by, "walk-in")
+	})
+	if err != nil {
+		return WalkInResult{}, err
+	}
+	publishChange(res.Registration, models.EventCheckedIn, by)
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/models"
+)
+
+func TestCheckInWalkIn_NewFamily(t *testing.T) {
+	f := seedTrash(t, 10)
+	res, err := CheckInWalkIn(WalkIn{
+		Phone: "0812 555 000", ParentName: "Pak Joko", ChildName: "Rara",
+		ClassID: f.class.ID, Code: "REG-W1",
+	}, admin)
+	if err != nil {
+		t.Fatalf("CheckInWalkIn: %v", err)
+	}
+	if !res.NewParent || !res.NewChild {
+		t.Errorf("NewParent=%v NewChild=%v, want both", res.NewParent, res.NewChild)
+	}
+	if res.Parent.HouseholdID == 0 || res.Parent.HouseholdID == f.parent.HouseholdID {
+		t.Errorf("walk-in parent household = %d, want a new one", res.Parent.HouseholdID)
+	}
+	reg := res.Registration
+	if StatusOf(reg) != StatusConfirmed || reg.CheckInAt == nil || reg.SecurityCode == "" {
+		t.Errorf("walk-in registration = %+v, want confirmed and checked in", reg)
+	}
+	var kinds []string
+	for _, e := range eventsOf(t, reg.ID) {
+		kinds = append(kinds, e.Kind)
+	}
+	want := []string{models.EventCreated, models.EventConfirmed, models.EventCheckedIn}
+	if len(kinds) != len(want) {
+		t.Fatalf("events = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("events = %v, want %v", kinds, want)
+		}
+	}
+}
+
+func TestCheckInWalkIn_UsesWalkInSeatsOnly(t *testing.T) {
+	f := seedTrash(t, 1) // REG-T1 fills the class
+	db.Conn().Model(&f.class).Update("walk_in_seats", 1)
+
+	sib := WalkIn{Phone: f.parent.Phone, ChildName: "Bima", ClassID: f.class.ID, Code: "REG-W1"}
+	res, err := CheckInWalkIn(sib, admin)
+	if err != nil {
+		t.Fatalf("first walk-in: %v", err)
+	}
+	if res.NewParent || res.Child.HouseholdID != f.parent.HouseholdID {
+		t.Errorf("walk-in for a known phone made a new family: %+v", res)
+	}
+
+	sib.ChildName, sib.Code = "Citra", "REG-W2"
+	if _, err := CheckInWalkIn(sib, admin); !errors.Is(err, ErrWalkInFull) {
+		t.Fatalf("second walk-in = %v, want ErrWalkInFull", err)
+	}
+	// Nothing of the refused walk-in is left behind.
+	if n := count(t, &models.Child{}); n != 2 {
+		t.Errorf("children = %d, want 2", n)
+	}
+}
+
+func TestCheckInWalkIn_RefusesOtherFamilysChild(t *testing.T) {
+	f := seedTrash(t, 10)
+	other := models.Parent{Name: "Ibu Lain", Phone: "+628222"}
+	if err := CreateParent(db.Conn(), &other); err != nil {
+		t.Fatal(err)
+	}
+	later := models.Class{Name: "FJB Stars Club", Date: f.class.Date.AddDate(0, 0, 7), Capacity: 10}
+	db.Conn().Create(&later)
+	_, err := CheckInWalkIn(WalkIn{Phone: other.Phone, ChildID: f.kid.ID, ClassID: later.ID, Code: "REG-W1"}, admin)
+	if !errors.Is(err, ErrWalkInChild) {
+		t.Fatalf("err = %v, want ErrWalkInChild", err)
+	}
+}
+
+func TestCheckInWalkIn_TypedNameFindsHouseholdChild(t *testing.T) {
+	f := seedTrash(t, 10) // Budi is already on f.class
+
+	_, err := CheckInWalkIn(WalkIn{Phone: f.parent.Phone, ChildName: " budi ", ClassID: f.class.ID, Code: "REG-W1"}, admin)
+	if !errors.Is(err, ErrDuplicateReg) {
+		t.Fatalf("err = %v, want ErrDuplicateReg", err)
+	}
+
+	later := models.Class{Name: "FJB Stars Club", Date: f.class.Date.AddDate(0, 0, 7), Capacity: 10}
+	db.Conn().Create(&later)
+	res, err := CheckInWalkIn(WalkIn{Phone: f.parent.Phone, ChildName: "BUDI", ClassID: later.ID, Code: "REG-W2"}, admin)
+	if err != nil {
+		t.Fatalf("walk-in: %v", err)
+	}
+	if res.NewChild || res.Child.ID != f.kid.ID {
+		t.Errorf("walk-in child = %+v, want Budi (%d)", res.Child, f.kid.ID)
+	}
+	if n := count(t, &models.Child{}); n != 1 {
+		t.Errorf("children = %d, want 1", n)
+	}
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lojf/nextgen/internal/config"
 	"github.com/lojf/nextgen/internal/handlers"
 	"github.com/lojf/nextgen/internal/models"
 	"html"
@@ -13,7 +14,9 @@ import (
 	"time"
 )
 
-func Router() http.Handler {
+func Router(cfg config.Config) http.Handler {
+	handlers.Configure(cfg)
+
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
@@ -48,6 +51,10 @@ func Router() http.Handler {
 	r.Get("/register/classes/confirm", handlers.SelectClassConfirmForm(tmpl))
 	r.Post("/register/classes/confirm", handlers.SelectClassConfirmSubmit(tmpl))
 
+	// Whole-series signup (same confirm page when the series has questions)
+	r.Post("/register/series", handlers.SelectSeriesSubmit(tmpl))
+	r.Get("/register/series/confirm", handlers.SelectSeriesConfirmForm(tmpl))
+
 	// Parent self-service: cancel + "My registrations"
 	r.Get("/cancel", handlers.CancelForm(tmpl))
 	r.Post("/cancel", handlers.CancelSubmit(tmpl))
@@ -65,6 +72,11 @@ func Router() http.Handler {
 	r.With(handlers.RequireParent).Get("/account/children/edit", handlers.AccountEditChildForm(tmpl))
 	r.With(handlers.RequireParent).Post("/account/children/edit", handlers.AccountEditChildSubmit)
 	r.With(handlers.RequireParent).Post("/account/children/delete", handlers.AccountDeleteChild)
+	r.With(handlers.RequireParent).Post("/account/children/pickups", handlers.AccountPickupAdd)
+	r.With(handlers.RequireParent).Post("/account/children/pickups/remove", handlers.AccountPickupRemove)
+	r.With(handlers.RequireParent).Get("/account/pickups/{id}/photo", handlers.AccountPickupPhoto)
+	r.With(handlers.RequireParent).Post("/account/guardians", handlers.AccountGuardianAdd)
+	r.With(handlers.RequireParent).Post("/account/guardians/remove", handlers.AccountGuardianRemove)
 
 	r.With(handlers.RequireParent).Post("/account/linkcode", handlers.AccountGenerateLinkCode)
 	r.With(handlers.RequireParent).Post("/account/unlink_telegram", handlers.AccountUnlinkTelegram)
@@ -81,6 +93,18 @@ func Router() http.Handler {
 		ad.Post("/checkin", handlers.CheckinConfirm(tmpl))
 		ad.Get("/station", handlers.CheckinStation(tmpl))
 		ad.Post("/station/staff", handlers.CheckinStationStaff)
+		ad.Post("/station/rooms/{id}/staff", handlers.StationRoomStaff)
+		ad.Get("/station/checkout/{id}", handlers.CheckoutForm(tmpl))
+		ad.Post("/station/checkout/{id}", handlers.CheckoutSubmit)
+		ad.Get("/station/pickups/{id}/photo", handlers.StationPickupPhoto)
+		ad.Get("/station/labels/{id}.{format}", handlers.CheckinLabels)
+		ad.Get("/station/walkin", handlers.WalkinForm(tmpl))
+		ad.Post("/station/walkin", handlers.WalkinSubmit)
+		ad.Get("/station/api/snapshot", handlers.StationSnapshot)
+		ad.Get("/station/live", handlers.StationLive)
+		ad.Get("/station/family", handlers.FamilyCheckinForm(tmpl))
+		ad.Post("/station/family", handlers.FamilyCheckinSubmit)
+		ad.Post("/station/api/checkins", handlers.StationSyncCheckins)
 	})
 
 	// --- Admin routes (with login + guard) ---
@@ -96,6 +120,7 @@ func Router() http.Handler {
 			cg.Get("/checkin", handlers.CheckinForm(tmpl))
 			cg.Post("/checkin", handlers.CheckinConfirm(tmpl))
 			cg.Post("/registrations/{id}/checkin", handlers.AdminRegCheckin)
+			cg.Post("/registrations/{id}/checkin/undo", handlers.AdminRegUndoCheckin)
 		})
 
 		// Admin-only pages
@@ -109,6 +134,13 @@ func Router() http.Handler {
 			ag.Get("/classes/{id}/edit", handlers.AdminEditClassForm(tmpl))
 			ag.Post("/classes/{id}", handlers.AdminUpdateClass)
 			ag.Post("/classes/{id}/delete", handlers.AdminDeleteClass)
+			ag.Post("/classes/{id}/register", handlers.AdminClassRegister)
+
+			// Recurring class series
+			ag.Get("/series", handlers.AdminSeries(tmpl))
+			ag.Get("/series/new", handlers.AdminNewSeries(tmpl))
+			ag.Post("/series", handlers.AdminCreateSeries)
+			ag.Post("/series/{id}/generate", handlers.AdminGenerateSeries)
 
 			// Roster & Capacity
 			ag.Get("/roster", handlers.AdminRoster(tmpl))
@@ -125,6 +157,7 @@ func Router() http.Handler {
 
 			// Families report
 			ag.Get("/families", handlers.AdminFamilies(tmpl))
+			ag.Post("/families/noshow-policy", handlers.AdminNoShowPolicy)
 
 			// Parents
 			ag.Get("/parents", handlers.AdminParentsList(tmpl))
@@ -132,7 +165,10 @@ func Router() http.Handler {
 			ag.Post("/parents/{id}", handlers.AdminParentUpdate)
 			ag.Post("/parents/{id}/children/update", handlers.AdminChildUpdate)
 			ag.Post("/parents/{id}/children/delete", handlers.AdminChildDelete)
+			ag.Post("/parents/{id}/children/pickups", handlers.AdminPickupAdd)
+			ag.Post("/parents/{id}/children/pickups/remove", handlers.AdminPickupRemove)
 			ag.Post("/parents/{id}/delete", handlers.AdminParentDelete)
+			ag.Post("/parents/{id}/guardians", handlers.AdminGuardianAdd)
 
 			// Templates
 			ag.Get("/templates", handlers.AdminTemplatesIndex(tmpl))
@@ -148,6 +184,16 @@ func Router() http.Handler {
 			ag.Post("/users/{id}/password", handlers.AdminUserPassword)
 			ag.Post("/users/{id}/toggle", handlers.AdminUserToggle)
 
+			// Campuses (classes and check-in accounts point here by campus_id)
+			ag.Get("/campuses", handlers.AdminCampuses(tmpl))
+			ag.Post("/campuses", handlers.AdminCampusCreate)
+			ag.Post("/campuses/{id}", handlers.AdminCampusUpdate)
+
+			// Trash (soft-deleted parents, children, classes, registrations)
+			ag.Get("/trash", handlers.AdminTrash(tmpl))
+			ag.Post("/trash/{kind}/{id}/restore", handlers.AdminTrashRestore)
+			ag.Post("/trash/{kind}/{id}/purge", handlers.AdminTrashPurge)
+
 			// JSON for prefill
 			ag.Get("/templates/{id}.json", handlers.AdminTemplatesShowJSON)
 		})
@@ -169,6 +215,7 @@ func mustParseTemplates(baseDir string) *template.Template {
 		"jlong":       func(t time.Time) string { return t.In(loc).Format("02 January 2006") }, // 12 January 2012
 		"fmtDate":     func(t time.Time) string { return t.In(loc).Format("02-01-2006") },
 		"fmtDateTime": func(t time.Time) string { return t.In(loc).Format("Mon, 02 Jan 2006 15:04") },
+		"jtime":       func(t time.Time) string { return t.In(loc).Format("15:04") },
 		"unescape": func(s string) string {
 			s = strings.ReplaceAll(s, "\r\n", "\n")   // normalize
 			s = strings.ReplaceAll(s, "\\r\\n", "\n") // literal backslash-encoded
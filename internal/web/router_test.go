@@ -7,7 +7,8 @@ import (
 	"os"
 	"testing"
 
-	"github.com/lojf/nextgen/internal/db"
+	"github.com/lojf/nextgen/internal/config"
+	"github.com/lojf/nextgen/internal/db/dbtest"
 )
 
 // TestMain changes the working directory to the project root before any tests
@@ -25,10 +26,8 @@ func TestMain(m *testing.M) {
 }
 
 func TestRouterHealthz(t *testing.T) {
-	if err := db.Init(); err != nil {
-		t.Fatalf("db init: %v", err)
-	}
-	r := Router()
+	dbtest.Init(t)
+	r := Router(config.Default())
 	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 	rec := httptest.NewRecorder()
 	r.ServeHTTP(rec, req)
@@ -41,9 +40,7 @@ func TestRouterHealthz(t *testing.T) {
 // startup. template.Must (called in every factory function) panics on any
 // missing or unparseable template file, which would immediately fail this test.
 func TestRouterTemplatePreload(t *testing.T) {
-	if err := db.Init(); err != nil {
-		t.Fatalf("db init: %v", err)
-	}
+	dbtest.Init(t)
 	// If any template file is absent or broken, Router() panics here.
-	_ = Router()
+	_ = Router(config.Default())
 }
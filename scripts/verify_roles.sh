@@ -19,7 +19,7 @@ check(){ [ "$2" = "$3" ] && ok "$1" || bad "$1" "$2 != $3"; }
 ln -s "$REPO/templates" "$WORK/templates"
 cd "$WORK" || exit 1
 
-ADDR=:$PORT ADMIN_PASSWORD=adminpass123 SESSION_SECRET=verify-secret \
+AUTO_MIGRATE=1 ADDR=:$PORT ADMIN_PASSWORD=adminpass123 SESSION_SECRET=verify-secret \
   "$REPO/bin/lojf-nextgen" >"$WORK/server.log" 2>&1 &
 PID=$!
 
@@ -33,15 +33,18 @@ curl -sf "$BASE/health" >/dev/null || { echo "server never came up"; cat "$WORK/
 TODAY=$(date +%Y-%m-%d)
 TOMO=$(date -v+1d +%Y-%m-%d)
 sqlite3 "$WORK/nextgen.db" <<SQL
+INSERT INTO campuses (id,code,name,time_zone,created_at,updated_at) VALUES
+  (1,'FJB','Feast Jakarta Barat','Asia/Jakarta',datetime('now'),datetime('now')),
+  (2,'FJU','Feast Jakarta Utara','Asia/Jakarta',datetime('now'),datetime('now'));
 INSERT INTO parents (id,name,phone,created_at,updated_at) VALUES (1,'P','0811',datetime('now'),datetime('now'));
 INSERT INTO children (id,parent_id,name,created_at,updated_at) VALUES
   (1,1,'Anak Hari Ini',datetime('now'),datetime('now')),
   (2,1,'Anak Besok',datetime('now'),datetime('now')),
   (3,1,'Anak FJU',datetime('now'),datetime('now'));
-INSERT INTO classes (id,name,date,capacity,created_at,updated_at) VALUES
-  (1,'FJB Awesome Kids (Feast Jakarta Barat)','$TODAY 00:00:00+07:00',60,datetime('now'),datetime('now')),
-  (2,'FJB Little Stars (Feast Jakarta Barat)','$TOMO 00:00:00+07:00',60,datetime('now'),datetime('now')),
-  (3,'FJU Stars Club (Feast Jakarta Utara)','$TODAY 00:00:00+07:00',60,datetime('now'),datetime('now'));
+INSERT INTO classes (id,name,campus_id,date,capacity,created_at,updated_at) VALUES
+  (1,'FJB Awesome Kids (Feast Jakarta Barat)',1,'$TODAY 00:00:00+07:00',60,datetime('now'),datetime('now')),
+  (2,'FJB Little Stars (Feast Jakarta Barat)',1,'$TOMO 00:00:00+07:00',60,datetime('now'),datetime('now')),
+  (3,'FJU Stars Club (Feast Jakarta Utara)',2,'$TODAY 00:00:00+07:00',60,datetime('now'),datetime('now'));
 INSERT INTO registrations (id,parent_id,child_id,class_id,status,code,created_at,updated_at) VALUES
   (1,1,1,1,'confirmed','REG-TODAY01',datetime('now'),datetime('now')),
   (2,1,2,2,'confirmed','REG-TOMOR01',datetime('now'),datetime('now')),
@@ -58,8 +61,8 @@ code=$(curl -s -b "$AJAR" -o /dev/null -w '%{http_code}' "$BASE/admin/classes")
 check "admin can reach /admin/classes" "$code" "200"
 
 curl -s -b "$AJAR" -c "$AJAR" -o /dev/null -X POST "$BASE/admin/users" \
-  -d "username=fjb-checkin&password=volunteer123&role=checkin&campus=FJB"
-n=$(sqlite3 "$WORK/nextgen.db" "SELECT COUNT(*) FROM admin_users WHERE username='fjb-checkin' AND role='checkin' AND campus='FJB';")
+  -d "username=fjb-checkin&password=volunteer123&role=checkin&campus_id=1"
+n=$(sqlite3 "$WORK/nextgen.db" "SELECT COUNT(*) FROM admin_users WHERE username='fjb-checkin' AND role='checkin' AND campus_id=1;")
 check "fjb-checkin account created" "$n" "1"
 
 echo